@@ -9,6 +9,8 @@ import (
 	"syscall"
 
 	"github.com/filanov/netctrl-agent/internal/agent"
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/policy"
 )
 
 func main() {
@@ -21,6 +23,18 @@ func main() {
 	// Define command-line flags
 	clusterID := flag.String("cluster-id", "", "Cluster ID (required)")
 	serverAddr := flag.String("server-address", defaultServerAddr, "Server address")
+	policyFile := flag.String("policy-file", os.Getenv("NETCTRL_POLICY_FILE"), "Local execution policy file (optional)")
+	defaultStateDir := os.Getenv("NETCTRL_STATE_DIR")
+	if defaultStateDir == "" {
+		defaultStateDir = "/var/lib/netctrl-agent"
+	}
+	stateDir := flag.String("state-dir", defaultStateDir, "Directory for persistent agent state (scheduled instructions, queued results)")
+	defaultHostRoot := os.Getenv("NETCTRL_HOST_ROOT")
+	if defaultHostRoot == "" {
+		defaultHostRoot = "/"
+	}
+	hostRoot := flag.String("host-root", defaultHostRoot, "Root under which the host's /sys, /proc and /etc are mounted (e.g., /host in a container)")
+	enableLLDP := flag.Bool("lldp", os.Getenv("NETCTRL_LLDP") == "true", "Listen for LLDP neighbors on Mellanox NIC ports")
 	flag.Parse()
 
 	// Check for cluster ID from environment variable if not provided via flag
@@ -39,6 +53,28 @@ func main() {
 
 	// Create agent instance
 	agentInstance := agent.New(*clusterID, *serverAddr)
+	if *hostRoot != "/" {
+		agentInstance.SetHost(host.New(*hostRoot, nil))
+	}
+
+	// Load local execution policy if configured
+	if *policyFile != "" {
+		p, err := policy.Load(*policyFile)
+		if err != nil {
+			log.Fatalf("Failed to load policy: %v", err)
+		}
+		agentInstance.SetPolicy(p)
+	}
+
+	// Enable local scheduler; the agent still works without it
+	if err := agentInstance.EnableScheduler(*stateDir); err != nil {
+		log.Printf("Warning: local scheduler disabled: %v", err)
+	}
+
+	// LLDP needs raw packet sockets (CAP_NET_RAW) in the host's network namespace
+	if *enableLLDP {
+		agentInstance.EnableLLDP()
+	}
 
 	// Run agent in daemon mode with signal handling
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
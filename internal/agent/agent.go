@@ -3,17 +3,35 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/filanov/netctrl-agent/internal/client"
 	"github.com/filanov/netctrl-agent/internal/discovery"
+	"github.com/filanov/netctrl-agent/internal/host"
 	"github.com/filanov/netctrl-agent/internal/instruction"
 	"github.com/filanov/netctrl-agent/internal/instruction/handlers"
+	"github.com/filanov/netctrl-agent/internal/lldp"
+	"github.com/filanov/netctrl-agent/internal/policy"
+	"github.com/filanov/netctrl-agent/internal/scheduler"
 	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
 )
 
+// errCancelledByServer is the cancellation cause for instructions cancelled
+// through a CANCEL instruction.
+var errCancelledByServer = errors.New("cancelled by server")
+
+// exclusiveTypes are the instruction types of which only one instruction
+// runs at a time. The server issues a new COLLECT_HARDWARE instruction on
+// every poll until it receives a result, so a slow collection would otherwise
+// run several times in parallel over the same devices.
+var exclusiveTypes = map[v1.InstructionType]bool{
+	v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE: true,
+}
+
 // Agent represents the netctrl agent instance.
 type Agent struct {
 	clusterID     string
@@ -23,6 +41,26 @@ type Agent struct {
 	ipAddress     string
 	pollInterval  time.Duration
 	registry      *instruction.Registry
+	policy        *policy.Policy
+	scheduler     *scheduler.Scheduler
+	lldp          *lldp.Listener
+	host          *host.Host
+	// hardware is shared by the COLLECT_HARDWARE handlers so that the AER
+	// history survives SetHost
+	hardware handlers.CollectHardwareState
+
+	// mu guards pollInterval, running and runningTypes, which are updated
+	// by handlers executing in the background.
+	mu      sync.Mutex
+	running map[string]context.CancelCauseFunc
+	// runningTypes maps each exclusive type with a running instruction to
+	// that instruction's ID.
+	runningTypes map[v1.InstructionType]string
+	wg           sync.WaitGroup
+
+	// pollIntervalChanged signals the run loop to reset its ticker after
+	// the poll interval is changed by a handler.
+	pollIntervalChanged chan struct{}
 }
 
 // New creates a new Agent instance with instruction handlers.
@@ -32,29 +70,145 @@ func New(clusterID, serverAddress string) *Agent {
 		serverAddress: serverAddress,
 		pollInterval:  60 * time.Second, // Default 60 seconds
 		registry:      instruction.NewRegistry(),
+		host:          host.Default(),
+		running:       make(map[string]context.CancelCauseFunc),
+		runningTypes:  make(map[v1.InstructionType]string),
+
+		pollIntervalChanged: make(chan struct{}, 1),
 	}
 
 	// Register instruction handlers
 	agent.registry.Register(
 		v1.InstructionType_INSTRUCTION_TYPE_POLL_INTERVAL,
-		handlers.NewPollIntervalHandler(agent.updatePollInterval),
+		handlers.NewPollIntervalHandler(agent.updatePollInterval, agent.currentPollInterval),
 	)
 	agent.registry.Register(
 		v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK,
-		handlers.NewHealthCheckHandler(),
+		handlers.NewHealthCheckHandler(agent.policySummary),
 	)
 	agent.registry.Register(
 		v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE,
-		handlers.NewCollectHardwareHandler(),
+		handlers.NewCollectHardwareHandler(agent.host, &agent.hardware),
+	)
+	agent.registry.Register(
+		instruction.TypeCollectCounters,
+		handlers.NewCollectCountersHandler(agent.host),
+	)
+	agent.registry.Register(
+		instruction.TypeVerifyCabling,
+		handlers.NewVerifyCablingHandler(agent.host),
+	)
+	agent.registry.Register(
+		instruction.TypeCancel,
+		handlers.NewCancelHandler(agent.cancelInstruction),
+	)
+	agent.registry.Register(
+		instruction.TypeBatch,
+		handlers.NewBatchHandler(agent.registry),
 	)
 
 	return agent
 }
 
-// updatePollInterval updates the agent's polling interval.
+// SetPolicy installs a local execution policy. Every instruction is evaluated
+// against it before dispatch, and a summary is reported in HEALTH_CHECK results.
+func (a *Agent) SetPolicy(p *policy.Policy) {
+	a.policy = p
+	if p == nil {
+		a.registry.SetAuthorizer(nil)
+		return
+	}
+	a.registry.SetAuthorizer(p)
+	log.Printf("Local execution policy active: %s", p.Summary())
+}
+
+// policySummary returns a summary of the active policy, or "" if there is
+// none.
+func (a *Agent) policySummary() string {
+	if a.policy == nil {
+		return ""
+	}
+	return a.policy.Summary()
+}
+
+// SetHost sets how the agent accesses the host's filesystems and commands,
+// e.g., when running in a container with the host's /sys, /proc and /etc
+// mounted under a different root. Hardware and counter collection, cabling
+// verification and hostname discovery use it. The LLDP neighbor table is
+// kept.
+func (a *Agent) SetHost(h *host.Host) {
+	if h.LLDP == nil {
+		h.LLDP = a.host.LLDP
+	}
+	a.host = h
+	a.registry.Register(
+		v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE,
+		handlers.NewCollectHardwareHandler(h, &a.hardware),
+	)
+	a.registry.Register(
+		instruction.TypeCollectCounters,
+		handlers.NewCollectCountersHandler(h),
+	)
+	a.registry.Register(
+		instruction.TypeVerifyCabling,
+		handlers.NewVerifyCablingHandler(h),
+	)
+	if !h.IsDefaultRoot() {
+		log.Printf("Host filesystems rooted at %s", h.Root)
+	}
+}
+
+// EnableScheduler enables locally scheduled and recurring instructions.
+// Schedules and results awaiting submission are persisted in stateDir.
+func (a *Agent) EnableScheduler(stateDir string) error {
+	s, err := scheduler.New(stateDir, a.registry)
+	if err != nil {
+		return fmt.Errorf("failed to create scheduler: %w", err)
+	}
+
+	a.scheduler = s
+	a.registry.Register(
+		instruction.TypeSchedule,
+		handlers.NewScheduleHandler(s),
+	)
+
+	log.Printf("Local scheduler enabled with %d entries (state: %s)", len(s.Entries()), stateDir)
+	return nil
+}
+
+// EnableLLDP listens for LLDP frames on the ports of Mellanox NICs while
+// the agent runs; hardware collection reports the neighbors.
+func (a *Agent) EnableLLDP() {
+	table := lldp.NewTable(nil)
+	a.host.LLDP = table
+	a.lldp = &lldp.Listener{
+		Table: table,
+		Interfaces: func(ctx context.Context) ([]string, error) {
+			return handlers.PortInterfaces(ctx, a.host)
+		},
+	}
+	log.Printf("LLDP neighbor discovery enabled")
+}
+
+// updatePollInterval updates the agent's polling interval and signals the
+// run loop to apply it without waiting for the next poll.
 func (a *Agent) updatePollInterval(interval time.Duration) {
 	log.Printf("Updating poll interval to %v", interval)
+	a.mu.Lock()
 	a.pollInterval = interval
+	a.mu.Unlock()
+
+	select {
+	case a.pollIntervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+// currentPollInterval returns the agent's polling interval.
+func (a *Agent) currentPollInterval() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.pollInterval
 }
 
 // Run starts the agent in daemon mode:
@@ -67,16 +221,35 @@ func (a *Agent) Run(ctx context.Context) error {
 		return fmt.Errorf("initial registration failed: %w", err)
 	}
 
-	log.Printf("Starting daemon mode with %v poll interval", a.pollInterval)
+	log.Printf("Starting daemon mode with %v poll interval", a.currentPollInterval())
+
+	if a.scheduler != nil {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.scheduler.Run(ctx)
+		}()
+	}
+
+	if a.lldp != nil {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.lldp.Run(ctx)
+		}()
+	}
 
 	backoff := NewBackoff()
-	ticker := time.NewTicker(a.pollInterval)
+	ticker := time.NewTicker(a.currentPollInterval())
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("Shutdown signal received, unregistering agent...")
+			log.Printf("Shutdown signal received, waiting for running instructions...")
+			a.wait()
+
+			log.Printf("Unregistering agent...")
 			// Use a fresh context for unregister since ctx is cancelled
 			unregisterCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
@@ -85,6 +258,9 @@ func (a *Agent) Run(ctx context.Context) error {
 			}
 			return ctx.Err()
 
+		case <-a.pollIntervalChanged:
+			ticker.Reset(a.currentPollInterval())
+
 		case <-ticker.C:
 			if err := a.poll(ctx); err != nil {
 				log.Printf("Poll error: %v", err)
@@ -96,7 +272,7 @@ func (a *Agent) Run(ctx context.Context) error {
 				// Reset backoff on success
 				backoff.Reset()
 				// Update ticker with current poll interval (may have changed)
-				ticker.Reset(a.pollInterval)
+				ticker.Reset(a.currentPollInterval())
 			}
 		}
 	}
@@ -125,56 +301,188 @@ func (a *Agent) poll(ctx context.Context) error {
 	// Update poll interval if server specifies one
 	if resp.PollIntervalSeconds > 0 {
 		newInterval := time.Duration(resp.PollIntervalSeconds) * time.Second
-		if newInterval != a.pollInterval {
+		if newInterval != a.currentPollInterval() {
 			log.Printf("Server updated poll interval to %v", newInterval)
-			a.pollInterval = newInterval
+			a.updatePollInterval(newInterval)
 		}
 	}
 
-	// Process each instruction
+	// Submit results of locally scheduled instructions queued since the last poll
+	a.flushScheduledResults(ctx, grpcClient)
+
+	// Dispatch each instruction; handlers run in the background so that
+	// later polls can deliver cancellations for them
 	for _, instruction := range resp.Instructions {
 		log.Printf("Processing instruction: id=%s, type=%s", instruction.Id, instruction.Type)
+		a.dispatch(ctx, instruction)
+	}
 
-		// Check if handler is registered for this instruction type
-		if !a.registry.HasHandler(instruction.Type) {
-			log.Printf("Warning: no handler for instruction type %s, skipping", instruction.Type)
-			// Submit error result for unsupported instruction type
-			a.submitResult(ctx, grpcClient, instruction.Id, instruction.Type, nil, fmt.Errorf("no handler registered for instruction type %s", instruction.Type))
-			continue
-		}
+	return nil
+}
 
-		// Execute instruction
-		resultData, err := a.registry.Execute(ctx, instruction)
-		if err != nil {
-			log.Printf("Error executing instruction %s: %v", instruction.Id, err)
-			// Submit error result
-			a.submitResult(ctx, grpcClient, instruction.Id, instruction.Type, nil, err)
-			continue
-		}
+// dispatch starts executing an instruction in the background and tracks it
+// so that it can be cancelled by ID. Instructions that are already running
+// are not started again, nor are instructions of an exclusive type while
+// another instruction of that type runs.
+func (a *Agent) dispatch(ctx context.Context, inst *v1.Instruction) {
+	execCtx, cancel := context.WithCancelCause(ctx)
+
+	a.mu.Lock()
+	if _, running := a.running[inst.Id]; running {
+		a.mu.Unlock()
+		cancel(nil)
+		log.Printf("Instruction %s is already running, skipping", inst.Id)
+		return
+	}
+	if runningID, busy := a.runningTypes[inst.Type]; busy {
+		a.mu.Unlock()
+		cancel(nil)
+		log.Printf("Instruction %s of type %s is already running, skipping %s", runningID, inst.Type, inst.Id)
+		return
+	}
+	a.running[inst.Id] = cancel
+	if exclusiveTypes[inst.Type] {
+		a.runningTypes[inst.Type] = inst.Id
+	}
+	a.mu.Unlock()
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		defer func() {
+			a.mu.Lock()
+			delete(a.running, inst.Id)
+			if a.runningTypes[inst.Type] == inst.Id {
+				delete(a.runningTypes, inst.Type)
+			}
+			a.mu.Unlock()
+			cancel(nil)
+		}()
+		a.execute(execCtx, inst)
+	}()
+}
+
+// cancelInstruction cancels a running instruction. It returns false if no
+// instruction with that ID is running.
+func (a *Agent) cancelInstruction(instructionID string) bool {
+	a.mu.Lock()
+	cancel, ok := a.running[instructionID]
+	a.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	log.Printf("Cancelling instruction %s", instructionID)
+	cancel(errCancelledByServer)
+	return true
+}
+
+// wait blocks until all dispatched instructions have finished.
+func (a *Agent) wait() {
+	a.wg.Wait()
+}
+
+// execute runs a single instruction and submits its result.
+func (a *Agent) execute(ctx context.Context, inst *v1.Instruction) {
+	// Create gRPC client for this instruction's results
+	grpcClient, err := client.NewClient(a.serverAddress)
+	if err != nil {
+		log.Printf("Failed to create gRPC client for instruction %s: %v", inst.Id, err)
+		return
+	}
+	defer grpcClient.Close()
+
+	// Results are submitted even if the instruction was cancelled
+	submitCtx := context.WithoutCancel(ctx)
+
+	// Check if handler is registered for this instruction type
+	if !a.registry.HasHandler(inst.Type) {
+		log.Printf("Warning: no handler for instruction type %s, skipping", inst.Type)
+		// Submit error result for unsupported instruction type
+		a.submitResult(submitCtx, grpcClient, inst.Id, inst.Type, nil, fmt.Errorf("no handler registered for instruction type %s", inst.Type))
+		return
+	}
+
+	// Execute instruction
+	resultData, err := a.registry.Execute(ctx, inst)
+	if err != nil {
+		log.Printf("Error executing instruction %s: %v", inst.Id, err)
+		// Submit error result
+		a.submitResult(submitCtx, grpcClient, inst.Id, inst.Type, nil, err)
+		return
+	}
 
-		log.Printf("Successfully executed instruction %s", instruction.Id)
-		if resultData != "" {
-			log.Printf("Result: %s", resultData)
+	log.Printf("Successfully executed instruction %s", inst.Id)
+	if resultData != "" {
+		log.Printf("Result: %s", resultData)
+	}
+
+	// Convert result string to proto message
+	result, err := convertToProtoResult(inst.Type, resultData)
+	if err != nil {
+		log.Printf("Error converting result for instruction %s: %v", inst.Id, err)
+		// Submit error result
+		a.submitResult(submitCtx, grpcClient, inst.Id, inst.Type, nil, err)
+		return
+	}
+
+	// Submit successful result
+	if a.submitResult(submitCtx, grpcClient, inst.Id, inst.Type, result, nil) {
+		a.registry.Delivered(inst.Type, inst.Id)
+	}
+}
+
+// flushScheduledResults submits queued results of scheduled instructions.
+// Results stay queued if the server cannot be reached.
+func (a *Agent) flushScheduledResults(ctx context.Context, grpcClient *client.Client) {
+	if a.scheduler == nil || a.scheduler.Results().Len() == 0 {
+		return
+	}
+
+	err := a.scheduler.Results().Drain(func(queued scheduler.QueuedResult) error {
+		var result *v1.InstructionResult
+		succeeded := queued.Error == ""
+		if !succeeded {
+			result = createErrorResult(queued.Type, errors.New(queued.Error))
+		} else {
+			var err error
+			if result, err = convertToProtoResult(queued.Type, queued.Result); err != nil {
+				result = createErrorResult(queued.Type, err)
+				succeeded = false
+			}
 		}
 
-		// Convert result string to proto message
-		result, err := convertToProtoResult(instruction.Type, resultData)
+		resp, err := grpcClient.SubmitInstructionResult(ctx, &v1.SubmitInstructionResultRequest{
+			AgentId:       a.agentID,
+			InstructionId: queued.InstructionID,
+			Result:        result,
+		})
 		if err != nil {
-			log.Printf("Error converting result for instruction %s: %v", instruction.Id, err)
-			// Submit error result
-			a.submitResult(ctx, grpcClient, instruction.Id, instruction.Type, nil, err)
-			continue
+			return err
 		}
-
-		// Submit successful result
-		a.submitResult(ctx, grpcClient, instruction.Id, instruction.Type, result, nil)
+		if !resp.Success {
+			// Resubmitting would be rejected again and block the results
+			// queued behind it; keep it aside instead
+			log.Printf("Server rejected result of scheduled instruction %s: %s", queued.InstructionID, resp.Message)
+			if err := a.scheduler.Rejected().Push(queued); err != nil {
+				return fmt.Errorf("failed to keep rejected result %s: %w", queued.InstructionID, err)
+			}
+			return nil
+		}
+		if succeeded {
+			a.registry.Delivered(queued.Type, queued.InstructionID)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to submit scheduled results, %d still queued: %v", a.scheduler.Results().Len(), err)
 	}
-
-	return nil
 }
 
-// submitResult submits an instruction result to the server.
-func (a *Agent) submitResult(ctx context.Context, grpcClient *client.Client, instructionID string, instructionType v1.InstructionType, result *v1.InstructionResult, err error) {
+// submitResult submits an instruction result to the server and reports
+// whether the server accepted it.
+func (a *Agent) submitResult(ctx context.Context, grpcClient *client.Client, instructionID string, instructionType v1.InstructionType, result *v1.InstructionResult, err error) bool {
 	// Create result if error occurred
 	if err != nil {
 		result = createErrorResult(instructionType, err)
@@ -191,7 +499,7 @@ func (a *Agent) submitResult(ctx context.Context, grpcClient *client.Client, ins
 	submitResp, submitErr := grpcClient.SubmitInstructionResult(ctx, submitReq)
 	if submitErr != nil {
 		log.Printf("Failed to submit result for instruction %s: %v", instructionID, submitErr)
-		return
+		return false
 	}
 
 	if submitResp.Success {
@@ -199,6 +507,7 @@ func (a *Agent) submitResult(ctx context.Context, grpcClient *client.Client, ins
 	} else {
 		log.Printf("Server reported failure when submitting result for instruction %s: %s", instructionID, submitResp.Message)
 	}
+	return submitResp.Success
 }
 
 // Unregister removes the agent registration from the server.
@@ -238,7 +547,7 @@ func (a *Agent) Unregister(ctx context.Context) error {
 // 5. Stores agent state for daemon mode
 func (a *Agent) Register(ctx context.Context) error {
 	// Discover hostname
-	hostname, err := discovery.GetHostname()
+	hostname, err := discovery.GetHostnameFrom(a.host)
 	if err != nil {
 		return fmt.Errorf("failed to discover hostname: %w", err)
 	}
@@ -305,7 +614,8 @@ func convertToProtoResult(instructionType v1.InstructionType, resultData string)
 
 	switch instructionType {
 	case v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE:
-		// Parse JSON directly into HardwareCollectionResult
+		// Parse the proto fields from the JSON report. HardwareCollectionResult
+		// has no field for the rest of the report, so it is not submitted
 		var hwResult v1.HardwareCollectionResult
 		if err := json.Unmarshal([]byte(resultData), &hwResult); err != nil {
 			return nil, fmt.Errorf("failed to parse hardware result: %w", err)
@@ -315,15 +625,27 @@ func convertToProtoResult(instructionType v1.InstructionType, resultData string)
 		}
 
 	case v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK:
-		// For health check, just mark as healthy if no error
+		// HealthCheckResult has no field for the health document (uptime,
+		// active policy); it travels in the message, as for extension types
 		result.Result = &v1.InstructionResult_HealthCheck{
 			HealthCheck: &v1.HealthCheckResult{
-				Healthy: true,
+				Healthy:      true,
+				ErrorMessage: fmt.Sprintf("%s: %s", instruction.StatusSucceeded, resultData),
 			},
 		}
 
 	default:
-		return nil, fmt.Errorf("unsupported instruction type: %v", instructionType)
+		if !instruction.IsExtensionType(instructionType) {
+			return nil, fmt.Errorf("unsupported instruction type: %v", instructionType)
+		}
+		// Agent extension types have no dedicated result message yet; the
+		// result document travels in the generic health check carrier.
+		result.Result = &v1.InstructionResult_HealthCheck{
+			HealthCheck: &v1.HealthCheckResult{
+				Healthy:      true,
+				ErrorMessage: fmt.Sprintf("%s: %s", instruction.StatusSucceeded, resultData),
+			},
+		}
 	}
 
 	return result, nil
@@ -345,11 +667,16 @@ func createErrorResult(instructionType v1.InstructionType, err error) *v1.Instru
 			},
 		}
 	default:
-		// For other types, we'll use health check as a generic error carrier
+		// For other types, we'll use health check as a generic error carrier.
+		// Errors with an explicit status (e.g., POLICY_DENIED) keep it as the message prefix.
+		message := fmt.Sprintf("instruction failed: %v", err)
+		if instruction.StatusOf(err) != instruction.StatusFailed {
+			message = err.Error()
+		}
 		result.Result = &v1.InstructionResult_HealthCheck{
 			HealthCheck: &v1.HealthCheckResult{
 				Healthy:      false,
-				ErrorMessage: fmt.Sprintf("instruction failed: %v", err),
+				ErrorMessage: message,
 			},
 		}
 	}
@@ -2,9 +2,17 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"net"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/filanov/netctrl-agent/internal/client"
+	"github.com/filanov/netctrl-agent/internal/host/hosttest"
+	"github.com/filanov/netctrl-agent/internal/instruction"
+	"github.com/filanov/netctrl-agent/internal/instruction/handlers"
+	"github.com/filanov/netctrl-agent/internal/scheduler"
 	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/test/bufconn"
@@ -109,6 +117,19 @@ func TestAgent_UpdatePollInterval(t *testing.T) {
 	if agent.pollInterval != 120*time.Second {
 		t.Errorf("poll interval after update = %v, want %v", agent.pollInterval, 120*time.Second)
 	}
+
+	select {
+	case <-agent.pollIntervalChanged:
+	default:
+		t.Error("expected the run loop to be signalled to reset its ticker")
+	}
+
+	// Repeated updates before the run loop catches up must not block
+	agent.updatePollInterval(30 * time.Second)
+	agent.updatePollInterval(45 * time.Second)
+	if got := agent.currentPollInterval(); got != 45*time.Second {
+		t.Errorf("poll interval after repeated updates = %v, want %v", got, 45*time.Second)
+	}
 }
 
 func TestAgent_Unregister_NotRegistered(t *testing.T) {
@@ -229,3 +250,244 @@ func (m *mockHandler) Execute(ctx context.Context, instruction *v1.Instruction)
 	}
 	return "", nil
 }
+
+func TestCreateErrorResult_PolicyDenied(t *testing.T) {
+	err := instruction.NewStatusError(instruction.StatusPolicyDenied, "denied by local policy")
+
+	result := createErrorResult(v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE, err)
+
+	healthCheck := result.GetHealthCheck()
+	if healthCheck == nil {
+		t.Fatal("expected health check error carrier")
+	}
+	if healthCheck.Healthy {
+		t.Error("expected unhealthy result")
+	}
+	if !strings.HasPrefix(healthCheck.ErrorMessage, "POLICY_DENIED:") {
+		t.Errorf("ErrorMessage = %q, want POLICY_DENIED prefix", healthCheck.ErrorMessage)
+	}
+}
+
+func TestConvertToProtoResult_HealthCheck(t *testing.T) {
+	data := `{"status":"active","policy":"name=prod digest=abc default=deny rules=1 maintenance=false"}`
+
+	result, err := convertToProtoResult(v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK, data)
+	if err != nil {
+		t.Fatalf("convertToProtoResult() error = %v", err)
+	}
+
+	healthCheck := result.GetHealthCheck()
+	if healthCheck == nil || !healthCheck.Healthy {
+		t.Fatalf("expected healthy health check result, got %v", result)
+	}
+	if want := "SUCCEEDED: " + data; healthCheck.ErrorMessage != want {
+		t.Errorf("ErrorMessage = %q, want %q", healthCheck.ErrorMessage, want)
+	}
+}
+
+func TestAgent_CancelInstruction(t *testing.T) {
+	agent := New("test-cluster", "localhost:0")
+	agent.agentID = "test-agent-id"
+
+	started := make(chan struct{})
+	var handlerErr error
+	agent.registry.Register(v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE, &mockHandler{
+		executeFunc: func(ctx context.Context, instruction *v1.Instruction) (string, error) {
+			close(started)
+			<-ctx.Done()
+			handlerErr = context.Cause(ctx)
+			return "", ctx.Err()
+		},
+	})
+
+	agent.dispatch(context.Background(), &v1.Instruction{
+		Id:   "long-running",
+		Type: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE,
+	})
+	<-started
+
+	if agent.cancelInstruction("unknown") {
+		t.Error("cancelInstruction() = true for unknown instruction")
+	}
+	if !agent.cancelInstruction("long-running") {
+		t.Error("cancelInstruction() = false for running instruction")
+	}
+
+	agent.wait()
+
+	if handlerErr != errCancelledByServer {
+		t.Errorf("handler cancellation cause = %v, want %v", handlerErr, errCancelledByServer)
+	}
+	if agent.cancelInstruction("long-running") {
+		t.Error("cancelInstruction() = true for finished instruction")
+	}
+}
+
+func TestAgent_Dispatch_ExclusiveType(t *testing.T) {
+	agent := New("test-cluster", "localhost:0")
+	agent.agentID = "test-agent-id"
+
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	agent.registry.Register(v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE, &mockHandler{
+		executeFunc: func(ctx context.Context, instruction *v1.Instruction) (string, error) {
+			started <- instruction.Id
+			<-release
+			return "", nil
+		},
+	})
+
+	agent.dispatch(context.Background(), &v1.Instruction{Id: "collect-1", Type: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE})
+	if id := <-started; id != "collect-1" {
+		t.Fatalf("started %s, want collect-1", id)
+	}
+
+	// The server re-issues the collection with a new ID while it runs
+	agent.dispatch(context.Background(), &v1.Instruction{Id: "collect-2", Type: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE})
+	close(release)
+	agent.wait()
+
+	select {
+	case id := <-started:
+		t.Errorf("instruction %s started while collect-1 was running", id)
+	default:
+	}
+
+	// Once the first collection finished, a new one may run
+	agent.registry.Register(v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE, &mockHandler{
+		executeFunc: func(ctx context.Context, instruction *v1.Instruction) (string, error) {
+			started <- instruction.Id
+			return "", nil
+		},
+	})
+	agent.dispatch(context.Background(), &v1.Instruction{Id: "collect-3", Type: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE})
+	agent.wait()
+	if id := <-started; id != "collect-3" {
+		t.Errorf("started %s, want collect-3", id)
+	}
+}
+
+func TestAgent_FlushScheduledResults_Rejected(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	v1.RegisterAgentServiceServer(server, &mockAgentServer{
+		submitInstructionResultFunc: func(ctx context.Context, req *v1.SubmitInstructionResultRequest) (*v1.SubmitInstructionResultResponse, error) {
+			if req.InstructionId == "bad@2026-01-01T00:00:00Z" {
+				return &v1.SubmitInstructionResultResponse{Success: false, Message: "Failed to process result"}, nil
+			}
+			return &v1.SubmitInstructionResultResponse{Success: true}, nil
+		},
+	})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	agent := New("test-cluster", listener.Addr().String())
+	agent.agentID = "test-agent-id"
+	if err := agent.EnableScheduler(t.TempDir()); err != nil {
+		t.Fatalf("EnableScheduler() failed: %v", err)
+	}
+	for _, id := range []string{"bad@2026-01-01T00:00:00Z", "good@2026-01-01T00:00:00Z"} {
+		err := agent.scheduler.Results().Push(scheduler.QueuedResult{
+			InstructionID: id,
+			Type:          v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK,
+			Result:        `{"status":"active"}`,
+		})
+		if err != nil {
+			t.Fatalf("Push() failed: %v", err)
+		}
+	}
+
+	grpcClient, err := client.NewClient(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer grpcClient.Close()
+	agent.flushScheduledResults(context.Background(), grpcClient)
+
+	if n := agent.scheduler.Results().Len(); n != 0 {
+		t.Errorf("%d results still queued, want 0", n)
+	}
+	var rejected []string
+	agent.scheduler.Rejected().Drain(func(r scheduler.QueuedResult) error {
+		rejected = append(rejected, r.InstructionID)
+		return nil
+	})
+	if len(rejected) != 1 || rejected[0] != "bad@2026-01-01T00:00:00Z" {
+		t.Errorf("rejected = %v, want [bad@2026-01-01T00:00:00Z]", rejected)
+	}
+}
+
+func TestAgent_Execute_SubmitsHardwareCollection(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	submitted := make(chan *v1.SubmitInstructionResultRequest, 1)
+	server := grpc.NewServer()
+	v1.RegisterAgentServiceServer(server, &mockAgentServer{
+		submitInstructionResultFunc: func(ctx context.Context, req *v1.SubmitInstructionResultRequest) (*v1.SubmitInstructionResultResponse, error) {
+			submitted <- req
+			return &v1.SubmitInstructionResultResponse{Success: true}, nil
+		},
+	})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	agent := New("test-cluster", listener.Addr().String())
+	agent.agentID = "test-agent-id"
+	agent.SetHost(hosttest.Load(t, "../instruction/handlers/testdata/hosts/cx5.txt"))
+
+	agent.execute(context.Background(), &v1.Instruction{
+		Id:   "collect-1",
+		Type: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE,
+	})
+
+	var req *v1.SubmitInstructionResultRequest
+	select {
+	case req = <-submitted:
+	default:
+		t.Fatal("no result submitted")
+	}
+	hwResult := req.Result.GetHardwareCollection()
+	if hwResult == nil {
+		t.Fatalf("submitted result has no hardware collection: %v", req.Result)
+	}
+	if len(hwResult.NetworkInterfaces) != 2 {
+		t.Fatalf("network_interfaces has %d NICs, want 2", len(hwResult.NetworkInterfaces))
+	}
+	nic := hwResult.NetworkInterfaces[0]
+	if nic.PciAddress != "0000:3b:00.0" || nic.SerialNumber == "" || len(nic.Ports) == 0 {
+		t.Errorf("NIC summary incomplete: %v", nic)
+	}
+}
+
+func TestAgent_SetHost_KeepsAERHistory(t *testing.T) {
+	agent := New("test-cluster", "localhost:0")
+	agent.SetHost(hosttest.Load(t, "../instruction/handlers/testdata/hosts/cx5.txt"))
+
+	first := &v1.Instruction{Id: "collect-1", Type: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE}
+	if _, err := agent.registry.Execute(context.Background(), first); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	agent.registry.Delivered(first.Type, first.Id)
+
+	// Re-registering the handlers for a new host keeps the delivered counts
+	agent.SetHost(hosttest.Load(t, "../instruction/handlers/testdata/hosts/cx5.txt"))
+	result, err := agent.registry.Execute(context.Background(), &v1.Instruction{
+		Id:   "collect-2",
+		Type: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	var report handlers.HardwareReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatal(err)
+	}
+	if aer := report.NICs[0].AER; aer == nil || aer.Delta == nil {
+		t.Errorf("collection after SetHost has no AER delta: %+v", aer)
+	}
+}
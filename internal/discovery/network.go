@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net"
 	"os"
+
+	"github.com/filanov/netctrl-agent/internal/host"
 )
 
 // GetHostname returns the system hostname.
@@ -15,6 +17,21 @@ func GetHostname() (string, error) {
 	return hostname, nil
 }
 
+// GetHostnameFrom returns the hostname of h. When h's filesystems are
+// mounted under a root (e.g., a container with the host's /etc at
+// /host/etc), the hostname is read from its /etc/hostname, since the
+// container's own UTS namespace may differ from the host's.
+func GetHostnameFrom(h *host.Host) (string, error) {
+	if h == nil || h.IsDefaultRoot() {
+		return GetHostname()
+	}
+
+	if hostname := h.ReadString("/etc/hostname"); hostname != "" {
+		return hostname, nil
+	}
+	return GetHostname()
+}
+
 // GetPrimaryIPAddress returns the first non-loopback IPv4 address.
 func GetPrimaryIPAddress() (string, error) {
 	interfaces, err := net.Interfaces()
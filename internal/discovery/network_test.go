@@ -1,7 +1,11 @@
 package discovery
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/host"
 )
 
 func TestGetHostname(t *testing.T) {
@@ -33,3 +37,31 @@ func TestGetPrimaryIPAddress(t *testing.T) {
 
 	t.Logf("Primary IP: %s", ip)
 }
+
+func TestGetHostnameFrom(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "etc", "hostname"), []byte("node-17.example.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	hostname, err := GetHostnameFrom(host.New(root, nil))
+	if err != nil {
+		t.Fatalf("GetHostnameFrom() failed: %v", err)
+	}
+	if hostname != "node-17.example.com" {
+		t.Errorf("GetHostnameFrom() = %q, want %q", hostname, "node-17.example.com")
+	}
+
+	// Without /etc/hostname under the root, fall back to the local hostname
+	fallback, err := GetHostnameFrom(host.New(t.TempDir(), nil))
+	if err != nil {
+		t.Fatalf("GetHostnameFrom() fallback failed: %v", err)
+	}
+	local, _ := GetHostname()
+	if fallback != local {
+		t.Errorf("GetHostnameFrom() fallback = %q, want %q", fallback, local)
+	}
+}
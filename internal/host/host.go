@@ -0,0 +1,161 @@
+// Package host provides access to the host the agent manages: its
+// pseudo-filesystems and the external tools used to query hardware.
+package host
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/filanov/netctrl-agent/internal/lldp"
+	"github.com/filanov/netctrl-agent/internal/netlink"
+)
+
+// CommandRunner runs external commands on the host.
+type CommandRunner interface {
+	// Run executes name with args and returns its standard output.
+	Run(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// ExecRunner runs commands with os/exec.
+type ExecRunner struct{}
+
+// Run executes name with args and returns its standard output. The command
+// is killed if ctx is cancelled. If the command fails, the error includes
+// its standard error output.
+func (ExecRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	output, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return output, fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return output, fmt.Errorf("%s failed: %w", name, err)
+	}
+	return output, nil
+}
+
+// Host provides access to the host's pseudo-filesystems (/sys, /proc, /etc),
+// commands, netlink and LLDP neighbors. All absolute paths are resolved
+// under Root, so the agent can run in a container with the host's
+// filesystems mounted at e.g. /host, and tests can point it at a captured
+// sysfs tree.
+type Host struct {
+	// Root is prepended to every path; empty or "/" means the real root.
+	Root string
+	// Runner runs external commands such as mstflint.
+	Runner CommandRunner
+	// Netlink queries the kernel over netlink. Netlink is not affected by
+	// Root; the agent must share the host's network namespace.
+	Netlink netlink.Client
+	// LLDP holds the LLDP neighbors seen on the host's ports. It is nil
+	// unless an LLDP listener is running.
+	LLDP *lldp.Table
+}
+
+// New creates a Host rooted at root that runs commands with runner.
+// A nil runner defaults to ExecRunner. Netlink queries go to the kernel.
+func New(root string, runner CommandRunner) *Host {
+	if runner == nil {
+		runner = ExecRunner{}
+	}
+	return &Host{
+		Root:    root,
+		Runner:  runner,
+		Netlink: netlink.System{},
+	}
+}
+
+// Default returns a Host for the real root filesystem.
+func Default() *Host {
+	return New("/", nil)
+}
+
+// IsDefaultRoot reports whether paths are resolved against the real root.
+func (h *Host) IsDefaultRoot() bool {
+	return h.Root == "" || h.Root == "/"
+}
+
+// Path resolves an absolute host path (e.g., "/sys/bus/pci/devices") under Root.
+func (h *Host) Path(path string) string {
+	if h.IsDefaultRoot() {
+		return path
+	}
+	return filepath.Join(h.Root, path)
+}
+
+// ReadFile reads a host file.
+func (h *Host) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(h.Path(path))
+}
+
+// ReadDir lists a host directory.
+func (h *Host) ReadDir(path string) ([]os.DirEntry, error) {
+	return os.ReadDir(h.Path(path))
+}
+
+// Readlink returns the target of a host symlink. Targets are returned as
+// stored; sysfs links are relative.
+func (h *Host) Readlink(path string) (string, error) {
+	return os.Readlink(h.Path(path))
+}
+
+// EvalSymlinks resolves all symlinks in a host path and returns the
+// resulting host path (without Root). For example, it resolves
+// /sys/bus/pci/devices/<address> to the device's place in the
+// /sys/devices hierarchy.
+func (h *Host) EvalSymlinks(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(h.Path(path))
+	if err != nil {
+		return "", err
+	}
+	if h.IsDefaultRoot() {
+		return resolved, nil
+	}
+
+	root, err := filepath.EvalSymlinks(h.Root)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%s resolves outside of %s", path, h.Root)
+	}
+	return "/" + rel, nil
+}
+
+// Exists reports whether a host path exists.
+func (h *Host) Exists(path string) bool {
+	_, err := os.Stat(h.Path(path))
+	return err == nil
+}
+
+// ReadString reads a host file (typically a sysfs attribute) and trims
+// surrounding whitespace. Returns an empty string if it cannot be read.
+func (h *Host) ReadString(path string) string {
+	data, err := h.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// LinkName returns the base name of a host symlink's target (e.g., the
+// driver name for a sysfs <device>/driver link). Returns an empty string if
+// the link does not exist.
+func (h *Host) LinkName(path string) string {
+	target, err := h.Readlink(path)
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+// Run runs a command on the host.
+func (h *Host) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return h.Runner.Run(ctx, name, args...)
+}
@@ -0,0 +1,114 @@
+package host
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHost_Path(t *testing.T) {
+	tests := []struct {
+		name string
+		root string
+		path string
+		want string
+	}{
+		{name: "empty root", root: "", path: "/sys/bus/pci/devices", want: "/sys/bus/pci/devices"},
+		{name: "slash root", root: "/", path: "/sys/bus/pci/devices", want: "/sys/bus/pci/devices"},
+		{name: "container mount", root: "/host", path: "/sys/bus/pci/devices", want: "/host/sys/bus/pci/devices"},
+		{name: "relative path", root: "/host", path: "proc/cpuinfo", want: "/host/proc/cpuinfo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(tt.root, nil)
+			if got := h.Path(tt.path); got != tt.want {
+				t.Errorf("Path(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHost_ReadHelpers(t *testing.T) {
+	root := t.TempDir()
+	devPath := filepath.Join(root, "sys", "bus", "pci", "devices", "0000:03:00.0")
+	if err := os.MkdirAll(devPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(devPath, "vendor"), []byte("0x15b3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../../bus/pci/drivers/mlx5_core", filepath.Join(devPath, "driver")); err != nil {
+		t.Fatal(err)
+	}
+
+	h := New(root, nil)
+	dev := "/sys/bus/pci/devices/0000:03:00.0"
+
+	if got := h.ReadString(dev + "/vendor"); got != "0x15b3" {
+		t.Errorf("ReadString() = %q, want %q", got, "0x15b3")
+	}
+	if got := h.ReadString(dev + "/missing"); got != "" {
+		t.Errorf("ReadString() of missing file = %q, want empty", got)
+	}
+	if got := h.LinkName(dev + "/driver"); got != "mlx5_core" {
+		t.Errorf("LinkName() = %q, want %q", got, "mlx5_core")
+	}
+	if got := h.LinkName(dev + "/iommu_group"); got != "" {
+		t.Errorf("LinkName() of missing link = %q, want empty", got)
+	}
+	if !h.Exists(dev) || h.Exists(dev+"/missing") {
+		t.Error("Exists() returned wrong result")
+	}
+
+	entries, err := h.ReadDir("/sys/bus/pci/devices")
+	if err != nil {
+		t.Fatalf("ReadDir() failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "0000:03:00.0" {
+		t.Errorf("ReadDir() = %v, want [0000:03:00.0]", entries)
+	}
+}
+
+func TestExecRunner(t *testing.T) {
+	output, err := ExecRunner{}.Run(context.Background(), "echo", "hello")
+	if err != nil {
+		t.Skipf("echo not available: %v", err)
+	}
+	if string(output) != "hello\n" {
+		t.Errorf("Run() = %q, want %q", output, "hello\n")
+	}
+
+	if _, err := (ExecRunner{}).Run(context.Background(), "netctrl-agent-no-such-command"); err == nil {
+		t.Error("expected error for missing command")
+	}
+}
+
+func TestHost_EvalSymlinks(t *testing.T) {
+	root := t.TempDir()
+	canonical := filepath.Join(root, "sys", "devices", "pci0000:3a", "0000:3a:00.0", "0000:3b:00.0")
+	if err := os.MkdirAll(canonical, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	busDir := filepath.Join(root, "sys", "bus", "pci", "devices")
+	if err := os.MkdirAll(busDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../../devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0", filepath.Join(busDir, "0000:3b:00.0")); err != nil {
+		t.Fatal(err)
+	}
+
+	h := New(root, nil)
+	got, err := h.EvalSymlinks("/sys/bus/pci/devices/0000:3b:00.0")
+	if err != nil {
+		t.Fatalf("EvalSymlinks() failed: %v", err)
+	}
+	if want := "/sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0"; got != want {
+		t.Errorf("EvalSymlinks() = %q, want %q", got, want)
+	}
+
+	if _, err := h.EvalSymlinks("/sys/bus/pci/devices/0000:ff:00.0"); err == nil {
+		t.Error("expected error for missing path")
+	}
+}
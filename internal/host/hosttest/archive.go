@@ -0,0 +1,177 @@
+// Package hosttest provides fake hosts for tests: a canned CommandRunner and
+// a loader for host captures (sysfs/procfs trees plus command outputs and
+// netlink replies) stored as text archives.
+package hosttest
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/lldp"
+)
+
+// Archive is a parsed host capture. The text format is a sequence of
+// sections, each starting with a header line:
+//
+//	-- <path> --        file contents (e.g., a sysfs attribute)
+//	-- hex <path> --    binary file contents as whitespace-separated hex bytes
+//	-- link <path> --   symlink; the body is the target
+//	-- dir <path> --    empty directory
+//	-- cmd <cmdline> -- output of a command
+//	-- netlink <query> -- JSON reply to a netlink query (see FakeNetlink)
+//	-- lldp <ifname> -- LLDP frame received on an interface, as hex bytes
+//
+// Paths are relative to the host root. Lines before the first header
+// are comments.
+type Archive struct {
+	Files    map[string][]byte
+	Links    map[string]string
+	Dirs     []string
+	Commands map[string]string
+	Netlink  map[string]string
+	LLDP     map[string][]byte
+}
+
+// ParseArchive parses a host capture.
+func ParseArchive(data string) (*Archive, error) {
+	a := &Archive{
+		Files:    make(map[string][]byte),
+		Links:    make(map[string]string),
+		Commands: make(map[string]string),
+		Netlink:  make(map[string]string),
+		LLDP:     make(map[string][]byte),
+	}
+
+	var kind, name string
+	var body []string
+	flush := func() error {
+		if kind == "" {
+			return nil
+		}
+		content := strings.Join(body, "")
+		switch kind {
+		case "file":
+			a.Files[name] = []byte(content)
+		case "hex", "lldp":
+			b, err := hex.DecodeString(strings.Join(strings.Fields(content), ""))
+			if err != nil {
+				return fmt.Errorf("section %q: %w", name, err)
+			}
+			if kind == "lldp" {
+				a.LLDP[name] = b
+			} else {
+				a.Files[name] = b
+			}
+		case "link":
+			a.Links[name] = strings.TrimSpace(content)
+		case "dir":
+			a.Dirs = append(a.Dirs, name)
+		case "cmd":
+			a.Commands[name] = content
+		case "netlink":
+			a.Netlink[name] = content
+		}
+		return nil
+	}
+
+	for _, line := range strings.SplitAfter(data, "\n") {
+		header := strings.TrimRight(line, "\n")
+		if strings.HasPrefix(header, "-- ") && strings.HasSuffix(header, " --") && len(header) > 6 {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			body = nil
+			kind, name = "file", strings.TrimSpace(header[3:len(header)-3])
+			if k, rest, ok := strings.Cut(name, " "); ok {
+				switch k {
+				case "hex", "link", "dir", "cmd", "netlink", "lldp":
+					kind, name = k, rest
+				}
+			}
+			continue
+		}
+		if kind != "" {
+			body = append(body, line)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// WriteTo materializes the archive's filesystem under root.
+func (a *Archive) WriteTo(root string) error {
+	for _, dir := range a.Dirs {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			return err
+		}
+	}
+	for name, content := range a.Files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			return err
+		}
+	}
+	for name, target := range a.Links {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.Symlink(target, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CaptureTime is the time at which captured LLDP frames are recorded as
+// received. The neighbor table of a loaded host keeps this time, so
+// captured neighbors never expire.
+var CaptureTime = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// Load reads the capture at path, materializes it in a temporary directory
+// and returns a Host rooted there that replays the captured commands and
+// netlink replies. Captured LLDP frames populate the host's neighbor table.
+func Load(t testing.TB, path string) *host.Host {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read host capture: %v", err)
+	}
+	archive, err := ParseArchive(string(data))
+	if err != nil {
+		t.Fatalf("failed to parse host capture %s: %v", path, err)
+	}
+
+	root := t.TempDir()
+	if err := archive.WriteTo(root); err != nil {
+		t.Fatalf("failed to write host capture %s: %v", path, err)
+	}
+
+	h := host.New(root, &FakeRunner{Outputs: archive.Commands})
+	h.Netlink = &FakeNetlink{Replies: archive.Netlink}
+
+	if len(archive.LLDP) > 0 {
+		h.LLDP = lldp.NewTable(func() time.Time { return CaptureTime })
+		for ifName, frame := range archive.LLDP {
+			neighbor, err := lldp.Parse(frame)
+			if err != nil {
+				t.Fatalf("invalid LLDP frame for %s in %s: %v", ifName, path, err)
+			}
+			h.LLDP.Update(ifName, *neighbor)
+		}
+	}
+	return h
+}
@@ -0,0 +1,92 @@
+package hosttest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/netlink"
+)
+
+func TestParseArchive(t *testing.T) {
+	data := `# comment before the first section
+-- sys/class/net/eth0/mtu --
+1500
+-- hex sys/bus/pci/devices/0000:03:00.0/vpd --
+82 02 00
+41 42 78
+-- link sys/bus/pci/devices/0000:03:00.0/driver --
+../../../bus/pci/drivers/mlx5_core
+-- dir sys/bus/pci/devices/0000:03:00.0/infiniband/mlx5_0 --
+-- cmd mstflint -d 0000:03:00.0 q --
+FW Version:            16.35.3006
+PSID:                  MT_0000000011
+-- netlink vfs ens1f0np0 --
+[{"index": 0, "mac": "02:00:00:00:00:01", "vlan": 10}]
+-- netlink module-eeprom ens1f0np0 0x50 0 0 --
+11 07 00 00
+-- lldp ens1f0np0 --
+01 80 c2 00 00 0e
+`
+
+	archive, err := ParseArchive(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := string(archive.Files["sys/class/net/eth0/mtu"]); got != "1500\n" {
+		t.Errorf("file = %q, want %q", got, "1500\n")
+	}
+	if got := archive.Files["sys/bus/pci/devices/0000:03:00.0/vpd"]; string(got) != "\x82\x02\x00AB\x78" {
+		t.Errorf("hex file = %x", got)
+	}
+	if got := archive.Links["sys/bus/pci/devices/0000:03:00.0/driver"]; got != "../../../bus/pci/drivers/mlx5_core" {
+		t.Errorf("link = %q", got)
+	}
+	if len(archive.Dirs) != 1 || archive.Dirs[0] != "sys/bus/pci/devices/0000:03:00.0/infiniband/mlx5_0" {
+		t.Errorf("dirs = %v", archive.Dirs)
+	}
+	want := "FW Version:            16.35.3006\nPSID:                  MT_0000000011\n"
+	if got := archive.Commands["mstflint -d 0000:03:00.0 q"]; got != want {
+		t.Errorf("command output = %q, want %q", got, want)
+	}
+	if got := archive.LLDP["ens1f0np0"]; string(got) != "\x01\x80\xc2\x00\x00\x0e" {
+		t.Errorf("LLDP frame = %x", got)
+	}
+
+	netlinkFake := &FakeNetlink{Replies: archive.Netlink}
+	vfs, err := netlinkFake.LinkVFs("ens1f0np0")
+	if err != nil || len(vfs) != 1 || vfs[0].MAC != "02:00:00:00:00:01" || vfs[0].VLAN != 10 {
+		t.Errorf("LinkVFs() = %+v, %v", vfs, err)
+	}
+	if _, err := netlinkFake.LinkVFs("ens1f1np1"); err == nil {
+		t.Error("expected error for link without recorded reply")
+	}
+	page, err := netlinkFake.ModuleEEPROM("ens1f0np0", netlink.EEPROMRequest{I2CAddress: 0x50, Offset: 0, Length: 2})
+	if err != nil || string(page) != "\x11\x07" {
+		t.Errorf("ModuleEEPROM() = %x, %v", page, err)
+	}
+	if _, err := netlinkFake.ModuleEEPROM("ens1f1np1", netlink.EEPROMRequest{I2CAddress: 0x50, Length: 1}); !netlink.IsNotSupported(err) {
+		t.Errorf("ModuleEEPROM() error = %v, want not supported for port without recorded pages", err)
+	}
+}
+
+func TestParseArchive_InvalidHex(t *testing.T) {
+	if _, err := ParseArchive("-- hex vpd --\nzz\n"); err == nil {
+		t.Error("expected error for invalid hex")
+	}
+}
+
+func TestFakeRunner(t *testing.T) {
+	runner := &FakeRunner{Outputs: map[string]string{"lspci -D -d 15b3:": "0000:03:00.0 Ethernet controller\n"}}
+
+	output, err := runner.Run(context.Background(), "lspci", "-D", "-d", "15b3:")
+	if err != nil || string(output) != "0000:03:00.0 Ethernet controller\n" {
+		t.Errorf("Run() = %q, %v", output, err)
+	}
+	if _, err := runner.Run(context.Background(), "mstflint", "-d", "0000:03:00.0", "q"); err == nil {
+		t.Error("expected error for command without canned output")
+	}
+	if calls := runner.Calls(); len(calls) != 2 || calls[1] != "mstflint -d 0000:03:00.0 q" {
+		t.Errorf("Calls() = %v", calls)
+	}
+}
@@ -0,0 +1,178 @@
+package hosttest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/filanov/netctrl-agent/internal/netlink"
+)
+
+// FakeNetlink is a netlink.Client that replays recorded replies, for tests.
+// Replies are JSON keyed by query and arguments (e.g., "vfs ens1f0np0",
+// "link bond0", "devlink ports 0000:3b:00.0", "ethtool-stats ens1f0np0",
+// "drvinfo ens1f0np0", "channels ens1f0np0"), except module EEPROM pages, which are hex
+// bytes keyed "module-eeprom <ifname> <i2c address> <page> <bank>"
+// (e.g., "module-eeprom ens1f0np0 0x50 0 0"). Page 0 replies hold the lower
+// and upper page (256 bytes), other pages only the upper page (128 bytes).
+type FakeNetlink struct {
+	Replies map[string]string
+}
+
+// LinkVFs implements netlink.Client. Links without a recorded reply fail
+// with ENODEV, as the kernel does for unknown interfaces.
+func (f *FakeNetlink) LinkVFs(ifName string) ([]netlink.VFInfo, error) {
+	var vfs []netlink.VFInfo
+	if err := f.decode("vfs "+ifName, &vfs); err != nil {
+		return nil, err
+	}
+	return vfs, nil
+}
+
+// LinkInfo implements netlink.Client. Links without a recorded reply fail
+// with ENODEV.
+func (f *FakeNetlink) LinkInfo(ifName string) (*netlink.LinkInfo, error) {
+	var info netlink.LinkInfo
+	if err := f.decode("link "+ifName, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// DevlinkEswitch implements netlink.Client. Devices without a recorded
+// reply fail with EOPNOTSUPP, as for devices without an e-switch.
+func (f *FakeNetlink) DevlinkEswitch(device string) (*netlink.DevlinkEswitch, error) {
+	var eswitch netlink.DevlinkEswitch
+	if err := f.decodeOptional("devlink eswitch "+device, &eswitch); err != nil {
+		return nil, err
+	}
+	return &eswitch, nil
+}
+
+// DevlinkPorts implements netlink.Client. Devices without a recorded reply
+// have no ports, as a dump returns nothing for unregistered devices.
+func (f *FakeNetlink) DevlinkPorts(device string) ([]netlink.DevlinkPort, error) {
+	var ports []netlink.DevlinkPort
+	if err := f.decodeDump("devlink ports "+device, &ports); err != nil {
+		return nil, err
+	}
+	return ports, nil
+}
+
+// DevlinkHealthReporters implements netlink.Client. Devices without a
+// recorded reply have no reporters.
+func (f *FakeNetlink) DevlinkHealthReporters(device string) ([]netlink.DevlinkHealthReporter, error) {
+	var reporters []netlink.DevlinkHealthReporter
+	if err := f.decodeDump("devlink health "+device, &reporters); err != nil {
+		return nil, err
+	}
+	return reporters, nil
+}
+
+// ModuleEEPROM implements netlink.Client. Ports without recorded pages fail
+// with EOPNOTSUPP, as for drivers without module EEPROM access.
+func (f *FakeNetlink) ModuleEEPROM(ifName string, req netlink.EEPROMRequest) ([]byte, error) {
+	offset := int(req.Offset)
+	page, bank := req.Page, req.Bank
+	// The lower page is the same for every page
+	if offset < 128 {
+		page, bank = 0, 0
+	}
+
+	reply, ok := f.Replies[fmt.Sprintf("module-eeprom %s 0x%02x %d %d", ifName, req.I2CAddress, page, bank)]
+	if !ok {
+		return nil, &netlink.Error{Errno: int(syscall.EOPNOTSUPP)}
+	}
+	data, err := hex.DecodeString(strings.Join(strings.Fields(reply), ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid recorded module EEPROM page: %w", err)
+	}
+	if len(data) == 128 {
+		offset -= 128
+	}
+	end := offset + int(req.Length)
+	if offset < 0 || end > len(data) {
+		return nil, &netlink.Error{Errno: int(syscall.EINVAL)}
+	}
+	return data[offset:end], nil
+}
+
+// EthtoolStats implements netlink.Client. Netdevs without a recorded reply
+// fail with EOPNOTSUPP, as for drivers without statistics.
+func (f *FakeNetlink) EthtoolStats(ifName string) (map[string]uint64, error) {
+	var stats map[string]uint64
+	if err := f.decodeOptional("ethtool-stats "+ifName, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// DriverInfo implements netlink.Client. Netdevs without a recorded reply
+// fail with EOPNOTSUPP.
+func (f *FakeNetlink) DriverInfo(ifName string) (*netlink.DriverInfo, error) {
+	var info netlink.DriverInfo
+	if err := f.decodeOptional("drvinfo "+ifName, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// LinkModes implements netlink.Client. Netdevs without a recorded reply
+// fail with EOPNOTSUPP, as for drivers without link settings.
+func (f *FakeNetlink) LinkModes(ifName string) (*netlink.LinkModes, error) {
+	var modes netlink.LinkModes
+	if err := f.decodeOptional("linkmodes "+ifName, &modes); err != nil {
+		return nil, err
+	}
+	return &modes, nil
+}
+
+// FEC implements netlink.Client. Netdevs without a recorded reply fail
+// with EOPNOTSUPP, as for drivers without FEC control.
+func (f *FakeNetlink) FEC(ifName string) (*netlink.FECSettings, error) {
+	var fec netlink.FECSettings
+	if err := f.decodeOptional("fec "+ifName, &fec); err != nil {
+		return nil, err
+	}
+	return &fec, nil
+}
+
+// Channels implements netlink.Client. Netdevs without a recorded reply
+// fail with EOPNOTSUPP, as for drivers without channel control.
+func (f *FakeNetlink) Channels(ifName string) (*netlink.Channels, error) {
+	var channels netlink.Channels
+	if err := f.decodeOptional("channels "+ifName, &channels); err != nil {
+		return nil, err
+	}
+	return &channels, nil
+}
+
+// decodeOptional is like decode, but a missing reply fails with EOPNOTSUPP.
+func (f *FakeNetlink) decodeOptional(key string, v any) error {
+	if _, ok := f.Replies[key]; !ok {
+		return &netlink.Error{Errno: int(syscall.EOPNOTSUPP)}
+	}
+	return f.decode(key, v)
+}
+
+// decodeDump is like decode, but a missing reply is an empty dump.
+func (f *FakeNetlink) decodeDump(key string, v any) error {
+	if _, ok := f.Replies[key]; !ok {
+		return nil
+	}
+	return f.decode(key, v)
+}
+
+// decode unmarshals the reply recorded for key into v.
+func (f *FakeNetlink) decode(key string, v any) error {
+	reply, ok := f.Replies[key]
+	if !ok {
+		return &netlink.Error{Errno: int(syscall.ENODEV)}
+	}
+	if err := json.Unmarshal([]byte(reply), v); err != nil {
+		return fmt.Errorf("invalid recorded netlink reply %q: %w", key, err)
+	}
+	return nil
+}
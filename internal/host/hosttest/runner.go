@@ -0,0 +1,47 @@
+package hosttest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FakeRunner is a host.CommandRunner that returns canned outputs, for tests.
+// Outputs are keyed by the command line joined with spaces
+// (e.g., "mstflint -d 0000:03:00.0 q").
+type FakeRunner struct {
+	Outputs map[string]string
+	Errors  map[string]error
+
+	mu    sync.Mutex
+	calls []string
+}
+
+// Run returns the canned output for the command line. Commands without
+// an output or error fail as if the tool were not installed.
+func (f *FakeRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmdline := strings.Join(append([]string{name}, args...), " ")
+
+	f.mu.Lock()
+	f.calls = append(f.calls, cmdline)
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err, ok := f.Errors[cmdline]; ok {
+		return nil, err
+	}
+	if output, ok := f.Outputs[cmdline]; ok {
+		return []byte(output), nil
+	}
+	return nil, fmt.Errorf("%s: executable file not found in $PATH", name)
+}
+
+// Calls returns the command lines run so far.
+func (f *FakeRunner) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
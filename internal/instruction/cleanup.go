@@ -0,0 +1,73 @@
+package instruction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CleanupTimeout bounds how long registered cleanup functions may run after
+// an instruction has been cancelled.
+const CleanupTimeout = 30 * time.Second
+
+// CleanupFunc undoes partial work of a cancelled handler. It receives a fresh
+// context that is not cancelled together with the instruction.
+type CleanupFunc func(ctx context.Context) error
+
+// cleanupStack collects cleanup functions registered during one execution.
+type cleanupStack struct {
+	mu    sync.Mutex
+	funcs []CleanupFunc
+}
+
+type cleanupKey struct{}
+
+// RegisterCleanup registers fn to run if the instruction is cancelled and the
+// handler returns an error. Cleanups run in reverse registration order. It is a
+// no-op if the context was not created by Registry.Execute.
+func RegisterCleanup(ctx context.Context, fn CleanupFunc) {
+	stack, ok := ctx.Value(cleanupKey{}).(*cleanupStack)
+	if !ok {
+		return
+	}
+
+	stack.mu.Lock()
+	defer stack.mu.Unlock()
+	stack.funcs = append(stack.funcs, fn)
+}
+
+// withCleanupStack returns a context that accepts cleanup registrations.
+func withCleanupStack(ctx context.Context) (context.Context, *cleanupStack) {
+	stack := &cleanupStack{}
+	return context.WithValue(ctx, cleanupKey{}, stack), stack
+}
+
+// run executes the registered cleanups in reverse order and returns the
+// combined error.
+func (s *cleanupStack) run(ctx context.Context) error {
+	s.mu.Lock()
+	funcs := s.funcs
+	s.funcs = nil
+	s.mu.Unlock()
+
+	if len(funcs) == 0 {
+		return nil
+	}
+
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), CleanupTimeout)
+	defer cancel()
+
+	var errs []error
+	for i := len(funcs) - 1; i >= 0; i-- {
+		if err := funcs[i](cleanupCtx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("cleanup failed: %w", errors.Join(errs...))
+	}
+	return nil
+}
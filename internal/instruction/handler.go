@@ -2,6 +2,7 @@ package instruction
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
@@ -14,9 +15,36 @@ type Handler interface {
 	Execute(ctx context.Context, instruction *v1.Instruction) (string, error)
 }
 
+// Undoer is implemented by handlers that can compensate a completed execution,
+// for example to roll back earlier steps of a failed batch.
+type Undoer interface {
+	// Undo reverts the effect of a previous successful Execute call.
+	// result is the result data that Execute returned.
+	Undo(ctx context.Context, instruction *v1.Instruction, result string) error
+}
+
+// Deliverer is implemented by handlers that track which results reached the
+// server, e.g., to report changes since the last delivered result.
+type Deliverer interface {
+	// Delivered is called after the result of a successful Execute call
+	// for instructionID was submitted to the server.
+	Delivered(instructionID string)
+}
+
+// ErrUndoNotSupported is returned by Registry.Undo for handlers that do not implement Undoer.
+var ErrUndoNotSupported = errors.New("undo not supported")
+
+// Authorizer decides whether an instruction may be executed on this host.
+type Authorizer interface {
+	// Authorize returns nil if the instruction is allowed, or an error
+	// (typically a StatusError with StatusPolicyDenied) if it is not.
+	Authorize(ctx context.Context, instruction *v1.Instruction) error
+}
+
 // Registry manages instruction handlers and executes instructions.
 type Registry struct {
-	handlers map[v1.InstructionType]Handler
+	handlers   map[v1.InstructionType]Handler
+	authorizer Authorizer
 }
 
 // NewRegistry creates a new instruction handler registry.
@@ -31,9 +59,21 @@ func (r *Registry) Register(instructionType v1.InstructionType, handler Handler)
 	r.handlers[instructionType] = handler
 }
 
+// SetAuthorizer sets the authorizer consulted before every execution.
+// A nil authorizer allows all instructions. CANCEL is never authorized, so
+// a policy cannot keep an instruction it allowed from being cancelled.
+func (r *Registry) SetAuthorizer(authorizer Authorizer) {
+	r.authorizer = authorizer
+}
+
 // Execute processes an instruction using the registered handler.
 // Returns result data and error. If the instruction type is not registered,
-// returns an error.
+// or the authorizer rejects the instruction, returns an error. CANCEL
+// instructions bypass the authorizer.
+// If ctx is cancelled and the handler fails, cleanups registered with
+// RegisterCleanup are executed and the error is reported as StatusCancelled.
+// A handler that completes despite the cancellation keeps its result and its
+// cleanups do not run.
 func (r *Registry) Execute(ctx context.Context, instruction *v1.Instruction) (string, error) {
 	if instruction == nil {
 		return "", fmt.Errorf("instruction is nil")
@@ -44,7 +84,53 @@ func (r *Registry) Execute(ctx context.Context, instruction *v1.Instruction) (st
 		return "", fmt.Errorf("no handler registered for instruction type: %v", instruction.Type)
 	}
 
-	return handler.Execute(ctx, instruction)
+	if r.authorizer != nil && instruction.Type != TypeCancel {
+		if err := r.authorizer.Authorize(ctx, instruction); err != nil {
+			return "", err
+		}
+	}
+
+	execCtx, cleanups := withCleanupStack(ctx)
+	result, err := handler.Execute(execCtx, instruction)
+	if err == nil || ctx.Err() == nil {
+		return result, err
+	}
+
+	if cleanupErr := cleanups.run(ctx); cleanupErr != nil {
+		err = errors.Join(err, cleanupErr)
+	}
+	return "", &StatusError{
+		Status: StatusCancelled,
+		Err:    fmt.Errorf("%w (%v)", err, context.Cause(ctx)),
+	}
+}
+
+// Undo reverts a previously executed instruction using its handler's Undoer
+// implementation. Returns ErrUndoNotSupported if the handler cannot undo.
+func (r *Registry) Undo(ctx context.Context, instruction *v1.Instruction, result string) error {
+	if instruction == nil {
+		return fmt.Errorf("instruction is nil")
+	}
+
+	handler, ok := r.handlers[instruction.Type]
+	if !ok {
+		return fmt.Errorf("no handler registered for instruction type: %v", instruction.Type)
+	}
+
+	undoer, ok := handler.(Undoer)
+	if !ok {
+		return ErrUndoNotSupported
+	}
+
+	return undoer.Undo(ctx, instruction, result)
+}
+
+// Delivered notifies the handler of instructionType that the result of
+// instructionID reached the server, if the handler implements Deliverer.
+func (r *Registry) Delivered(instructionType v1.InstructionType, instructionID string) {
+	if deliverer, ok := r.handlers[instructionType].(Deliverer); ok {
+		deliverer.Delivered(instructionID)
+	}
 }
 
 // HasHandler returns true if a handler is registered for the given instruction type.
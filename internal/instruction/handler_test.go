@@ -3,6 +3,8 @@ package instruction
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
 	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
@@ -166,3 +168,206 @@ func TestRegistry_Execute_ContextCancellation(t *testing.T) {
 		t.Errorf("Execute() with cancelled context returned error: %v", err)
 	}
 }
+
+// denyAuthorizer rejects every instruction.
+type denyAuthorizer struct{}
+
+func (denyAuthorizer) Authorize(ctx context.Context, instruction *v1.Instruction) error {
+	return NewStatusError(StatusPolicyDenied, "denied by test policy")
+}
+
+func TestRegistry_Execute_Authorizer(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK, &mockHandler{result: "test"})
+	registry.SetAuthorizer(denyAuthorizer{})
+
+	instruction := &v1.Instruction{
+		Id:   "test-denied",
+		Type: v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK,
+	}
+
+	result, err := registry.Execute(context.Background(), instruction)
+	if err == nil {
+		t.Fatal("Execute() expected error from authorizer")
+	}
+	if result != "" {
+		t.Errorf("Execute() result = %q, want empty", result)
+	}
+	if status := StatusOf(err); status != StatusPolicyDenied {
+		t.Errorf("StatusOf() = %s, want %s", status, StatusPolicyDenied)
+	}
+
+	registry.SetAuthorizer(nil)
+	if _, err := registry.Execute(context.Background(), instruction); err != nil {
+		t.Errorf("Execute() without authorizer returned error: %v", err)
+	}
+}
+
+func TestRegistry_Execute_AuthorizerSkipsCancel(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(TypeCancel, &mockHandler{result: "cancelled"})
+	registry.SetAuthorizer(denyAuthorizer{})
+
+	result, err := registry.Execute(context.Background(), &v1.Instruction{
+		Id:   "test-cancel",
+		Type: TypeCancel,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v, want CANCEL to bypass the authorizer", err)
+	}
+	if result != "cancelled" {
+		t.Errorf("Execute() result = %q, want %q", result, "cancelled")
+	}
+}
+
+func TestStatusOf(t *testing.T) {
+	if status := StatusOf(nil); status != StatusSucceeded {
+		t.Errorf("StatusOf(nil) = %s, want %s", status, StatusSucceeded)
+	}
+	if status := StatusOf(errors.New("boom")); status != StatusFailed {
+		t.Errorf("StatusOf(error) = %s, want %s", status, StatusFailed)
+	}
+	wrapped := fmt.Errorf("wrapped: %w", NewStatusError(StatusPolicyDenied, "nope"))
+	if status := StatusOf(wrapped); status != StatusPolicyDenied {
+		t.Errorf("StatusOf(wrapped) = %s, want %s", status, StatusPolicyDenied)
+	}
+}
+
+// blockingHandler registers a cleanup and waits for cancellation.
+type blockingHandler struct {
+	cleanups []string
+}
+
+func (h *blockingHandler) Execute(ctx context.Context, instruction *v1.Instruction) (string, error) {
+	RegisterCleanup(ctx, func(ctx context.Context) error {
+		h.cleanups = append(h.cleanups, "first")
+		return nil
+	})
+	RegisterCleanup(ctx, func(ctx context.Context) error {
+		if ctx.Err() != nil {
+			return errors.New("cleanup context is cancelled")
+		}
+		h.cleanups = append(h.cleanups, "second")
+		return nil
+	})
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestRegistry_Execute_CancelRunsCleanup(t *testing.T) {
+	registry := NewRegistry()
+	handler := &blockingHandler{}
+	registry.Register(v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE, handler)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	go cancel(errors.New("cancelled by test"))
+
+	_, err := registry.Execute(ctx, &v1.Instruction{
+		Id:   "test-cancel-cleanup",
+		Type: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE,
+	})
+	if status := StatusOf(err); status != StatusCancelled {
+		t.Fatalf("StatusOf() = %s, want %s (err: %v)", status, StatusCancelled, err)
+	}
+
+	want := []string{"second", "first"}
+	if len(handler.cleanups) != len(want) {
+		t.Fatalf("cleanups = %v, want %v", handler.cleanups, want)
+	}
+	for i := range want {
+		if handler.cleanups[i] != want[i] {
+			t.Errorf("cleanups = %v, want %v", handler.cleanups, want)
+			break
+		}
+	}
+}
+
+// completingHandler registers a cleanup and succeeds after cancellation.
+type completingHandler struct {
+	cleanedUp bool
+}
+
+func (h *completingHandler) Execute(ctx context.Context, instruction *v1.Instruction) (string, error) {
+	RegisterCleanup(ctx, func(ctx context.Context) error {
+		h.cleanedUp = true
+		return nil
+	})
+	<-ctx.Done()
+	return `{"applied":true}`, nil
+}
+
+func TestRegistry_Execute_CancelAfterSuccessKeepsResult(t *testing.T) {
+	registry := NewRegistry()
+	handler := &completingHandler{}
+	registry.Register(v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE, handler)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	go cancel(errors.New("cancelled by test"))
+
+	result, err := registry.Execute(ctx, &v1.Instruction{
+		Id:   "test-cancel-success",
+		Type: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE,
+	})
+	if err != nil || result != `{"applied":true}` {
+		t.Fatalf("Execute() = %q, %v, want the handler's result", result, err)
+	}
+	if handler.cleanedUp {
+		t.Error("cleanup ran after the handler succeeded")
+	}
+}
+
+// deliveringHandler records the instructions whose results were delivered.
+type deliveringHandler struct {
+	mockHandler
+	delivered []string
+}
+
+func (h *deliveringHandler) Delivered(instructionID string) {
+	h.delivered = append(h.delivered, instructionID)
+}
+
+func TestRegistry_Delivered(t *testing.T) {
+	registry := NewRegistry()
+	handler := &deliveringHandler{}
+	registry.Register(v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE, handler)
+	registry.Register(v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK, &mockHandler{})
+
+	registry.Delivered(v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE, "collect-1")
+	// Handlers without Deliverer and unregistered types are ignored
+	registry.Delivered(v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK, "health-1")
+	registry.Delivered(v1.InstructionType_INSTRUCTION_TYPE_POLL_INTERVAL, "poll-1")
+
+	if len(handler.delivered) != 1 || handler.delivered[0] != "collect-1" {
+		t.Errorf("delivered = %v, want [collect-1]", handler.delivered)
+	}
+}
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    v1.InstructionType
+		wantErr bool
+	}{
+		{name: "COLLECT_HARDWARE", want: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE},
+		{name: "instruction_type_health_check", want: v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK},
+		{name: "CANCEL", want: TypeCancel},
+		{name: "collect_counters", want: TypeCollectCounters},
+		{name: "VERIFY_CABLING", want: TypeVerifyCabling},
+		{name: "REBOOT", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseType(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseType() = %v, want %v", got, tt.want)
+			}
+			if !tt.wantErr && TypeName(got) != strings.TrimPrefix(strings.ToUpper(tt.name), "INSTRUCTION_TYPE_") {
+				t.Errorf("TypeName() = %s", TypeName(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+)
+
+// ConditionPCIeErrorsIncreasing is reported when the AER error counts of a
+// NIC or its upstream port grew since the previous collection.
+const ConditionPCIeErrorsIncreasing = "pcie_errors_increasing"
+
+// AERCounters are the PCIe Advanced Error Reporting counts of a PCI
+// function, from the aer_dev_correctable, aer_dev_nonfatal and
+// aer_dev_fatal sysfs attributes.
+type AERCounters struct {
+	Address     string    `json:"address"`
+	Correctable AERErrors `json:"correctable"`
+	NonFatal    AERErrors `json:"nonfatal"`
+	Fatal       AERErrors `json:"fatal"`
+	// Delta is the increase since the last hardware collection whose result
+	// reached the server; it is omitted until one has.
+	Delta *AERDelta `json:"delta,omitempty"`
+	// Upstream are the counts of the port the function is attached to
+	// (a root port or switch downstream port).
+	Upstream *AERCounters `json:"upstream,omitempty"`
+}
+
+// AERErrors is the total error count of one severity and its breakdown by
+// error type (e.g., "BadTLP", "CmpltTO").
+type AERErrors struct {
+	Total  uint64            `json:"total"`
+	Counts map[string]uint64 `json:"counts,omitempty"`
+}
+
+// AERDelta is the increase of the AER counts between two collections. The
+// per-type counts only list the error types that increased.
+type AERDelta struct {
+	// Since is when the delivered counts were collected.
+	Since       time.Time `json:"since"`
+	Correctable AERErrors `json:"correctable"`
+	NonFatal    AERErrors `json:"nonfatal"`
+	Fatal       AERErrors `json:"fatal"`
+}
+
+// Increased reports whether any error count increased.
+func (d *AERDelta) Increased() bool {
+	return d != nil && d.Correctable.Total+d.NonFatal.Total+d.Fatal.Total > 0
+}
+
+// collectAER reads the AER counts of a PCI function and its upstream port.
+// It returns nil if the kernel does not report AER for the function.
+func collectAER(h *host.Host, pciAddr string) *AERCounters {
+	devPath := filepath.Join(sysBusPCIDevices, pciAddr)
+	aer := readAER(h, devPath, pciAddr)
+	if aer == nil {
+		return nil
+	}
+
+	if resolved, err := h.EvalSymlinks(devPath); err == nil {
+		parent := filepath.Dir(resolved)
+		if pciAddressRegex.MatchString(filepath.Base(parent)) {
+			aer.Upstream = readAER(h, parent, filepath.Base(parent))
+		}
+	}
+
+	return aer
+}
+
+// readAER reads the AER counts from a device directory.
+func readAER(h *host.Host, devPath, addr string) *AERCounters {
+	aer := &AERCounters{Address: addr}
+	found := false
+	for _, severity := range []struct {
+		attr   string
+		errors *AERErrors
+	}{
+		{"aer_dev_correctable", &aer.Correctable},
+		{"aer_dev_nonfatal", &aer.NonFatal},
+		{"aer_dev_fatal", &aer.Fatal},
+	} {
+		data, err := h.ReadFile(filepath.Join(devPath, severity.attr))
+		if err != nil {
+			continue
+		}
+		found = true
+		*severity.errors = parseAERErrors(string(data))
+	}
+	if !found {
+		return nil
+	}
+	return aer
+}
+
+// parseAERErrors parses an aer_dev_* attribute: one "<type> <count>" line
+// per error type and a TOTAL_ERR_* line.
+func parseAERErrors(data string) AERErrors {
+	errors := AERErrors{Counts: make(map[string]uint64)}
+	hasTotal := false
+	var sum uint64
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		count, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(fields[0], "TOTAL_ERR_") {
+			errors.Total = count
+			hasTotal = true
+			continue
+		}
+		errors.Counts[fields[0]] = count
+		sum += count
+	}
+	if !hasTotal {
+		errors.Total = sum
+	}
+	return errors
+}
+
+// aerDelta returns the increase from prev to cur. Counts lower than before
+// mean the device was removed and re-added (e.g., after a reset), which
+// restarts its counters, so they are taken as the increase.
+func aerDelta(prev, cur *AERCounters, since time.Time) *AERDelta {
+	if prev.Correctable.Total > cur.Correctable.Total ||
+		prev.NonFatal.Total > cur.NonFatal.Total ||
+		prev.Fatal.Total > cur.Fatal.Total {
+		prev = &AERCounters{}
+	}
+	return &AERDelta{
+		Since:       since,
+		Correctable: aerErrorsDelta(prev.Correctable, cur.Correctable),
+		NonFatal:    aerErrorsDelta(prev.NonFatal, cur.NonFatal),
+		Fatal:       aerErrorsDelta(prev.Fatal, cur.Fatal),
+	}
+}
+
+// aerErrorsDelta returns the increase of one severity's counts.
+func aerErrorsDelta(prev, cur AERErrors) AERErrors {
+	delta := AERErrors{Total: cur.Total - prev.Total}
+	for name, count := range cur.Counts {
+		if count > prev.Counts[name] {
+			if delta.Counts == nil {
+				delta.Counts = make(map[string]uint64)
+			}
+			delta.Counts[name] = count - prev.Counts[name]
+		}
+	}
+	return delta
+}
+
+// maxPendingAER bounds the collections whose results await delivery.
+const maxPendingAER = 32
+
+// aerHistory keeps the AER counts of the last hardware collection whose
+// result reached the server, to report deltas against it. It is safe for
+// concurrent use.
+type aerHistory struct {
+	mu   sync.Mutex
+	at   time.Time
+	prev map[string]AERCounters // keyed by PCI address
+	// pending are the counts of collections whose results have not been
+	// delivered yet, keyed by instruction ID
+	pending map[string]aerSnapshot
+}
+
+// aerSnapshot are the AER counts of one collection.
+type aerSnapshot struct {
+	at     time.Time
+	counts map[string]AERCounters
+}
+
+// update sets the deltas of the AER counts of nics against the last
+// delivered collection and adds a condition to the NICs whose errors
+// increased. The counts replace the delivered ones once commit is called
+// for instructionID.
+func (a *aerHistory) update(instructionID string, nics []NICInfo, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	counts := make(map[string]AERCounters)
+	for i := range nics {
+		nic := &nics[i]
+		if nic.AER == nil {
+			continue
+		}
+
+		var increased []string
+		for _, aer := range []*AERCounters{nic.AER, nic.AER.Upstream} {
+			if aer == nil {
+				continue
+			}
+			if prev, ok := a.prev[aer.Address]; ok {
+				aer.Delta = aerDelta(&prev, aer, a.at)
+				if aer.Delta.Increased() {
+					increased = append(increased, fmt.Sprintf("%s (%s)", aer.Address, formatAERDelta(aer.Delta)))
+				}
+			}
+			counts[aer.Address] = AERCounters{
+				Correctable: aer.Correctable,
+				NonFatal:    aer.NonFatal,
+				Fatal:       aer.Fatal,
+			}
+		}
+
+		if len(increased) > 0 {
+			nic.Conditions = append(nic.Conditions, Condition{
+				Type:    ConditionPCIeErrorsIncreasing,
+				Message: fmt.Sprintf("PCIe AER errors increased since %s: %s", a.at.UTC().Format(time.RFC3339), strings.Join(increased, ", ")),
+			})
+		}
+	}
+
+	if a.pending == nil {
+		a.pending = make(map[string]aerSnapshot)
+	}
+	if len(a.pending) >= maxPendingAER {
+		oldest := ""
+		for id, snapshot := range a.pending {
+			if oldest == "" || snapshot.at.Before(a.pending[oldest].at) {
+				oldest = id
+			}
+		}
+		delete(a.pending, oldest)
+	}
+	a.pending[instructionID] = aerSnapshot{at: now, counts: counts}
+}
+
+// commit makes the counts of the collection run by instructionID the base
+// of later deltas, unless a later collection was delivered first.
+func (a *aerHistory) commit(instructionID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	snapshot, ok := a.pending[instructionID]
+	if !ok {
+		return
+	}
+	delete(a.pending, instructionID)
+	if snapshot.at.Before(a.at) {
+		return
+	}
+
+	a.prev = snapshot.counts
+	a.at = snapshot.at
+	// Collections older than the delivered one can no longer become the base
+	for id, pending := range a.pending {
+		if !pending.at.After(a.at) {
+			delete(a.pending, id)
+		}
+	}
+}
+
+// formatAERDelta formats the non-zero increases of a delta, e.g.,
+// "3 correctable, 1 fatal".
+func formatAERDelta(d *AERDelta) string {
+	var parts []string
+	for _, severity := range []struct {
+		name  string
+		count uint64
+	}{
+		{"correctable", d.Correctable.Total},
+		{"nonfatal", d.NonFatal.Total},
+		{"fatal", d.Fatal.Total},
+	} {
+		if severity.count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", severity.count, severity.name))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
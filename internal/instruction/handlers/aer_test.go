@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAERErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want AERErrors
+	}{
+		{
+			name: "correctable",
+			data: "RxErr 0\nBadTLP 3\nBadDLLP 12\nTOTAL_ERR_COR 15\n",
+			want: AERErrors{Total: 15, Counts: map[string]uint64{"RxErr": 0, "BadTLP": 3, "BadDLLP": 12}},
+		},
+		{
+			// Kernels before 5.1 have no TOTAL_ERR_* line
+			name: "without total",
+			data: "DLP 1\nCmpltTO 2\n",
+			want: AERErrors{Total: 3, Counts: map[string]uint64{"DLP": 1, "CmpltTO": 2}},
+		},
+		{
+			name: "malformed lines",
+			data: "DLP x\nSDES\nTLP 4\n",
+			want: AERErrors{Total: 4, Counts: map[string]uint64{"TLP": 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseAERErrors(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAERErrors() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAERHistory(t *testing.T) {
+	first := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	aerNIC := func(nicCorrectable, portFatal uint64) []NICInfo {
+		return []NICInfo{{
+			PCIAddress: "0000:3b:00.0",
+			AER: &AERCounters{
+				Address:     "0000:3b:00.0",
+				Correctable: AERErrors{Total: nicCorrectable, Counts: map[string]uint64{"BadTLP": nicCorrectable, "RxErr": 0}},
+				Upstream: &AERCounters{
+					Address: "0000:3a:00.0",
+					Fatal:   AERErrors{Total: portFatal, Counts: map[string]uint64{"CmpltTO": portFatal}},
+				},
+			},
+		}}
+	}
+
+	var history aerHistory
+
+	// The first collection has nothing to compare with
+	nics := aerNIC(2, 0)
+	history.update("collect-1", nics, first)
+	if nics[0].AER.Delta != nil || nics[0].AER.Upstream.Delta != nil || len(nics[0].Conditions) != 0 {
+		t.Fatalf("first collection: delta %+v, conditions %+v", nics[0].AER.Delta, nics[0].Conditions)
+	}
+
+	// Deltas are relative to delivered collections only
+	nics = aerNIC(2, 0)
+	history.update("collect-2", nics, first.Add(time.Minute))
+	if nics[0].AER.Delta != nil {
+		t.Fatalf("undelivered base: delta %+v", nics[0].AER.Delta)
+	}
+	history.commit("collect-1")
+
+	// Unchanged counts have a zero delta and no condition
+	nics = aerNIC(2, 0)
+	history.update("collect-3", nics, first.Add(2*time.Minute))
+	want := &AERDelta{Since: first}
+	if !reflect.DeepEqual(nics[0].AER.Delta, want) || len(nics[0].Conditions) != 0 {
+		t.Fatalf("unchanged: delta %+v, conditions %+v", nics[0].AER.Delta, nics[0].Conditions)
+	}
+	history.commit("collect-3")
+
+	// A collection delivered after a later one does not become the base
+	history.commit("collect-2")
+
+	// Increases on the function and its upstream port are reported by
+	// every collection until one of them is delivered
+	for _, id := range []string{"collect-4", "collect-5"} {
+		nics = aerNIC(5, 1)
+		history.update(id, nics, first.Add(3*time.Minute))
+		want = &AERDelta{Since: first.Add(2 * time.Minute), Correctable: AERErrors{Total: 3, Counts: map[string]uint64{"BadTLP": 3}}}
+		if got := nics[0].AER.Delta; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: delta = %+v, want %+v", id, got, want)
+		}
+		wantUpstream := &AERDelta{Since: first.Add(2 * time.Minute), Fatal: AERErrors{Total: 1, Counts: map[string]uint64{"CmpltTO": 1}}}
+		if got := nics[0].AER.Upstream.Delta; !reflect.DeepEqual(got, wantUpstream) {
+			t.Errorf("%s: upstream delta = %+v, want %+v", id, got, wantUpstream)
+		}
+		if len(nics[0].Conditions) != 1 || nics[0].Conditions[0].Type != ConditionPCIeErrorsIncreasing {
+			t.Fatalf("%s: conditions = %+v", id, nics[0].Conditions)
+		}
+		if msg := nics[0].Conditions[0].Message; !strings.Contains(msg, "0000:3b:00.0 (3 correctable)") || !strings.Contains(msg, "0000:3a:00.0 (1 fatal)") {
+			t.Errorf("%s: condition message = %q", id, msg)
+		}
+	}
+	history.commit("collect-5")
+
+	// Lower counts after a reset are taken as new errors
+	nics = aerNIC(1, 1)
+	history.update("collect-6", nics, first.Add(4*time.Minute))
+	if got := nics[0].AER.Delta.Correctable; got.Total != 1 || got.Counts["BadTLP"] != 1 {
+		t.Errorf("delta after reset = %+v", got)
+	}
+	if len(history.pending) != 1 {
+		t.Errorf("pending collections = %d, want 1", len(history.pending))
+	}
+
+}
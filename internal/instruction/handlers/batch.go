@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/filanov/netctrl-agent/internal/instruction"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+// Batch step failure policies.
+const (
+	// OnFailureAbort stops the batch; completed steps are kept.
+	OnFailureAbort = "abort"
+	// OnFailureContinue records the failure and runs the next step.
+	OnFailureContinue = "continue"
+	// OnFailureRollback stops the batch and undoes completed steps in reverse order.
+	OnFailureRollback = "rollback"
+)
+
+// Batch step statuses reported in BatchResult.
+const (
+	StepSucceeded      = "succeeded"
+	StepFailed         = "failed"
+	StepSkipped        = "skipped"
+	StepRolledBack     = "rolled_back"
+	StepRollbackFailed = "rollback_failed"
+	StepNotUndoable    = "not_undoable"
+)
+
+// BatchPayload represents the JSON payload for BATCH instructions.
+type BatchPayload struct {
+	Steps []BatchStep `json:"steps"`
+}
+
+// BatchStep is a single sub-instruction of a batch.
+type BatchStep struct {
+	// ID identifies the step in the result; defaults to its 1-based index.
+	ID string `json:"id,omitempty"`
+	// Type is the instruction type name (e.g., "POLL_INTERVAL").
+	Type string `json:"type"`
+	// Payload is the sub-instruction payload, either a JSON object or a JSON-encoded string.
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// OnFailure is one of "abort" (default), "continue" or "rollback".
+	OnFailure string `json:"on_failure,omitempty"`
+}
+
+// BatchResult reports the outcome of each step of a batch.
+type BatchResult struct {
+	Status string            `json:"status"`
+	Steps  []BatchStepResult `json:"steps"`
+}
+
+// BatchStepResult is the outcome of a single batch step.
+type BatchStepResult struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Status string          `json:"status"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// BatchError is returned when a batch is aborted or rolled back.
+// It carries the per-step report so the caller can see partial progress.
+type BatchError struct {
+	Step   string
+	Err    error
+	Report BatchResult
+}
+
+// Error implements the error interface.
+func (e *BatchError) Error() string {
+	report, _ := json.Marshal(e.Report)
+	return fmt.Sprintf("batch %s at step %s: %v: %s", e.Report.Status, e.Step, e.Err, report)
+}
+
+// Unwrap returns the error of the failed step.
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// maxPendingBatches bounds the batches whose results await delivery.
+const maxPendingBatches = 32
+
+// BatchHandler handles BATCH instructions by executing each step through
+// the instruction registry, so local policy, cancellation and progress
+// reporting apply to every step.
+type BatchHandler struct {
+	registry *instruction.Registry
+
+	mu sync.Mutex
+	// pending are the succeeded steps of batches whose results have not
+	// been delivered yet, keyed by batch instruction ID, in completion order
+	pending      map[string][]*v1.Instruction
+	pendingOrder []string
+}
+
+// NewBatchHandler creates a new batch handler that dispatches steps to registry.
+func NewBatchHandler(registry *instruction.Registry) *BatchHandler {
+	return &BatchHandler{
+		registry: registry,
+	}
+}
+
+// completedStep is a step that succeeded and may need to be undone.
+type completedStep struct {
+	index       int
+	instruction *v1.Instruction
+	result      string
+}
+
+// Execute processes a BATCH instruction.
+func (h *BatchHandler) Execute(ctx context.Context, inst *v1.Instruction) (string, error) {
+	if inst == nil {
+		return "", fmt.Errorf("instruction is nil")
+	}
+
+	steps, err := parseBatchSteps(inst)
+	if err != nil {
+		return "", err
+	}
+
+	report := BatchResult{
+		Status: "completed",
+		Steps:  make([]BatchStepResult, len(steps)),
+	}
+	for i, step := range steps {
+		report.Steps[i] = BatchStepResult{
+			ID:     step.ID,
+			Type:   instruction.TypeName(step.instruction.Type),
+			Status: StepSkipped,
+		}
+	}
+
+	var completed []completedStep
+
+	for i, step := range steps {
+		instruction.ReportProgress(ctx, i*100/len(steps), "step",
+			fmt.Sprintf("step %d/%d %s", i+1, len(steps), step.ID))
+
+		result, err := h.registry.Execute(ctx, step.instruction)
+		if err == nil {
+			report.Steps[i].Status = StepSucceeded
+			report.Steps[i].Result = resultJSON(result)
+			completed = append(completed, completedStep{index: i, instruction: step.instruction, result: result})
+			continue
+		}
+
+		report.Steps[i].Status = StepFailed
+		report.Steps[i].Error = err.Error()
+
+		// A cancelled batch cannot continue with later steps
+		if step.OnFailure == OnFailureContinue && ctx.Err() == nil {
+			report.Status = "completed_with_errors"
+			continue
+		}
+
+		if step.OnFailure == OnFailureRollback {
+			report.Status = "rolled_back"
+			h.rollback(ctx, completed, &report)
+		} else {
+			report.Status = "aborted"
+		}
+
+		return "", &BatchError{Step: step.ID, Err: err, Report: report}
+	}
+
+	instruction.ReportProgress(ctx, 100, "done", report.Status)
+
+	resultData, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal batch result: %w", err)
+	}
+
+	h.addPending(inst.Id, completed)
+	return string(resultData), nil
+}
+
+// addPending remembers the succeeded steps of a batch until its result is
+// delivered, dropping the oldest batch beyond maxPendingBatches.
+func (h *BatchHandler) addPending(batchID string, completed []completedStep) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.pending == nil {
+		h.pending = make(map[string][]*v1.Instruction)
+	}
+	if _, ok := h.pending[batchID]; !ok {
+		if len(h.pendingOrder) >= maxPendingBatches {
+			delete(h.pending, h.pendingOrder[0])
+			h.pendingOrder = h.pendingOrder[1:]
+		}
+		h.pendingOrder = append(h.pendingOrder, batchID)
+	}
+	steps := make([]*v1.Instruction, len(completed))
+	for i, step := range completed {
+		steps[i] = step.instruction
+	}
+	h.pending[batchID] = steps
+}
+
+// Delivered implements instruction.Deliverer: the result of every
+// succeeded step reached the server with the batch result.
+func (h *BatchHandler) Delivered(instructionID string) {
+	h.mu.Lock()
+	steps, ok := h.pending[instructionID]
+	delete(h.pending, instructionID)
+	for i, id := range h.pendingOrder {
+		if id == instructionID {
+			h.pendingOrder = append(h.pendingOrder[:i], h.pendingOrder[i+1:]...)
+			break
+		}
+	}
+	h.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	for _, step := range steps {
+		h.registry.Delivered(step.Type, step.Id)
+	}
+}
+
+// rollback undoes completed steps in reverse order. It uses a context that
+// is not cancelled together with the batch, so compensation still runs after
+// a cancellation.
+func (h *BatchHandler) rollback(ctx context.Context, completed []completedStep, report *BatchResult) {
+	undoCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), instruction.CleanupTimeout)
+	defer cancel()
+
+	for i := len(completed) - 1; i >= 0; i-- {
+		step := completed[i]
+		err := h.registry.Undo(undoCtx, step.instruction, step.result)
+		switch {
+		case err == nil:
+			report.Steps[step.index].Status = StepRolledBack
+		case errors.Is(err, instruction.ErrUndoNotSupported):
+			report.Steps[step.index].Status = StepNotUndoable
+		default:
+			report.Steps[step.index].Status = StepRollbackFailed
+			report.Steps[step.index].Error = err.Error()
+		}
+	}
+}
+
+// parsedStep is a validated batch step ready for execution.
+type parsedStep struct {
+	BatchStep
+	instruction *v1.Instruction
+}
+
+// parseBatchSteps parses and validates the batch payload.
+func parseBatchSteps(inst *v1.Instruction) ([]parsedStep, error) {
+	var payload BatchPayload
+	if err := json.Unmarshal([]byte(inst.Payload), &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse batch payload: %w", err)
+	}
+
+	if len(payload.Steps) == 0 {
+		return nil, fmt.Errorf("batch has no steps")
+	}
+
+	steps := make([]parsedStep, 0, len(payload.Steps))
+	for i, step := range payload.Steps {
+		if step.ID == "" {
+			step.ID = fmt.Sprintf("%d", i+1)
+		}
+
+		switch step.OnFailure {
+		case "":
+			step.OnFailure = OnFailureAbort
+		case OnFailureAbort, OnFailureContinue, OnFailureRollback:
+		default:
+			return nil, fmt.Errorf("step %s: invalid on_failure %q", step.ID, step.OnFailure)
+		}
+
+		stepType, err := instruction.ParseType(step.Type)
+		if err != nil {
+			return nil, fmt.Errorf("step %s: %w", step.ID, err)
+		}
+
+		stepPayload, err := stepPayloadString(step.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("step %s: %w", step.ID, err)
+		}
+
+		steps = append(steps, parsedStep{
+			BatchStep: step,
+			instruction: &v1.Instruction{
+				Id:        inst.Id + "/" + step.ID,
+				Type:      stepType,
+				Payload:   stepPayload,
+				CreatedAt: inst.CreatedAt,
+			},
+		})
+	}
+
+	return steps, nil
+}
+
+// stepPayloadString converts a step payload to the string form handlers expect.
+func stepPayloadString(raw json.RawMessage) (string, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return "", nil
+	}
+
+	if strings.HasPrefix(trimmed, `"`) {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", fmt.Errorf("invalid payload: %w", err)
+		}
+		return s, nil
+	}
+
+	return trimmed, nil
+}
+
+// resultJSON embeds a handler result in the batch report, quoting it if it is
+// not valid JSON.
+func resultJSON(result string) json.RawMessage {
+	if result == "" {
+		return nil
+	}
+	if json.Valid([]byte(result)) {
+		return json.RawMessage(result)
+	}
+	quoted, _ := json.Marshal(result)
+	return quoted
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/instruction"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+// recordingHandler records executions and undos, failing for payloads containing "fail".
+type recordingHandler struct {
+	log *[]string
+}
+
+func (h *recordingHandler) Execute(ctx context.Context, inst *v1.Instruction) (string, error) {
+	*h.log = append(*h.log, "exec "+inst.Id)
+	if strings.Contains(inst.Payload, "fail") {
+		return "", errors.New("step failed")
+	}
+	return `{"ok":true}`, nil
+}
+
+// undoableHandler is a recordingHandler that supports undo.
+type undoableHandler struct {
+	recordingHandler
+}
+
+func (h *undoableHandler) Undo(ctx context.Context, inst *v1.Instruction, result string) error {
+	*h.log = append(*h.log, "undo "+inst.Id)
+	return nil
+}
+
+// deliverableHandler is a recordingHandler that records deliveries.
+type deliverableHandler struct {
+	recordingHandler
+}
+
+func (h *deliverableHandler) Delivered(instructionID string) {
+	*h.log = append(*h.log, "delivered "+instructionID)
+}
+
+func newBatchTestRegistry(log *[]string) *instruction.Registry {
+	registry := instruction.NewRegistry()
+	registry.Register(v1.InstructionType_INSTRUCTION_TYPE_POLL_INTERVAL, &undoableHandler{recordingHandler{log: log}})
+	registry.Register(v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK, &recordingHandler{log: log})
+	registry.Register(instruction.TypeBatch, NewBatchHandler(registry))
+	return registry
+}
+
+func TestBatchHandler_Execute(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       string
+		expectedError bool
+		expectedLog   []string
+		expectedSteps []string
+	}{
+		{
+			name: "all steps succeed",
+			payload: `{"steps": [
+				{"id": "mtu-p0", "type": "POLL_INTERVAL", "payload": {"port": 0}},
+				{"id": "mtu-p1", "type": "POLL_INTERVAL", "payload": "{\"port\": 1}"},
+				{"id": "verify", "type": "HEALTH_CHECK"}
+			]}`,
+			expectedLog:   []string{"exec b/mtu-p0", "exec b/mtu-p1", "exec b/verify"},
+			expectedSteps: []string{StepSucceeded, StepSucceeded, StepSucceeded},
+		},
+		{
+			name: "abort keeps completed steps",
+			payload: `{"steps": [
+				{"id": "a", "type": "POLL_INTERVAL"},
+				{"id": "b", "type": "HEALTH_CHECK", "payload": {"mode": "fail"}},
+				{"id": "c", "type": "POLL_INTERVAL"}
+			]}`,
+			expectedError: true,
+			expectedLog:   []string{"exec b/a", "exec b/b"},
+			expectedSteps: []string{StepSucceeded, StepFailed, StepSkipped},
+		},
+		{
+			name: "continue runs remaining steps",
+			payload: `{"steps": [
+				{"id": "a", "type": "HEALTH_CHECK", "payload": {"mode": "fail"}, "on_failure": "continue"},
+				{"id": "b", "type": "POLL_INTERVAL"}
+			]}`,
+			expectedLog:   []string{"exec b/a", "exec b/b"},
+			expectedSteps: []string{StepFailed, StepSucceeded},
+		},
+		{
+			name: "rollback undoes completed steps in reverse order",
+			payload: `{"steps": [
+				{"id": "a", "type": "POLL_INTERVAL"},
+				{"id": "b", "type": "HEALTH_CHECK"},
+				{"id": "c", "type": "POLL_INTERVAL"},
+				{"id": "d", "type": "POLL_INTERVAL", "payload": {"mode": "fail"}, "on_failure": "rollback"}
+			]}`,
+			expectedError: true,
+			expectedLog:   []string{"exec b/a", "exec b/b", "exec b/c", "exec b/d", "undo b/c", "undo b/a"},
+			expectedSteps: []string{StepRolledBack, StepNotUndoable, StepRolledBack, StepFailed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log []string
+			registry := newBatchTestRegistry(&log)
+
+			result, err := registry.Execute(context.Background(), &v1.Instruction{
+				Id:      "b",
+				Type:    instruction.TypeBatch,
+				Payload: tt.payload,
+			})
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Execute() error = %v, expectedError %v", err, tt.expectedError)
+			}
+
+			if strings.Join(log, ",") != strings.Join(tt.expectedLog, ",") {
+				t.Errorf("execution log = %v, want %v", log, tt.expectedLog)
+			}
+
+			var report BatchResult
+			if err != nil {
+				var batchErr *BatchError
+				if !errors.As(err, &batchErr) {
+					t.Fatalf("expected BatchError, got %T", err)
+				}
+				report = batchErr.Report
+			} else if err := json.Unmarshal([]byte(result), &report); err != nil {
+				t.Fatalf("failed to parse result JSON: %v", err)
+			}
+
+			if len(report.Steps) != len(tt.expectedSteps) {
+				t.Fatalf("got %d step results, want %d", len(report.Steps), len(tt.expectedSteps))
+			}
+			for i, status := range tt.expectedSteps {
+				if report.Steps[i].Status != status {
+					t.Errorf("step %d status = %s, want %s", i, report.Steps[i].Status, status)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchHandler_Execute_InvalidPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{name: "malformed json", payload: `{`},
+		{name: "no steps", payload: `{"steps": []}`},
+		{name: "unknown type", payload: `{"steps": [{"type": "REBOOT"}]}`},
+		{name: "invalid on_failure", payload: `{"steps": [{"type": "HEALTH_CHECK", "on_failure": "retry"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log []string
+			handler := NewBatchHandler(newBatchTestRegistry(&log))
+
+			if _, err := handler.Execute(context.Background(), &v1.Instruction{Id: "b", Payload: tt.payload}); err == nil {
+				t.Error("Execute() expected error")
+			}
+			if len(log) != 0 {
+				t.Errorf("steps executed for invalid batch: %v", log)
+			}
+		})
+	}
+}
+
+func TestBatchHandler_Delivered(t *testing.T) {
+	var log []string
+	registry := instruction.NewRegistry()
+	registry.Register(v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE, &deliverableHandler{recordingHandler{log: &log}})
+	registry.Register(v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK, &recordingHandler{log: &log})
+	handler := NewBatchHandler(registry)
+	registry.Register(instruction.TypeBatch, handler)
+
+	payload := `{"steps": [
+		{"id": "collect", "type": "COLLECT_HARDWARE"},
+		{"id": "failed", "type": "COLLECT_HARDWARE", "payload": "fail", "on_failure": "continue"},
+		{"id": "nested", "type": "BATCH", "payload": {"steps": [{"id": "inner", "type": "COLLECT_HARDWARE"}]}},
+		{"id": "verify", "type": "HEALTH_CHECK"}
+	]}`
+	if _, err := handler.Execute(context.Background(), &v1.Instruction{Id: "b", Payload: payload}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	log = nil
+
+	handler.Delivered("b")
+	want := []string{"delivered b/collect", "delivered b/nested/inner"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("deliveries = %v, want %v", log, want)
+	}
+
+	// Each batch is delivered once
+	log = nil
+	handler.Delivered("b")
+	if len(log) != 0 {
+		t.Errorf("repeated delivery forwarded %v", log)
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+// CancelPayload represents the JSON payload for CANCEL instructions.
+type CancelPayload struct {
+	InstructionID string `json:"instruction_id"`
+}
+
+// CancelCallback cancels the running instruction with the given ID.
+// It returns false if no such instruction is running.
+type CancelCallback func(instructionID string) bool
+
+// CancelHandler handles CANCEL instructions.
+type CancelHandler struct {
+	callback CancelCallback
+}
+
+// NewCancelHandler creates a new cancellation handler with the given callback.
+func NewCancelHandler(callback CancelCallback) *CancelHandler {
+	return &CancelHandler{
+		callback: callback,
+	}
+}
+
+// Execute processes a CANCEL instruction.
+func (h *CancelHandler) Execute(ctx context.Context, instruction *v1.Instruction) (string, error) {
+	if instruction == nil {
+		return "", fmt.Errorf("instruction is nil")
+	}
+
+	// Parse payload
+	var payload CancelPayload
+	if err := json.Unmarshal([]byte(instruction.Payload), &payload); err != nil {
+		return "", fmt.Errorf("failed to parse cancel payload: %w", err)
+	}
+
+	if payload.InstructionID == "" {
+		return "", fmt.Errorf("instruction_id is required")
+	}
+
+	cancelled := false
+	if h.callback != nil {
+		cancelled = h.callback(payload.InstructionID)
+	}
+
+	// Return result; cancelling an instruction that is not running is not an error
+	result := map[string]interface{}{
+		"status":         "ok",
+		"instruction_id": payload.InstructionID,
+		"cancelled":      cancelled,
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return string(resultJSON), nil
+}
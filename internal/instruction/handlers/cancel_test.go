@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/instruction"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+func TestCancelHandler_Execute(t *testing.T) {
+	running := map[string]bool{"running-1": true}
+
+	tests := []struct {
+		name            string
+		instruction     *v1.Instruction
+		expectedError   bool
+		expectCancelled bool
+	}{
+		{
+			name: "cancel running instruction",
+			instruction: &v1.Instruction{
+				Id:      "cancel-1",
+				Type:    instruction.TypeCancel,
+				Payload: `{"instruction_id": "running-1"}`,
+			},
+			expectCancelled: true,
+		},
+		{
+			name: "cancel unknown instruction",
+			instruction: &v1.Instruction{
+				Id:      "cancel-2",
+				Type:    instruction.TypeCancel,
+				Payload: `{"instruction_id": "unknown"}`,
+			},
+			expectCancelled: false,
+		},
+		{
+			name: "missing instruction id",
+			instruction: &v1.Instruction{
+				Id:      "cancel-3",
+				Type:    instruction.TypeCancel,
+				Payload: `{}`,
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid payload",
+			instruction: &v1.Instruction{
+				Id:      "cancel-4",
+				Type:    instruction.TypeCancel,
+				Payload: `not json`,
+			},
+			expectedError: true,
+		},
+		{
+			name:          "nil instruction",
+			instruction:   nil,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewCancelHandler(func(id string) bool {
+				return running[id]
+			})
+
+			result, err := handler.Execute(context.Background(), tt.instruction)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Execute() error = %v, expectedError %v", err, tt.expectedError)
+			}
+			if tt.expectedError {
+				return
+			}
+
+			var resultData map[string]interface{}
+			if err := json.Unmarshal([]byte(result), &resultData); err != nil {
+				t.Fatalf("failed to parse result JSON: %v", err)
+			}
+			if resultData["cancelled"] != tt.expectCancelled {
+				t.Errorf("cancelled = %v, want %v", resultData["cancelled"], tt.expectCancelled)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/instruction"
+	"github.com/filanov/netctrl-agent/internal/netlink"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+// CollectCountersPayload represents the JSON payload for COLLECT_COUNTERS
+// instructions. An empty payload collects every counter of every port.
+type CollectCountersPayload struct {
+	// Interfaces limits collection to ports with these netdev or RDMA
+	// device names (e.g., "ens1f0np0", "mlx5_4").
+	Interfaces []string `json:"interfaces,omitempty"`
+	// Counters are glob patterns of counter names to report
+	// (e.g., "*_errors*", "rx_prio*_pause"). Empty reports all counters.
+	Counters []string `json:"counters,omitempty"`
+}
+
+// CountersReport is the result document of COLLECT_COUNTERS.
+type CountersReport struct {
+	Ports []PortCounters `json:"ports"`
+	Count int            `json:"count"`
+}
+
+// PortCounters holds the counters of a NIC port, keyed by counter name.
+type PortCounters struct {
+	PCIAddress    string `json:"pci_address"`
+	InterfaceName string `json:"interface_name,omitempty"`
+	RDMADevice    string `json:"rdma_device,omitempty"`
+	RDMAPort      int    `json:"rdma_port,omitempty"`
+	// Statistics are the netdev statistics (/sys/class/net/<if>/statistics).
+	Statistics map[string]uint64 `json:"statistics,omitempty"`
+	// Ethtool are the driver statistics (ethtool -S), including PFC pause
+	// frames per priority and vport RDMA counters.
+	Ethtool      map[string]uint64 `json:"ethtool,omitempty"`
+	EthtoolError string            `json:"ethtool_error,omitempty"`
+	// RDMACounters and RDMAHWCounters are the IB port counters and the
+	// driver's hardware counters (ports/<n>/counters and hw_counters).
+	RDMACounters   map[string]uint64 `json:"rdma_counters,omitempty"`
+	RDMAHWCounters map[string]uint64 `json:"rdma_hw_counters,omitempty"`
+}
+
+// CollectCountersHandler handles COLLECT_COUNTERS instructions.
+type CollectCountersHandler struct {
+	host *host.Host
+}
+
+// NewCollectCountersHandler creates a new counter collection handler that
+// reads sysfs and queries the kernel through h. A nil h uses the real host.
+func NewCollectCountersHandler(h *host.Host) *CollectCountersHandler {
+	if h == nil {
+		h = host.Default()
+	}
+	return &CollectCountersHandler{
+		host: h,
+	}
+}
+
+// Execute collects the counters of Mellanox NIC ports.
+func (h *CollectCountersHandler) Execute(ctx context.Context, inst *v1.Instruction) (string, error) {
+	if inst == nil {
+		return "", fmt.Errorf("instruction is nil")
+	}
+
+	var payload CollectCountersPayload
+	if inst.Payload != "" {
+		if err := json.Unmarshal([]byte(inst.Payload), &payload); err != nil {
+			return "", fmt.Errorf("failed to parse counters payload: %w", err)
+		}
+	}
+	for _, pattern := range payload.Counters {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return "", fmt.Errorf("invalid counter pattern %q: %w", pattern, err)
+		}
+	}
+
+	ports, err := collectPortCounters(ctx, h.host, payload)
+	if err != nil {
+		return "", err
+	}
+
+	resultJSON, err := json.Marshal(CountersReport{Ports: ports, Count: len(ports)})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal counters: %w", err)
+	}
+
+	return string(resultJSON), nil
+}
+
+// collectPortCounters reads the counters of the ports of every Mellanox
+// physical function selected by the payload.
+func collectPortCounters(ctx context.Context, h *host.Host, payload CollectCountersPayload) ([]PortCounters, error) {
+	pciDevices, err := findMellanoxPCIDevices(ctx, h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Mellanox devices: %w", err)
+	}
+
+	result := []PortCounters{}
+	for i, pciDev := range pciDevices {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		instruction.ReportProgress(ctx, i*100/len(pciDevices), "collect",
+			fmt.Sprintf("device %d/%d %s", i+1, len(pciDevices), pciDev.Address))
+
+		for _, port := range counterPorts(h, pciDev.Address) {
+			if !selectedPort(port, payload.Interfaces) {
+				continue
+			}
+			result = append(result, readPortCounters(h, port, payload.Counters))
+		}
+	}
+
+	instruction.ReportProgress(ctx, 100, "done", fmt.Sprintf("collected counters of %d ports", len(result)))
+
+	return result, nil
+}
+
+// counterPorts lists the ports of a PCI function: its physical netdevs and
+// RDMA ports, paired the same way as in hardware collection. Representors
+// are not ports and are skipped.
+func counterPorts(h *host.Host, pciAddr string) []PortCounters {
+	ports, _, _ := listNetdevs(h, pciAddr)
+
+	var rdmaName string
+	if entries, err := h.ReadDir(filepath.Join(sysBusPCIDevices, pciAddr, "infiniband")); err == nil && len(entries) > 0 {
+		rdmaName = entries[0].Name()
+		if rdma, err := collectRDMADevice(h, pciAddr, rdmaName); err == nil {
+			ports = mergeRDMAPorts(pciAddr, ports, rdma)
+		}
+	}
+
+	counters := make([]PortCounters, 0, len(ports))
+	for _, port := range ports {
+		pc := PortCounters{PCIAddress: pciAddr, InterfaceName: port.InterfaceName}
+		if port.RDMA != nil {
+			pc.RDMADevice = rdmaName
+			pc.RDMAPort = port.RDMA.Number
+		}
+		counters = append(counters, pc)
+	}
+	return counters
+}
+
+// selectedPort reports whether a port matches the requested interface
+// names. No names selects every port.
+func selectedPort(port PortCounters, interfaces []string) bool {
+	if len(interfaces) == 0 {
+		return true
+	}
+	for _, name := range interfaces {
+		if name == port.InterfaceName || (port.RDMADevice != "" && name == port.RDMADevice) {
+			return true
+		}
+	}
+	return false
+}
+
+// readPortCounters reads the counters of a port from every source that
+// applies to it.
+func readPortCounters(h *host.Host, port PortCounters, patterns []string) PortCounters {
+	if port.InterfaceName != "" {
+		netPath := filepath.Join(sysBusPCIDevices, port.PCIAddress, "net", port.InterfaceName)
+		port.Statistics = readCounterDir(h, filepath.Join(netPath, "statistics"), patterns)
+
+		stats, err := h.Netlink.EthtoolStats(port.InterfaceName)
+		switch {
+		case netlink.IsNotSupported(err):
+		case err != nil:
+			port.EthtoolError = err.Error()
+		default:
+			port.Ethtool = filterCounters(stats, patterns)
+		}
+	}
+
+	if port.RDMADevice != "" {
+		portPath := filepath.Join(sysBusPCIDevices, port.PCIAddress, "infiniband", port.RDMADevice, "ports", strconv.Itoa(port.RDMAPort))
+		port.RDMACounters = readCounterDir(h, filepath.Join(portPath, "counters"), patterns)
+		port.RDMAHWCounters = readCounterDir(h, filepath.Join(portPath, "hw_counters"), patterns)
+	}
+
+	return port
+}
+
+// readCounterDir reads a sysfs directory with one counter per file. Files
+// that cannot be read as a counter are skipped.
+func readCounterDir(h *host.Host, dir string, patterns []string) map[string]uint64 {
+	entries, err := h.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	counters := make(map[string]uint64)
+	for _, entry := range entries {
+		// hw_counters/lifespan is the counter cache lifetime, not a counter
+		if entry.IsDir() || entry.Name() == "lifespan" || !matchCounter(entry.Name(), patterns) {
+			continue
+		}
+		value, err := strconv.ParseUint(h.ReadString(filepath.Join(dir, entry.Name())), 10, 64)
+		if err != nil {
+			continue
+		}
+		counters[entry.Name()] = value
+	}
+	if len(counters) == 0 {
+		return nil
+	}
+	return counters
+}
+
+// filterCounters returns the counters whose names match patterns.
+func filterCounters(counters map[string]uint64, patterns []string) map[string]uint64 {
+	filtered := make(map[string]uint64)
+	for name, value := range counters {
+		if matchCounter(name, patterns) {
+			filtered[name] = value
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+	return filtered
+}
+
+// matchCounter reports whether a counter name matches any of the glob
+// patterns. No patterns match every counter.
+func matchCounter(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
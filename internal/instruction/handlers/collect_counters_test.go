@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/host/hosttest"
+	"github.com/filanov/netctrl-agent/internal/instruction"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+// TestCollectCountersHandler_Golden collects all counters from the host
+// captures in testdata/hosts and compares them with testdata/counters.
+// Run with -update to regenerate the golden files.
+func TestCollectCountersHandler_Golden(t *testing.T) {
+	captures, err := filepath.Glob(filepath.Join("testdata", "hosts", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, capture := range captures {
+		name := strings.TrimSuffix(filepath.Base(capture), ".txt")
+		t.Run(name, func(t *testing.T) {
+			report := executeCollectCounters(t, hosttest.Load(t, capture), "")
+
+			got, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", "counters", name+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("report does not match %s (run with -update to regenerate)\ngot:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestCollectCountersHandler_Filter(t *testing.T) {
+	h := hosttest.Load(t, filepath.Join("testdata", "hosts", "cx5.txt"))
+
+	report := executeCollectCounters(t, h, `{"interfaces": ["mlx5_0"], "counters": ["*crc_errors*", "rx_prio3_pause", "out_of_buffer"]}`)
+	if report.Count != 1 {
+		t.Fatalf("got %d ports, want 1: %+v", report.Count, report.Ports)
+	}
+
+	port := report.Ports[0]
+	if port.InterfaceName != "ens1f0np0" || port.RDMADevice != "mlx5_0" || port.RDMAPort != 1 {
+		t.Errorf("port = %s %s/%d, want ens1f0np0 mlx5_0/1", port.InterfaceName, port.RDMADevice, port.RDMAPort)
+	}
+	if len(port.Statistics) != 1 || port.Statistics["rx_crc_errors"] != 17 {
+		t.Errorf("statistics = %v, want only rx_crc_errors", port.Statistics)
+	}
+	if len(port.Ethtool) != 2 || port.Ethtool["rx_crc_errors_phy"] != 17 || port.Ethtool["rx_prio3_pause"] != 1842 {
+		t.Errorf("ethtool = %v, want rx_crc_errors_phy and rx_prio3_pause", port.Ethtool)
+	}
+	if port.RDMACounters != nil {
+		t.Errorf("rdma counters = %v, want none", port.RDMACounters)
+	}
+	if len(port.RDMAHWCounters) != 1 || port.RDMAHWCounters["out_of_buffer"] != 2048 {
+		t.Errorf("rdma hw counters = %v, want only out_of_buffer", port.RDMAHWCounters)
+	}
+}
+
+func TestCollectCountersHandler_InvalidPayload(t *testing.T) {
+	handler := NewCollectCountersHandler(hosttest.Load(t, filepath.Join("testdata", "hosts", "cx5.txt")))
+
+	for _, payload := range []string{`{"counters": "rx_*"}`, `{"counters": ["rx_[prio"]}`} {
+		_, err := handler.Execute(context.Background(), &v1.Instruction{
+			Type:    instruction.TypeCollectCounters,
+			Payload: payload,
+		})
+		if err == nil {
+			t.Errorf("expected error for payload %s", payload)
+		}
+	}
+
+	if _, err := handler.Execute(context.Background(), nil); err == nil {
+		t.Error("expected error for nil instruction")
+	}
+}
+
+func executeCollectCounters(t *testing.T, h *host.Host, payload string) CountersReport {
+	t.Helper()
+
+	result, err := NewCollectCountersHandler(h).Execute(context.Background(), &v1.Instruction{
+		Id:      "counters-1",
+		Type:    instruction.TypeCollectCounters,
+		Payload: payload,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var report CountersReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("failed to parse result: %v", err)
+	}
+	return report
+}
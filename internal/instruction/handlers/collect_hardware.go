@@ -3,47 +3,95 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"os/exec"
+	"log"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
-
+	"sync"
+	"time"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/instruction"
+	"github.com/filanov/netctrl-agent/internal/lldp"
+	"github.com/filanov/netctrl-agent/internal/netlink"
+	"github.com/filanov/netctrl-agent/internal/transceiver"
 	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
 )
 
+// ErrCollectionRunning is returned when a hardware collection starts while
+// another one is running.
+var ErrCollectionRunning = errors.New("another hardware collection is running")
+
+// CollectHardwareState is the state hardware collections share. It outlives
+// the handlers using it, e.g., when the agent re-creates them for a new
+// host. The zero value is ready to use.
+type CollectHardwareState struct {
+	// running is held while a collection runs, including collections
+	// started by BATCH and SCHEDULE, which bypass the agent's dispatch
+	running sync.Mutex
+	// aer keeps the AER counts of the last delivered collection to report
+	// deltas
+	aer aerHistory
+}
+
 // CollectHardwareHandler handles COLLECT_HARDWARE instructions.
-type CollectHardwareHandler struct{}
+type CollectHardwareHandler struct {
+	host  *host.Host
+	state *CollectHardwareState
+}
 
-// NewCollectHardwareHandler creates a new hardware collection handler.
-func NewCollectHardwareHandler() *CollectHardwareHandler {
-	return &CollectHardwareHandler{}
+// NewCollectHardwareHandler creates a new hardware collection handler that
+// reads sysfs and runs tools through h. A nil h uses the real host; a nil
+// state gives the handler a state of its own.
+func NewCollectHardwareHandler(h *host.Host, state *CollectHardwareState) *CollectHardwareHandler {
+	if h == nil {
+		h = host.Default()
+	}
+	if state == nil {
+		state = &CollectHardwareState{}
+	}
+	return &CollectHardwareHandler{
+		host:  h,
+		state: state,
+	}
 }
 
-// Execute collects Mellanox NIC information.
+// Execute collects Mellanox NIC and host information.
 func (h *CollectHardwareHandler) Execute(ctx context.Context, instruction *v1.Instruction) (string, error) {
 	if instruction == nil {
 		return "", fmt.Errorf("instruction is nil")
 	}
 
+	if !h.state.running.TryLock() {
+		return "", ErrCollectionRunning
+	}
+	defer h.state.running.Unlock()
+
 	// Collect Mellanox NICs
-	nics, err := collectMellanoxNICs()
+	nics, err := collectMellanoxNICs(ctx, h.host)
 	if err != nil {
 		return "", fmt.Errorf("failed to collect Mellanox NICs: %w", err)
 	}
 
+	h.state.aer.update(instruction.Id, nics, time.Now())
+
 	// Convert to proto MellanoxNIC objects
 	protoNICs := convertToProtoMellanoxNICs(nics)
 
-	// Create HardwareCollectionResult
-	hwResult := &v1.HardwareCollectionResult{
+	// Create the report; the agent parses the HardwareCollectionResult
+	// fields from it, the detailed NIC records are not submitted yet
+	report := &HardwareReport{
 		NetworkInterfaces: protoNICs,
+		NICs:              nics,
+		Count:             len(nics),
+		PCIeTopology:      collectPCIeTopology(ctx, h.host, nics),
+		Host:              collectHostInventory(h.host),
 	}
 
 	// Marshal to JSON (for agent to parse and put in InstructionResult)
-	resultJSON, err := json.Marshal(hwResult)
+	resultJSON, err := json.Marshal(report)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal hardware data: %w", err)
 	}
@@ -51,16 +99,48 @@ func (h *CollectHardwareHandler) Execute(ctx context.Context, instruction *v1.In
 	return string(resultJSON), nil
 }
 
+// Delivered implements instruction.Deliverer: AER deltas of later
+// collections are reported against the counts the server received.
+func (h *CollectHardwareHandler) Delivered(instructionID string) {
+	h.state.aer.commit(instructionID)
+}
+
+// HardwareReport is the result document of COLLECT_HARDWARE. Its
+// network_interfaces field has the same JSON shape as v1.HardwareCollectionResult;
+// the remaining fields carry details the proto does not model yet.
+type HardwareReport struct {
+	NetworkInterfaces []*v1.MellanoxNIC `json:"network_interfaces,omitempty"`
+	NICs              []NICInfo         `json:"nics"`
+	Count             int               `json:"count"`
+	// PCIeTopology is the PCIe hierarchy from the root complex down to the
+	// NICs and the devices sharing their root ports.
+	PCIeTopology []PCIeHostBridge `json:"pcie_topology,omitempty"`
+	// Host describes the host the NICs are installed in.
+	Host *HostInventory `json:"host,omitempty"`
+}
+
 // NICInfo represents collected NIC information for JSON serialization
 type NICInfo struct {
-	DeviceName      string     `json:"device_name"`
-	PCIAddress      string     `json:"pci_address"`
-	PartNumber      string     `json:"part_number"`
-	SerialNumber    string     `json:"serial_number"`
-	FirmwareVersion string     `json:"firmware_version"`
-	PortCount       int        `json:"port_count"`
-	PSID            string     `json:"psid"`
-	Ports           []PortInfo `json:"ports"`
+	DeviceName      string        `json:"device_name"`
+	PCIAddress      string        `json:"pci_address"`
+	PartNumber      string        `json:"part_number"`
+	SerialNumber    string        `json:"serial_number"`
+	FirmwareVersion string        `json:"firmware_version"`
+	PortCount       int           `json:"port_count"`
+	PSID            string        `json:"psid"`
+	Ports           []PortInfo    `json:"ports"`
+	PCI             PCIDevice     `json:"pci"`
+	VPD             *VPD          `json:"vpd,omitempty"`
+	Firmware        *FirmwareInfo `json:"firmware,omitempty"`
+	FirmwareError   string        `json:"firmware_error,omitempty"`
+	RDMA            *RDMADevice   `json:"rdma,omitempty"`
+	PCIeLink        *PCIeLink     `json:"pcie_link,omitempty"`
+	AER             *AERCounters  `json:"aer,omitempty"`
+	NUMA            *NUMAInfo     `json:"numa,omitempty"`
+	SRIOV           *SRIOVInfo    `json:"sriov,omitempty"`
+	Devlink         *DevlinkInfo  `json:"devlink,omitempty"`
+	Representors    []Representor `json:"representors,omitempty"`
+	Conditions      []Condition   `json:"conditions,omitempty"`
 }
 
 // PortInfo represents collected port information for JSON serialization
@@ -73,6 +153,27 @@ type PortInfo struct {
 	GUID          string `json:"guid"`
 	PCIAddress    string `json:"pci_address"`
 	InterfaceName string `json:"interface_name"`
+	// DevPort, PhysPortName, PhysPortID and PhysSwitchID identify the
+	// physical port; Number is derived from DevPort.
+	DevPort      *int         `json:"dev_port,omitempty"`
+	PhysPortName string       `json:"phys_port_name,omitempty"`
+	PhysPortID   string       `json:"phys_port_id,omitempty"`
+	PhysSwitchID string       `json:"phys_switch_id,omitempty"`
+	RDMA         *RDMAPort    `json:"rdma,omitempty"`
+	Channels     *ChannelInfo `json:"channels,omitempty"`
+	// Link is the ethtool view of the link: exact speed, lanes, link
+	// modes, autonegotiation and FEC.
+	Link *LinkSettings `json:"link,omitempty"`
+	// Driver is the netdev's driver info (ethtool -i).
+	Driver *netlink.DriverInfo `json:"driver,omitempty"`
+	// Module is the transceiver or cable plugged into the port.
+	Module      *transceiver.Module `json:"module,omitempty"`
+	ModuleError string              `json:"module_error,omitempty"`
+	// Uppers are the devices stacked on the port (bonds, bridges, VLANs,
+	// macvlans, IPoIB children).
+	Uppers []UpperDevice `json:"uppers,omitempty"`
+	// LLDPNeighbors are the switch ports seen over LLDP on the port.
+	LLDPNeighbors []lldp.Neighbor `json:"lldp_neighbors,omitempty"`
 }
 
 // convertToProtoMellanoxNICs converts internal NICInfo to proto MellanoxNIC objects.
@@ -131,125 +232,141 @@ func convertPortState(state string) v1.PortState {
 
 // convertPortSpeed converts speed string (e.g., "100G") to proto enum value.
 func convertPortSpeed(speed string) v1.PortSpeed {
-	switch speed {
-	case "1G":
-		return v1.PortSpeed_PORT_SPEED_1G
-	case "10G":
-		return v1.PortSpeed_PORT_SPEED_10G
-	case "25G":
-		return v1.PortSpeed_PORT_SPEED_25G
-	case "40G":
-		return v1.PortSpeed_PORT_SPEED_40G
-	case "50G":
-		return v1.PortSpeed_PORT_SPEED_50G
-	case "100G":
-		return v1.PortSpeed_PORT_SPEED_100G
-	case "200G":
-		return v1.PortSpeed_PORT_SPEED_200G
-	case "400G":
-		return v1.PortSpeed_PORT_SPEED_400G
-	default:
-		return v1.PortSpeed_PORT_SPEED_UNSPECIFIED
-	}
+	return portSpeeds[parseSpeed(speed)]
 }
 
 // collectMellanoxNICs discovers and collects information about Mellanox NICs.
-func collectMellanoxNICs() ([]NICInfo, error) {
-	// Find Mellanox devices via lspci
-	pciDevices, err := findMellanoxPCIDevices()
+// Progress is reported per device through the context's progress reporter.
+func collectMellanoxNICs(ctx context.Context, h *host.Host) ([]NICInfo, error) {
+	instruction.ReportProgress(ctx, 0, "enumerate", "discovering Mellanox PCI devices")
+
+	// Find Mellanox devices via sysfs (lspci fallback)
+	pciDevices, err := findMellanoxPCIDevices(ctx, h)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find Mellanox devices: %w", err)
 	}
 
 	var nics []NICInfo
 
-	for _, pciAddr := range pciDevices {
-		nic, err := collectNICInfo(pciAddr)
+	for i, pciDev := range pciDevices {
+		// Stop early if the instruction was cancelled
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		instruction.ReportProgress(ctx, i*100/len(pciDevices), "collect",
+			fmt.Sprintf("device %d/%d %s", i+1, len(pciDevices), pciDev.Address))
+
+		nic, err := collectNICInfo(ctx, h, pciDev)
 		if err != nil {
 			// Log error but continue with other devices
+			log.Printf("Skipping Mellanox device %s: %v", pciDev.Address, err)
 			continue
 		}
 		nics = append(nics, nic)
 	}
 
-	return nics, nil
-}
+	instruction.ReportProgress(ctx, 100, "done", fmt.Sprintf("collected %d NICs", len(nics)))
 
-// findMellanoxPCIDevices finds Mellanox network devices using lspci.
-func findMellanoxPCIDevices() ([]string, error) {
-	cmd := exec.Command("lspci", "-D", "-d", "15b3:")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("lspci command failed: %w", err)
-	}
-
-	var devices []string
-	lines := strings.Split(string(output), "\n")
-	pciRegex := regexp.MustCompile(`^([0-9a-f]{4}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-9])`)
-
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		matches := pciRegex.FindStringSubmatch(line)
-		if len(matches) > 1 {
-			devices = append(devices, matches[1])
-		}
-	}
-
-	return devices, nil
+	return nics, nil
 }
 
 // collectNICInfo collects detailed information about a specific NIC.
-func collectNICInfo(pciAddr string) (NICInfo, error) {
+func collectNICInfo(ctx context.Context, h *host.Host, pciDev PCIDevice) (NICInfo, error) {
+	pciAddr := pciDev.Address
 	nic := NICInfo{
 		PCIAddress: pciAddr,
+		PCI:        pciDev,
 	}
 
 	// Get device name from sysfs
-	deviceName, err := getDeviceNameFromPCI(pciAddr)
+	deviceName, err := getDeviceNameFromPCI(h, pciAddr)
 	if err == nil {
 		nic.DeviceName = deviceName
 	}
 
-	// Get firmware version and other details using mstflint if available
-	if fwVer := getFirmwareVersion(pciAddr); fwVer != "" {
-		nic.FirmwareVersion = fwVer
+	// Query firmware details with a single mstflint run if available
+	fw, err := queryFirmware(ctx, h, pciAddr)
+	if err == nil {
+		nic.Firmware = fw
+		nic.FirmwareVersion = fw.FWVersion
+		nic.PSID = fw.PSID
+	} else {
+		nic.FirmwareError = err.Error()
 	}
 
-	// Get part number and serial number from sysfs or mstflint
-	if partNum := getPartNumber(pciAddr); partNum != "" {
-		nic.PartNumber = partNum
+	// Get part number and serial number from the PCI VPD, falling back to mstflint
+	if vpd, err := readVPD(h, pciAddr); err == nil {
+		nic.VPD = vpd
+		if vpd.ChecksumValid {
+			nic.PartNumber = vpd.PartNumber
+			nic.SerialNumber = vpd.SerialNumber
+		}
 	}
 
-	if serialNum := getSerialNumber(pciAddr); serialNum != "" {
-		nic.SerialNumber = serialNum
+	if nic.PartNumber == "" && fw != nil {
+		nic.PartNumber = fw.PartNumber
 	}
 
-	if psid := getPSID(pciAddr); psid != "" {
-		nic.PSID = psid
+	// Check the negotiated PCIe link against the device's capability
+	nic.PCIeLink = collectPCIeLink(h, pciAddr)
+	if cond := pcieLinkCondition(nic.PCIeLink); cond != nil {
+		nic.Conditions = append(nic.Conditions, *cond)
 	}
 
-	// Collect port information
-	ports, err := collectPorts(pciAddr, deviceName)
-	if err == nil && len(ports) > 0 {
+	// Read the PCIe error counts; deltas are set by the handler
+	nic.AER = collectAER(h, pciAddr)
+
+	// Report NUMA locality and flag IRQs affine to remote CPUs
+	nic.NUMA = collectNUMAInfo(h, pciAddr)
+	if cond := irqAffinityCondition(nic.NUMA); cond != nil {
+		nic.Conditions = append(nic.Conditions, *cond)
+	}
+
+	// Collect port information; representors are reported apart from the
+	// physical ports
+	ports, representors, _ := collectPorts(ctx, h, pciAddr, deviceName)
+	nic.Representors = representors
+
+	// Attach RDMA (InfiniBand/RoCE) port attributes, including ports without a netdev
+	if rdma, err := collectRDMADevice(h, pciAddr, deviceName); err == nil {
+		nic.RDMA = rdma
+		ports = mergeRDMAPorts(pciAddr, ports, rdma)
+	}
+
+	if len(ports) > 0 {
 		nic.Ports = ports
 		nic.PortCount = len(ports)
 	}
 
+	// Without mstflint, take the firmware version and PSID from the driver
+	if nic.FirmwareVersion == "" {
+		version, psid := driverFirmwareVersion(ports)
+		nic.FirmwareVersion = version
+		if nic.PSID == "" {
+			nic.PSID = psid
+		}
+	}
+
+	// Report SR-IOV capability and the VFs created on this PF
+	nic.SRIOV = collectSRIOV(h, pciAddr, primaryNetdev(ports))
+
+	// Report e-switch mode, representors and health reporters from devlink
+	nic.Devlink = collectDevlink(h, pciAddr)
+
 	return nic, nil
 }
 
 // getDeviceNameFromPCI gets the device name (e.g., mlx5_0) from PCI address.
-func getDeviceNameFromPCI(pciAddr string) (string, error) {
+func getDeviceNameFromPCI(h *host.Host, pciAddr string) (string, error) {
 	// Look in /sys/bus/pci/devices/<pci>/infiniband/
-	ibPath := filepath.Join("/sys/bus/pci/devices", pciAddr, "infiniband")
+	ibPath := filepath.Join(sysBusPCIDevices, pciAddr, "infiniband")
 
-	entries, err := os.ReadDir(ibPath)
+	entries, err := h.ReadDir(ibPath)
 	if err != nil {
 		// Try net instead
-		netPath := filepath.Join("/sys/bus/pci/devices", pciAddr, "net")
-		entries, err = os.ReadDir(netPath)
+		netPath := filepath.Join(sysBusPCIDevices, pciAddr, "net")
+		entries, err = h.ReadDir(netPath)
 		if err != nil {
 			return "", err
 		}
@@ -266,165 +383,71 @@ func getDeviceNameFromPCI(pciAddr string) (string, error) {
 	return "", fmt.Errorf("no infiniband device found")
 }
 
-// getFirmwareVersion attempts to get firmware version.
-func getFirmwareVersion(pciAddr string) string {
-	// Try using mstflint if available
-	cmd := exec.Command("mstflint", "-d", pciAddr, "q")
-	output, err := cmd.Output()
-	if err != nil {
-		return ""
-	}
-
-	// Parse firmware version from mstflint output
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, "FW Version:") {
-			parts := strings.Split(line, ":")
-			if len(parts) > 1 {
-				return strings.TrimSpace(parts[1])
-			}
-		}
-	}
-
-	return ""
-}
-
-// getPartNumber attempts to get part number.
-func getPartNumber(pciAddr string) string {
-	cmd := exec.Command("mstflint", "-d", pciAddr, "q")
-	output, err := cmd.Output()
-	if err != nil {
-		return ""
-	}
-
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, "Part Number:") || strings.Contains(line, "Product Ver:") {
-			parts := strings.Split(line, ":")
-			if len(parts) > 1 {
-				return strings.TrimSpace(parts[1])
-			}
-		}
-	}
-
-	return ""
-}
-
-// getSerialNumber attempts to get serial number.
-func getSerialNumber(pciAddr string) string {
-	// Try reading from sysfs
-	serialPath := filepath.Join("/sys/bus/pci/devices", pciAddr, "vpd")
-	data, err := os.ReadFile(serialPath)
-	if err == nil {
-		// Simple extraction - VPD format is complex, this is a basic attempt
-		if sn := extractSerialFromVPD(string(data)); sn != "" {
-			return sn
-		}
-	}
-
-	return ""
-}
-
-// getPSID attempts to get PSID.
-func getPSID(pciAddr string) string {
-	cmd := exec.Command("mstflint", "-d", pciAddr, "q")
-	output, err := cmd.Output()
-	if err != nil {
-		return ""
-	}
-
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, "PSID:") {
-			parts := strings.Split(line, ":")
-			if len(parts) > 1 {
-				return strings.TrimSpace(parts[1])
-			}
-		}
-	}
-
-	return ""
-}
-
-// extractSerialFromVPD extracts serial number from VPD data.
-func extractSerialFromVPD(vpd string) string {
-	// VPD format is complex; this is a simplified extraction
-	// Look for "SN" tag followed by data
-	if idx := strings.Index(vpd, "SN"); idx != -1 && idx+2 < len(vpd) {
-		// Extract a reasonable length serial number
-		end := idx + 2 + 20
-		if end > len(vpd) {
-			end = len(vpd)
-		}
-		sn := vpd[idx+2 : end]
-		// Clean non-printable characters
-		return strings.Map(func(r rune) rune {
-			if r >= 32 && r < 127 {
-				return r
-			}
-			return -1
-		}, sn)
-	}
-	return ""
-}
-
-// collectPorts collects information about NIC ports.
-func collectPorts(pciAddr, deviceName string) ([]PortInfo, error) {
-	var ports []PortInfo
-
+// collectPorts collects information about NIC ports and returns the
+// function's representors separately.
+func collectPorts(ctx context.Context, h *host.Host, pciAddr, deviceName string) ([]PortInfo, []Representor, error) {
 	// Find network interfaces associated with this PCI device
-	netPath := filepath.Join("/sys/bus/pci/devices", pciAddr, "net")
-	entries, err := os.ReadDir(netPath)
+	netPath := filepath.Join(sysBusPCIDevices, pciAddr, "net")
+	ports, representors, err := listNetdevs(h, pciAddr)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	for portNum, entry := range entries {
-		ifName := entry.Name()
-
-		port := PortInfo{
-			Number:        portNum + 1,
-			InterfaceName: ifName,
-			PCIAddress:    pciAddr,
-		}
+	for i := range ports {
+		port := &ports[i]
+		ifName := port.InterfaceName
 
 		// Get port state
 		statePath := filepath.Join(netPath, ifName, "operstate")
-		if stateData, err := os.ReadFile(statePath); err == nil {
+		if stateData, err := h.ReadFile(statePath); err == nil {
 			state := strings.TrimSpace(string(stateData))
 			port.State = state
 		}
 
 		// Get MAC address
 		macPath := filepath.Join(netPath, ifName, "address")
-		if macData, err := os.ReadFile(macPath); err == nil {
+		if macData, err := h.ReadFile(macPath); err == nil {
 			port.MACAddress = strings.TrimSpace(string(macData))
 		}
 
 		// Get MTU
 		mtuPath := filepath.Join(netPath, ifName, "mtu")
-		if mtuData, err := os.ReadFile(mtuPath); err == nil {
+		if mtuData, err := h.ReadFile(mtuPath); err == nil {
 			if mtu, err := strconv.Atoi(strings.TrimSpace(string(mtuData))); err == nil {
 				port.MTU = mtu
 			}
 		}
 
-		// Get speed (requires interface to be up)
-		speedPath := filepath.Join(netPath, ifName, "speed")
-		if speedData, err := os.ReadFile(speedPath); err == nil {
-			speedStr := strings.TrimSpace(string(speedData))
-			if speedVal, err := strconv.Atoi(speedStr); err == nil {
-				// Convert Mbps to Gbps string
-				if speedVal >= 1000 {
-					port.Speed = fmt.Sprintf("%dG", speedVal/1000)
-				} else {
-					port.Speed = fmt.Sprintf("%dM", speedVal)
-				}
-			}
+		// Get speed in Mb/s (-1 while the link is down), preferring the
+		// ethtool link settings
+		speed, _ := strconv.Atoi(h.ReadString(filepath.Join(netPath, ifName, "speed")))
+		port.Link = collectLinkSettings(h, ifName)
+		if port.Link != nil && port.Link.SpeedMbps > 0 {
+			speed = port.Link.SpeedMbps
 		}
+		port.Speed = formatSpeed(speed)
+
+		// Get the driver, its version and the running firmware
+		port.Driver = collectDriverInfo(h, ifName)
 
-		ports = append(ports, port)
+		// Get the bonds, bridges, VLANs and other devices stacked on the port
+		port.Uppers = collectUppers(h, pciAddr, ifName)
+
+		// Get the LLDP neighbors, when the agent listens for them
+		if h.LLDP != nil {
+			port.LLDPNeighbors = h.LLDP.Neighbors(ifName)
+		}
+
+		// Get channel count
+		port.Channels = collectChannels(h, netPath, ifName)
+
+		// Decode the transceiver module EEPROM
+		if module, err := collectModule(h, ifName); err != nil {
+			port.ModuleError = err.Error()
+		} else {
+			port.Module = module
+		}
 	}
 
-	return ports, nil
+	return ports, representors, nil
 }
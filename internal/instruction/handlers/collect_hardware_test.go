@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/host/hosttest"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// TestCollectHardwareHandler_Golden runs hardware collection against host
+// captures in testdata/hosts and compares the report with the golden file
+// next to each capture. Run with -update to regenerate the golden files.
+func TestCollectHardwareHandler_Golden(t *testing.T) {
+	captures, err := filepath.Glob(filepath.Join("testdata", "hosts", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(captures) == 0 {
+		t.Fatal("no host captures found")
+	}
+
+	for _, capture := range captures {
+		name := strings.TrimSuffix(filepath.Base(capture), ".txt")
+		t.Run(name, func(t *testing.T) {
+			handler := NewCollectHardwareHandler(hosttest.Load(t, capture), nil)
+
+			result, err := handler.Execute(context.Background(), &v1.Instruction{
+				Id:   "collect-1",
+				Type: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var report HardwareReport
+			if err := json.Unmarshal([]byte(result), &report); err != nil {
+				t.Fatalf("failed to parse result: %v", err)
+			}
+			got, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := strings.TrimSuffix(capture, ".txt") + ".golden.json"
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("report does not match %s (run with -update to regenerate)\ngot:\n%s", golden, got)
+			}
+
+			// The result must remain parseable as the proto result by the agent
+			var hwResult v1.HardwareCollectionResult
+			if err := json.Unmarshal([]byte(result), &hwResult); err != nil {
+				t.Errorf("result is not a valid HardwareCollectionResult: %v", err)
+			}
+			if len(hwResult.NetworkInterfaces) != report.Count {
+				t.Errorf("HardwareCollectionResult has %d NICs, want %d", len(hwResult.NetworkInterfaces), report.Count)
+			}
+		})
+	}
+}
+
+func TestCollectHardwareHandler_NilInstruction(t *testing.T) {
+	handler := NewCollectHardwareHandler(nil, nil)
+	if _, err := handler.Execute(context.Background(), nil); err == nil {
+		t.Error("expected error for nil instruction")
+	}
+}
+
+func TestCollectHardwareHandler_SharedState(t *testing.T) {
+	var state CollectHardwareState
+	h := hosttest.Load(t, filepath.Join("testdata", "hosts", "cx5.txt"))
+	inst := &v1.Instruction{Id: "collect-1", Type: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE}
+
+	// A collection that starts while another one runs fails, even through
+	// another handler
+	state.running.Lock()
+	if _, err := NewCollectHardwareHandler(h, &state).Execute(context.Background(), inst); !errors.Is(err, ErrCollectionRunning) {
+		t.Errorf("Execute() during another collection error = %v, want %v", err, ErrCollectionRunning)
+	}
+	state.running.Unlock()
+
+	// The AER baseline delivered through one handler is used by the next
+	first := NewCollectHardwareHandler(h, &state)
+	if _, err := first.Execute(context.Background(), inst); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	first.Delivered(inst.Id)
+
+	result, err := NewCollectHardwareHandler(h, &state).Execute(context.Background(), &v1.Instruction{Id: "collect-2"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	var report HardwareReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.NICs[0].AER == nil || report.NICs[0].AER.Delta == nil {
+		t.Errorf("second collection has no AER delta against the delivered one: %+v", report.NICs[0].AER)
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/netlink"
+)
+
+// DevlinkInfo holds the devlink view of a PCI function: its e-switch mode
+// (legacy or switchdev), ports and representors, and health reporters.
+type DevlinkInfo struct {
+	// Eswitch is nil for functions without an e-switch (e.g., VFs or NICs
+	// that only support legacy mode).
+	Eswitch         *netlink.DevlinkEswitch         `json:"eswitch,omitempty"`
+	Ports           []netlink.DevlinkPort           `json:"ports,omitempty"`
+	HealthReporters []netlink.DevlinkHealthReporter `json:"health_reporters,omitempty"`
+	// Error is set when devlink is available but could not be queried.
+	Error string `json:"error,omitempty"`
+}
+
+// collectDevlink queries devlink for a PCI function. It returns nil if the
+// kernel has no devlink or the function is not a devlink device.
+func collectDevlink(h *host.Host, pciAddr string) *DevlinkInfo {
+	ports, err := h.Netlink.DevlinkPorts(pciAddr)
+	if netlink.IsNotSupported(err) {
+		return nil
+	}
+	if err != nil {
+		return &DevlinkInfo{Error: err.Error()}
+	}
+	if len(ports) == 0 {
+		return nil
+	}
+
+	info := &DevlinkInfo{Ports: ports}
+
+	// Functions without an e-switch return EOPNOTSUPP
+	if eswitch, err := h.Netlink.DevlinkEswitch(pciAddr); err == nil {
+		info.Eswitch = eswitch
+	}
+
+	reporters, err := h.Netlink.DevlinkHealthReporters(pciAddr)
+	if err != nil {
+		info.Error = err.Error()
+	}
+	info.HealthReporters = reporters
+
+	return info
+}
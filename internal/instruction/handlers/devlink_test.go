@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/netlink"
+)
+
+// devlinkStub is a netlink.Client whose devlink queries fail with fixed errors.
+type devlinkStub struct {
+	netlink.Client
+	portsErr  error
+	healthErr error
+}
+
+func (s devlinkStub) DevlinkPorts(device string) ([]netlink.DevlinkPort, error) {
+	if s.portsErr != nil {
+		return nil, s.portsErr
+	}
+	return []netlink.DevlinkPort{{Index: 65535, Flavour: "physical"}}, nil
+}
+
+func (s devlinkStub) DevlinkEswitch(device string) (*netlink.DevlinkEswitch, error) {
+	return nil, errors.New("operation not supported")
+}
+
+func (s devlinkStub) DevlinkHealthReporters(device string) ([]netlink.DevlinkHealthReporter, error) {
+	return nil, s.healthErr
+}
+
+func TestCollectDevlink(t *testing.T) {
+	tests := []struct {
+		name      string
+		stub      devlinkStub
+		wantNil   bool
+		wantError string
+	}{
+		{
+			name:    "kernel without devlink",
+			stub:    devlinkStub{portsErr: fmt.Errorf("%w: devlink", netlink.ErrFamilyNotFound)},
+			wantNil: true,
+		},
+		{
+			name:    "netlink unsupported",
+			stub:    devlinkStub{portsErr: netlink.ErrNotSupported},
+			wantNil: true,
+		},
+		{
+			name:      "port query failure",
+			stub:      devlinkStub{portsErr: errors.New("netlink: permission denied")},
+			wantError: "netlink: permission denied",
+		},
+		{
+			name:      "health query failure",
+			stub:      devlinkStub{healthErr: errors.New("netlink: operation not supported")},
+			wantError: "netlink: operation not supported",
+		},
+		{
+			name: "no e-switch",
+			stub: devlinkStub{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := host.New(t.TempDir(), nil)
+			h.Netlink = tt.stub
+
+			info := collectDevlink(h, "0000:03:00.0")
+			if tt.wantNil {
+				if info != nil {
+					t.Errorf("collectDevlink() = %+v, want nil", info)
+				}
+				return
+			}
+			if info == nil {
+				t.Fatal("collectDevlink() = nil")
+			}
+			if info.Error != tt.wantError {
+				t.Errorf("Error = %q, want %q", info.Error, tt.wantError)
+			}
+			if info.Eswitch != nil {
+				t.Errorf("Eswitch = %+v, want nil", info.Eswitch)
+			}
+		})
+	}
+}
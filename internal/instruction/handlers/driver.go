@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"path/filepath"
+	"regexp"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/netlink"
+)
+
+const sysModule = "/sys/module"
+
+// driverModules are the kernel modules of the mlx5 driver stack.
+var driverModules = []string{"mlx5_core", "mlx5_ib", "ib_core", "ib_uverbs", "rdma_cm", "mlx_compat"}
+
+// ofedInfoPaths are the locations of the ofed_info script installed by
+// MLNX_OFED and DOCA-OFED.
+var ofedInfoPaths = []string{"/usr/bin/ofed_info", "/usr/local/bin/ofed_info"}
+
+// ofedReleaseRegex matches the release printed by `ofed_info -s`, e.g.,
+// "MLNX_OFED_LINUX-23.10-1.1.9.0" or, for DOCA-OFED, "OFED-internal-24.10-1.1.4".
+var ofedReleaseRegex = regexp.MustCompile(`\b(MLNX_OFED_LINUX|OFED-internal)-(\d[0-9A-Za-z.\-]*[0-9A-Za-z])`)
+
+// firmwareVersionRegex splits the firmware version reported by mlx5 in
+// the driver info into the version and the PSID.
+var firmwareVersionRegex = regexp.MustCompile(`^(\S+)\s+\((\S+)\)$`)
+
+// KernelModule is a loaded kernel module of the driver stack.
+type KernelModule struct {
+	Name string `json:"name"`
+	// Version is set by out-of-tree drivers such as MLNX_OFED; in-tree
+	// modules have none.
+	Version    string `json:"version,omitempty"`
+	SrcVersion string `json:"srcversion,omitempty"`
+	// Builtin is set for modules compiled into the kernel.
+	Builtin bool `json:"builtin,omitempty"`
+}
+
+// OFEDInfo identifies an installed NVIDIA OFED driver stack.
+type OFEDInfo struct {
+	// Distribution is "MLNX_OFED" or "DOCA" when ofed_info is installed.
+	Distribution string `json:"distribution,omitempty"`
+	Version      string `json:"version"`
+	// Release is the full release name printed by `ofed_info -s`.
+	Release string `json:"release,omitempty"`
+}
+
+// collectDriverInfo returns the driver and firmware of a netdev, or nil if
+// the driver does not report them.
+func collectDriverInfo(h *host.Host, ifName string) *netlink.DriverInfo {
+	info, err := h.Netlink.DriverInfo(ifName)
+	if err != nil {
+		return nil
+	}
+	return info
+}
+
+// driverFirmwareVersion returns the firmware version and PSID the driver
+// reports for the first port that has them.
+func driverFirmwareVersion(ports []PortInfo) (version, psid string) {
+	for _, port := range ports {
+		if port.Driver == nil || port.Driver.FirmwareVersion == "" {
+			continue
+		}
+		if m := firmwareVersionRegex.FindStringSubmatch(port.Driver.FirmwareVersion); m != nil {
+			return m[1], m[2]
+		}
+		return port.Driver.FirmwareVersion, ""
+	}
+	return "", ""
+}
+
+// readKernelModules reads the version and source checksum of the loaded
+// driver stack modules from /sys/module.
+func readKernelModules(h *host.Host) []KernelModule {
+	var modules []KernelModule
+	for _, name := range driverModules {
+		modPath := filepath.Join(sysModule, name)
+		if !h.Exists(modPath) {
+			continue
+		}
+		modules = append(modules, KernelModule{
+			Name:       name,
+			Version:    h.ReadString(filepath.Join(modPath, "version")),
+			SrcVersion: h.ReadString(filepath.Join(modPath, "srcversion")),
+			Builtin:    !h.Exists(filepath.Join(modPath, "initstate")),
+		})
+	}
+	return modules
+}
+
+// readOFEDVersion returns the installed MLNX_OFED or DOCA-OFED version. It
+// reads the release from the ofed_info script, which is a shell script
+// that embeds it, so no command runs on the host. Without the script (e.g.,
+// /usr not mounted under the host root), the version of the loaded
+// mlx_compat module, which only OFED ships, is used.
+func readOFEDVersion(h *host.Host, modules []KernelModule) *OFEDInfo {
+	for _, path := range ofedInfoPaths {
+		data, err := h.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		m := ofedReleaseRegex.FindStringSubmatch(string(data))
+		if m == nil {
+			continue
+		}
+		info := &OFEDInfo{Distribution: "MLNX_OFED", Version: m[2], Release: m[0]}
+		if m[1] == "OFED-internal" {
+			info.Distribution = "DOCA"
+		}
+		return info
+	}
+
+	for _, module := range modules {
+		if module.Name == "mlx_compat" && module.Version != "" {
+			return &OFEDInfo{Version: module.Version}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/netlink"
+)
+
+func TestReadOFEDVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		capture string
+		want    *OFEDInfo
+	}{
+		{
+			name: "doca",
+			capture: `-- usr/bin/ofed_info --
+#!/bin/bash
+if [ "X$1" == "X-s" ]; then echo OFED-internal-24.10-1.1.4:; exit 1; fi
+-- sys/module/mlx_compat/version --
+24.10-1.1.4
+-- sys/module/mlx_compat/initstate --
+live
+`,
+			want: &OFEDInfo{Distribution: "DOCA", Version: "24.10-1.1.4", Release: "OFED-internal-24.10-1.1.4"},
+		},
+		{
+			name: "mlx_compat without ofed_info",
+			capture: `-- sys/module/mlx_compat/version --
+5.8-1.0.1
+-- sys/module/mlx_compat/initstate --
+live
+`,
+			want: &OFEDInfo{Version: "5.8-1.0.1"},
+		},
+		{
+			name: "inbox",
+			capture: `-- sys/module/mlx5_core/srcversion --
+1BE0A36F4D98C27E5B3F014
+-- sys/module/mlx5_core/initstate --
+live
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := loadArchive(t, tt.capture)
+
+			if got := readOFEDVersion(h, readKernelModules(h)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readOFEDVersion() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadKernelModules(t *testing.T) {
+	h := loadArchive(t, `-- sys/module/mlx5_core/version --
+23.10-1.1.9
+-- sys/module/mlx5_core/srcversion --
+7D3F1A42C8E0B9D16F5A2C3
+-- sys/module/mlx5_core/initstate --
+live
+-- dir sys/module/ib_core/parameters --
+-- dir sys/module/mlx4_core --
+`)
+
+	want := []KernelModule{
+		{Name: "mlx5_core", Version: "23.10-1.1.9", SrcVersion: "7D3F1A42C8E0B9D16F5A2C3"},
+		{Name: "ib_core", Builtin: true},
+	}
+	if got := readKernelModules(h); !reflect.DeepEqual(got, want) {
+		t.Errorf("readKernelModules() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDriverFirmwareVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		ports       []PortInfo
+		wantVersion string
+		wantPSID    string
+	}{
+		{
+			name:        "mlx5",
+			ports:       []PortInfo{{}, {Driver: &netlink.DriverInfo{FirmwareVersion: "22.39.2048 (MT_0000000359)"}}},
+			wantVersion: "22.39.2048",
+			wantPSID:    "MT_0000000359",
+		},
+		{
+			name:        "without PSID",
+			ports:       []PortInfo{{Driver: &netlink.DriverInfo{FirmwareVersion: "22.39.2048"}}},
+			wantVersion: "22.39.2048",
+		},
+		{
+			name:  "no driver info",
+			ports: []PortInfo{{Driver: &netlink.DriverInfo{Driver: "mlx5_core"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, psid := driverFirmwareVersion(tt.ports)
+			if version != tt.wantVersion || psid != tt.wantPSID {
+				t.Errorf("driverFirmwareVersion() = %q, %q, want %q, %q", version, psid, tt.wantVersion, tt.wantPSID)
+			}
+		})
+	}
+}
@@ -16,10 +16,13 @@ type HealthCheckHandler struct {
 	startTime time.Time
 	hostname  string
 	ipAddress string
+	policy    func() string
 }
 
-// NewHealthCheckHandler creates a new health check handler.
-func NewHealthCheckHandler() *HealthCheckHandler {
+// NewHealthCheckHandler creates a new health check handler. policy returns
+// a summary of the active local execution policy, or "" if there is none;
+// it may be nil.
+func NewHealthCheckHandler(policy func() string) *HealthCheckHandler {
 	hostname, _ := os.Hostname()
 	ipAddress := getLocalIP()
 
@@ -27,6 +30,7 @@ func NewHealthCheckHandler() *HealthCheckHandler {
 		startTime: time.Now(),
 		hostname:  hostname,
 		ipAddress: ipAddress,
+		policy:    policy,
 	}
 }
 
@@ -47,6 +51,11 @@ func (h *HealthCheckHandler) Execute(ctx context.Context, instruction *v1.Instru
 		"uptime_seconds": int(uptime.Seconds()),
 		"timestamp":      time.Now().UTC().Format(time.RFC3339),
 	}
+	if h.policy != nil {
+		if summary := h.policy(); summary != "" {
+			healthData["policy"] = summary
+		}
+	}
 
 	// Marshal to JSON
 	resultJSON, err := json.Marshal(healthData)
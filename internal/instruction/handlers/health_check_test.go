@@ -10,7 +10,7 @@ import (
 )
 
 func TestNewHealthCheckHandler(t *testing.T) {
-	handler := NewHealthCheckHandler()
+	handler := NewHealthCheckHandler(nil)
 
 	if handler == nil {
 		t.Fatal("NewHealthCheckHandler returned nil")
@@ -50,7 +50,7 @@ func TestHealthCheckHandler_Execute(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handler := NewHealthCheckHandler()
+			handler := NewHealthCheckHandler(nil)
 			ctx := context.Background()
 
 			// Wait a small amount to ensure uptime is non-zero
@@ -103,7 +103,7 @@ func TestHealthCheckHandler_Execute(t *testing.T) {
 }
 
 func TestHealthCheckHandler_Execute_Uptime(t *testing.T) {
-	handler := NewHealthCheckHandler()
+	handler := NewHealthCheckHandler(nil)
 	ctx := context.Background()
 
 	// Wait 100ms to ensure measurable uptime
@@ -137,7 +137,7 @@ func TestHealthCheckHandler_Execute_Uptime(t *testing.T) {
 }
 
 func TestHealthCheckHandler_Execute_ContextCancellation(t *testing.T) {
-	handler := NewHealthCheckHandler()
+	handler := NewHealthCheckHandler(nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // Cancel context immediately
 
@@ -158,6 +158,44 @@ func TestHealthCheckHandler_Execute_ContextCancellation(t *testing.T) {
 	}
 }
 
+func TestHealthCheckHandler_Execute_Policy(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary string
+	}{
+		{name: "policy loaded", summary: "name=prod digest=abc default=deny rules=2 maintenance=false"},
+		{name: "no policy", summary: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHealthCheckHandler(func() string { return tt.summary })
+			result, err := handler.Execute(context.Background(), &v1.Instruction{
+				Id:   "test-policy",
+				Type: v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK,
+			})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			var healthData map[string]interface{}
+			if err := json.Unmarshal([]byte(result), &healthData); err != nil {
+				t.Fatalf("Failed to parse result JSON: %v", err)
+			}
+			policy, ok := healthData["policy"]
+			if tt.summary == "" {
+				if ok {
+					t.Errorf("policy = %v, want absent", policy)
+				}
+				return
+			}
+			if policy != tt.summary {
+				t.Errorf("policy = %v, want %q", policy, tt.summary)
+			}
+		})
+	}
+}
+
 func TestGetLocalIP(t *testing.T) {
 	ip := getLocalIP()
 	// IP may be empty in some test environments, so just log it
@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+)
+
+const (
+	sysClassDMI  = "/sys/class/dmi/id"
+	sysSystemCPU = "/sys/devices/system/cpu"
+)
+
+// HostInventory describes the host the NICs are installed in.
+type HostInventory struct {
+	Hostname string      `json:"hostname,omitempty"`
+	System   *DMIInfo    `json:"system,omitempty"`
+	CPU      *CPUInfo    `json:"cpu,omitempty"`
+	OS       *OSInfo     `json:"os,omitempty"`
+	Kernel   *KernelInfo `json:"kernel,omitempty"`
+	// MemoryBytes is the total usable memory (MemTotal).
+	MemoryBytes uint64    `json:"memory_bytes,omitempty"`
+	BootTime    time.Time `json:"boot_time,omitzero"`
+	// VirtualMachine is set when the host is a guest of a hypervisor;
+	// Hypervisor names it (e.g., "kvm", "vmware", "microsoft", "xen").
+	VirtualMachine bool   `json:"virtual_machine"`
+	Hypervisor     string `json:"hypervisor,omitempty"`
+	// KernelModules are the loaded modules of the mlx5 driver stack, and
+	// OFED the installed MLNX_OFED or DOCA-OFED stack.
+	KernelModules []KernelModule `json:"kernel_modules,omitempty"`
+	OFED          *OFEDInfo      `json:"ofed,omitempty"`
+}
+
+// DMIInfo is the system identification from the SMBIOS/DMI tables. Serial
+// numbers and the UUID are only readable by root.
+type DMIInfo struct {
+	Vendor         string `json:"vendor,omitempty"`
+	ProductName    string `json:"product_name,omitempty"`
+	ProductVersion string `json:"product_version,omitempty"`
+	SerialNumber   string `json:"serial_number,omitempty"`
+	UUID           string `json:"uuid,omitempty"`
+	BoardVendor    string `json:"board_vendor,omitempty"`
+	BoardName      string `json:"board_name,omitempty"`
+	BIOSVendor     string `json:"bios_vendor,omitempty"`
+	BIOSVersion    string `json:"bios_version,omitempty"`
+	BIOSDate       string `json:"bios_date,omitempty"`
+}
+
+// CPUInfo describes the host's processors.
+type CPUInfo struct {
+	Model  string `json:"model,omitempty"`
+	Vendor string `json:"vendor,omitempty"`
+	// Sockets, Cores and Threads count physical packages, physical cores
+	// and logical CPUs.
+	Sockets int `json:"sockets"`
+	Cores   int `json:"cores"`
+	Threads int `json:"threads"`
+}
+
+// OSInfo is the distribution from os-release.
+type OSInfo struct {
+	ID         string `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Version    string `json:"version,omitempty"`
+	VersionID  string `json:"version_id,omitempty"`
+	PrettyName string `json:"pretty_name,omitempty"`
+}
+
+// KernelInfo identifies the running kernel.
+type KernelInfo struct {
+	Release string `json:"release"`
+	Version string `json:"version,omitempty"`
+}
+
+// dmiHypervisors maps prefixes of DMI vendor and product strings to
+// hypervisors, after systemd-detect-virt.
+var dmiHypervisors = []struct {
+	prefix     string
+	hypervisor string
+}{
+	{"KVM", "kvm"},
+	{"OpenStack", "kvm"},
+	{"KubeVirt", "kvm"},
+	{"Amazon EC2", "amazon"},
+	{"QEMU", "qemu"},
+	{"VMware", "vmware"},
+	{"VMW", "vmware"},
+	{"innotek GmbH", "oracle"},
+	{"VirtualBox", "oracle"},
+	{"Oracle Corporation", "oracle"},
+	{"Xen", "xen"},
+	{"Bochs", "bochs"},
+	{"Parallels", "parallels"},
+	{"BHYVE", "bhyve"},
+	{"Hyper-V", "microsoft"},
+	{"Apple Virtualization", "apple"},
+	{"Google Compute Engine", "google"},
+}
+
+// armImplementers and armParts name the CPUs of arm64 hosts, whose
+// /proc/cpuinfo has no model name.
+var armImplementers = map[string]string{
+	"0x41": "ARM",
+	"0x48": "HiSilicon",
+	"0x4e": "NVIDIA",
+	"0x51": "Qualcomm",
+	"0xc0": "Ampere",
+}
+
+var armParts = map[string]string{
+	"0x41/0xd08": "Cortex-A72",
+	"0x41/0xd0c": "Neoverse-N1",
+	"0x41/0xd40": "Neoverse-V1",
+	"0x41/0xd42": "Cortex-A78AE",
+	"0x41/0xd49": "Neoverse-N2",
+	"0x41/0xd4f": "Neoverse-V2",
+	"0xc0/0xac3": "AmpereOne",
+}
+
+// collectHostInventory reads the host's identity, processors, memory,
+// distribution, kernel and driver stack. It returns nil if none of them
+// can be read.
+func collectHostInventory(h *host.Host) *HostInventory {
+	inv := &HostInventory{
+		Hostname: h.ReadString("/proc/sys/kernel/hostname"),
+		System:   readDMI(h),
+		OS:       readOSRelease(h),
+	}
+	if inv.Hostname == "" {
+		inv.Hostname = h.ReadString("/etc/hostname")
+	}
+	if release := h.ReadString("/proc/sys/kernel/osrelease"); release != "" {
+		inv.Kernel = &KernelInfo{
+			Release: release,
+			Version: h.ReadString("/proc/sys/kernel/version"),
+		}
+	}
+
+	cpuinfo, _ := h.ReadFile("/proc/cpuinfo")
+	inv.CPU = readCPUInfo(h, cpuinfo)
+	inv.MemoryBytes = readMemTotal(h)
+	inv.BootTime = readBootTime(h)
+	inv.VirtualMachine, inv.Hypervisor = detectVirtualization(h, inv.System, cpuinfo)
+	inv.KernelModules = readKernelModules(h)
+	inv.OFED = readOFEDVersion(h, inv.KernelModules)
+
+	if reflect.ValueOf(inv).Elem().IsZero() {
+		return nil
+	}
+	return inv
+}
+
+// readDMI reads the system, board and BIOS identification.
+func readDMI(h *host.Host) *DMIInfo {
+	read := func(name string) string {
+		return h.ReadString(filepath.Join(sysClassDMI, name))
+	}
+	dmi := &DMIInfo{
+		Vendor:         read("sys_vendor"),
+		ProductName:    read("product_name"),
+		ProductVersion: read("product_version"),
+		SerialNumber:   read("product_serial"),
+		UUID:           read("product_uuid"),
+		BoardVendor:    read("board_vendor"),
+		BoardName:      read("board_name"),
+		BIOSVendor:     read("bios_vendor"),
+		BIOSVersion:    read("bios_version"),
+		BIOSDate:       read("bios_date"),
+	}
+	if *dmi == (DMIInfo{}) {
+		return nil
+	}
+	return dmi
+}
+
+// readCPUInfo counts sockets, cores and threads from the sysfs CPU
+// topology and takes the model from the first processor in /proc/cpuinfo.
+func readCPUInfo(h *host.Host, cpuinfo []byte) *CPUInfo {
+	cpu := &CPUInfo{}
+
+	entries, _ := h.ReadDir(sysSystemCPU)
+	packages := make(map[string]bool)
+	cores := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		if _, err := strconv.Atoi(strings.TrimPrefix(name, "cpu")); err != nil || !strings.HasPrefix(name, "cpu") {
+			continue
+		}
+		topology := filepath.Join(sysSystemCPU, name, "topology")
+		pkg := h.ReadString(filepath.Join(topology, "physical_package_id"))
+		if pkg == "" {
+			// Offline CPUs have no topology
+			continue
+		}
+		cpu.Threads++
+		packages[pkg] = true
+		cores[pkg+"/"+h.ReadString(filepath.Join(topology, "core_id"))] = true
+	}
+	cpu.Sockets = len(packages)
+	cpu.Cores = len(cores)
+
+	fields := cpuinfoFields(cpuinfo)
+	cpu.Vendor = fields["vendor_id"]
+	cpu.Model = fields["model name"]
+	if impl := fields["CPU implementer"]; impl != "" {
+		cpu.Vendor = armImplementers[impl]
+		if cpu.Model == "" {
+			cpu.Model = armParts[impl+"/"+fields["CPU part"]]
+		}
+	}
+	if cpu.Threads == 0 && cpu.Model == "" {
+		return nil
+	}
+	return cpu
+}
+
+// cpuinfoFields returns the fields of the first processor in /proc/cpuinfo.
+func cpuinfoFields(cpuinfo []byte) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(string(cpuinfo), "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(fields) > 0 {
+				break
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if ok {
+			fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
+
+// readOSRelease parses /etc/os-release, falling back to /usr/lib/os-release.
+func readOSRelease(h *host.Host) *OSInfo {
+	data, err := h.ReadFile("/etc/os-release")
+	if err != nil {
+		if data, err = h.ReadFile("/usr/lib/os-release"); err != nil {
+			return nil
+		}
+	}
+
+	values := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `'"`)
+		}
+		values[key] = value
+	}
+
+	return &OSInfo{
+		ID:         values["ID"],
+		Name:       values["NAME"],
+		Version:    values["VERSION"],
+		VersionID:  values["VERSION_ID"],
+		PrettyName: values["PRETTY_NAME"],
+	}
+}
+
+// readMemTotal returns MemTotal from /proc/meminfo in bytes.
+func readMemTotal(h *host.Host) uint64 {
+	data, err := h.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+// readBootTime returns the boot time (btime) from /proc/stat.
+func readBootTime(h *host.Host) time.Time {
+	data, err := h.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "btime" {
+			sec, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}
+			}
+			return time.Unix(sec, 0).UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// detectVirtualization reports whether the host is a virtual machine and
+// names its hypervisor. The x86 hypervisor CPU flag marks guests; the DMI
+// strings name the hypervisor. Xen guests are recognized from
+// /sys/hypervisor, except dom0.
+func detectVirtualization(h *host.Host, dmi *DMIInfo, cpuinfo []byte) (bool, string) {
+	hypervisor := dmiHypervisor(dmi)
+
+	fields := cpuinfoFields(cpuinfo)
+	if flags, ok := fields["flags"]; ok {
+		if strings.Contains(" "+flags+" ", " hypervisor ") {
+			if hypervisor == "" {
+				hypervisor = "unknown"
+			}
+			return true, hypervisor
+		}
+		// Bare-metal cloud instances keep their vendor's DMI strings
+		if hypervisor == "amazon" {
+			return false, ""
+		}
+	}
+	if hypervisor != "" {
+		return true, hypervisor
+	}
+
+	if h.ReadString("/sys/hypervisor/type") == "xen" &&
+		!strings.Contains(h.ReadString("/proc/xen/capabilities"), "control_d") {
+		return true, "xen"
+	}
+	return false, ""
+}
+
+// dmiHypervisor returns the hypervisor named by the DMI strings, or "".
+func dmiHypervisor(dmi *DMIInfo) string {
+	if dmi == nil {
+		return ""
+	}
+	if dmi.Vendor == "Microsoft Corporation" && dmi.ProductName == "Virtual Machine" {
+		return "microsoft"
+	}
+	for _, s := range []string{dmi.ProductName, dmi.Vendor, dmi.BoardVendor, dmi.BIOSVendor} {
+		for _, hv := range dmiHypervisors {
+			if strings.HasPrefix(s, hv.prefix) {
+				return hv.hypervisor
+			}
+		}
+	}
+	return ""
+}
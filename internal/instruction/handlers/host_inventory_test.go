@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/host/hosttest"
+)
+
+// loadArchive materializes a host capture given as text.
+func loadArchive(t *testing.T, data string) *host.Host {
+	t.Helper()
+
+	archive, err := hosttest.ParseArchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	if err := archive.WriteTo(root); err != nil {
+		t.Fatal(err)
+	}
+	return host.New(root, nil)
+}
+
+func TestDetectVirtualization(t *testing.T) {
+	tests := []struct {
+		name           string
+		capture        string
+		wantVirtual    bool
+		wantHypervisor string
+	}{
+		{
+			name: "vmware",
+			capture: `-- sys/class/dmi/id/sys_vendor --
+VMware, Inc.
+-- sys/class/dmi/id/product_name --
+VMware7,1
+-- proc/cpuinfo --
+flags		: fpu vme hypervisor lahf_lm
+`,
+			wantVirtual:    true,
+			wantHypervisor: "vmware",
+		},
+		{
+			name: "hyper-v",
+			capture: `-- sys/class/dmi/id/sys_vendor --
+Microsoft Corporation
+-- sys/class/dmi/id/product_name --
+Virtual Machine
+`,
+			wantVirtual:    true,
+			wantHypervisor: "microsoft",
+		},
+		{
+			name: "unknown hypervisor",
+			capture: `-- sys/class/dmi/id/sys_vendor --
+Acme
+-- proc/cpuinfo --
+flags		: fpu hypervisor
+`,
+			wantVirtual:    true,
+			wantHypervisor: "unknown",
+		},
+		{
+			name: "ec2 instance",
+			capture: `-- sys/class/dmi/id/sys_vendor --
+Amazon EC2
+-- proc/cpuinfo --
+flags		: fpu hypervisor
+`,
+			wantVirtual:    true,
+			wantHypervisor: "amazon",
+		},
+		{
+			name: "ec2 bare metal",
+			capture: `-- sys/class/dmi/id/sys_vendor --
+Amazon EC2
+-- proc/cpuinfo --
+flags		: fpu vme
+`,
+		},
+		{
+			name: "xen guest",
+			capture: `-- sys/hypervisor/type --
+xen
+-- proc/xen/capabilities --
+`,
+			wantVirtual:    true,
+			wantHypervisor: "xen",
+		},
+		{
+			name: "xen dom0",
+			capture: `-- sys/hypervisor/type --
+xen
+-- proc/xen/capabilities --
+control_d
+`,
+		},
+		{
+			name: "bare metal",
+			capture: `-- sys/class/dmi/id/sys_vendor --
+Supermicro
+-- proc/cpuinfo --
+flags		: fpu vme
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := loadArchive(t, tt.capture)
+			cpuinfo, _ := h.ReadFile("/proc/cpuinfo")
+
+			virtual, hypervisor := detectVirtualization(h, readDMI(h), cpuinfo)
+			if virtual != tt.wantVirtual || hypervisor != tt.wantHypervisor {
+				t.Errorf("detectVirtualization() = %v, %q, want %v, %q", virtual, hypervisor, tt.wantVirtual, tt.wantHypervisor)
+			}
+		})
+	}
+}
+
+func TestReadCPUInfo_Arm64(t *testing.T) {
+	h := loadArchive(t, `-- sys/devices/system/cpu/cpu0/topology/physical_package_id --
+0
+-- sys/devices/system/cpu/cpu0/topology/core_id --
+0
+-- sys/devices/system/cpu/cpu1/topology/physical_package_id --
+0
+-- sys/devices/system/cpu/cpu1/topology/core_id --
+1
+-- dir sys/devices/system/cpu/cpu2 --
+-- dir sys/devices/system/cpu/cpufreq --
+-- proc/cpuinfo --
+processor	: 0
+BogoMIPS	: 400.00
+Features	: fp asimd evtstrm aes pmull sha1 sha2 crc32 cpuid
+CPU implementer	: 0x41
+CPU architecture: 8
+CPU variant	: 0x0
+CPU part	: 0xd08
+CPU revision	: 3
+
+processor	: 1
+CPU implementer	: 0x41
+`)
+
+	cpuinfo, _ := h.ReadFile("/proc/cpuinfo")
+	cpu := readCPUInfo(h, cpuinfo)
+	if cpu == nil {
+		t.Fatal("readCPUInfo() = nil")
+	}
+	// cpu2 is offline and has no topology
+	want := CPUInfo{Model: "Cortex-A72", Vendor: "ARM", Sockets: 1, Cores: 2, Threads: 2}
+	if *cpu != want {
+		t.Errorf("readCPUInfo() = %+v, want %+v", *cpu, want)
+	}
+}
+
+func TestReadOSRelease_Fallback(t *testing.T) {
+	h := loadArchive(t, `-- usr/lib/os-release --
+# Debian
+NAME='Debian GNU/Linux'
+VERSION_ID="12"
+ID=debian
+`)
+
+	info := readOSRelease(h)
+	if info == nil || info.ID != "debian" || info.Name != "Debian GNU/Linux" || info.VersionID != "12" {
+		t.Errorf("readOSRelease() = %+v", info)
+	}
+}
+
+func TestCollectHostInventory_Empty(t *testing.T) {
+	if inv := collectHostInventory(host.New(t.TempDir(), nil)); inv != nil {
+		t.Errorf("collectHostInventory() = %+v, want nil", inv)
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+)
+
+// linkLayerInfiniBand is the link_layer of native InfiniBand ports; RoCE
+// ports report "Ethernet".
+const linkLayerInfiniBand = "InfiniBand"
+
+// zeroGID is an unused GID table entry.
+const zeroGID = "0000:0000:0000:0000:0000:0000:0000:0000"
+
+// RDMADevice describes an RDMA device (e.g., mlx5_0) and its ports, read
+// from /sys/class/infiniband/<dev>, which links to the device's directory
+// under its PCI function.
+type RDMADevice struct {
+	Name         string `json:"name"`
+	NodeGUID     string `json:"node_guid,omitempty"`
+	SysImageGUID string `json:"sys_image_guid,omitempty"`
+	HCAType      string `json:"hca_type,omitempty"`
+	BoardID      string `json:"board_id,omitempty"`
+	// Ports are reported with the NIC port they belong to (PortInfo.RDMA).
+	Ports []RDMAPort `json:"-"`
+}
+
+// RDMAPort holds the attributes of an RDMA port.
+type RDMAPort struct {
+	Number    int    `json:"number"`
+	State     string `json:"state,omitempty"`
+	PhysState string `json:"phys_state,omitempty"`
+	Rate      string `json:"rate,omitempty"`
+	LinkLayer string `json:"link_layer,omitempty"`
+	// LID, SMLID and SMSL are only meaningful on InfiniBand ports.
+	LID      string `json:"lid,omitempty"`
+	SMLID    string `json:"sm_lid,omitempty"`
+	SMSL     string `json:"sm_sl,omitempty"`
+	PortGUID string `json:"port_guid,omitempty"`
+	// Netdev is the network interface associated with the port, if any.
+	Netdev string    `json:"netdev,omitempty"`
+	GIDs   []GIDInfo `json:"gids,omitempty"`
+}
+
+// GIDInfo is a populated entry of a port's GID table.
+type GIDInfo struct {
+	Index  int    `json:"index"`
+	GID    string `json:"gid"`
+	Type   string `json:"type,omitempty"`
+	Netdev string `json:"netdev,omitempty"`
+}
+
+// collectRDMADevice reads the RDMA device exposed by a PCI function.
+func collectRDMADevice(h *host.Host, pciAddr, name string) (*RDMADevice, error) {
+	devPath := filepath.Join(sysBusPCIDevices, pciAddr, "infiniband", name)
+
+	entries, err := h.ReadDir(filepath.Join(devPath, "ports"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ports of %s: %w", name, err)
+	}
+
+	dev := &RDMADevice{
+		Name:         name,
+		NodeGUID:     h.ReadString(filepath.Join(devPath, "node_guid")),
+		SysImageGUID: h.ReadString(filepath.Join(devPath, "sys_image_guid")),
+		HCAType:      h.ReadString(filepath.Join(devPath, "hca_type")),
+		BoardID:      h.ReadString(filepath.Join(devPath, "board_id")),
+	}
+
+	for _, entry := range entries {
+		num, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		dev.Ports = append(dev.Ports, readRDMAPort(h, filepath.Join(devPath, "ports", entry.Name()), num))
+	}
+
+	sort.Slice(dev.Ports, func(i, j int) bool {
+		return dev.Ports[i].Number < dev.Ports[j].Number
+	})
+
+	return dev, nil
+}
+
+// readRDMAPort reads ports/<n> of an RDMA device.
+func readRDMAPort(h *host.Host, portPath string, num int) RDMAPort {
+	port := RDMAPort{
+		Number:    num,
+		State:     stripSysfsEnumPrefix(h.ReadString(filepath.Join(portPath, "state"))),
+		PhysState: stripSysfsEnumPrefix(h.ReadString(filepath.Join(portPath, "phys_state"))),
+		Rate:      h.ReadString(filepath.Join(portPath, "rate")),
+		LinkLayer: h.ReadString(filepath.Join(portPath, "link_layer")),
+		GIDs:      readGIDTable(h, portPath),
+	}
+
+	if port.LinkLayer == linkLayerInfiniBand {
+		port.LID = h.ReadString(filepath.Join(portPath, "lid"))
+		port.SMLID = h.ReadString(filepath.Join(portPath, "sm_lid"))
+		port.SMSL = h.ReadString(filepath.Join(portPath, "sm_sl"))
+		// The port GUID is the interface ID of the default (index 0) GID
+		for _, gid := range port.GIDs {
+			if gid.Index == 0 {
+				port.PortGUID = gidInterfaceID(gid.GID)
+			}
+		}
+	}
+
+	for _, gid := range port.GIDs {
+		if gid.Netdev != "" {
+			port.Netdev = gid.Netdev
+			break
+		}
+	}
+
+	return port
+}
+
+// readGIDTable returns the populated entries of a port's GID table with
+// their types ("IB/RoCE v1", "RoCE v2") and network devices.
+func readGIDTable(h *host.Host, portPath string) []GIDInfo {
+	entries, err := h.ReadDir(filepath.Join(portPath, "gids"))
+	if err != nil {
+		return nil
+	}
+
+	var gids []GIDInfo
+	for _, entry := range entries {
+		index, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		gid := h.ReadString(filepath.Join(portPath, "gids", entry.Name()))
+		if gid == "" || gid == zeroGID {
+			continue
+		}
+		gids = append(gids, GIDInfo{
+			Index:  index,
+			GID:    gid,
+			Type:   h.ReadString(filepath.Join(portPath, "gid_attrs", "types", entry.Name())),
+			Netdev: h.ReadString(filepath.Join(portPath, "gid_attrs", "ndevs", entry.Name())),
+		})
+	}
+
+	sort.Slice(gids, func(i, j int) bool {
+		return gids[i].Index < gids[j].Index
+	})
+
+	return gids
+}
+
+// mergeRDMAPorts attaches RDMA port attributes to the netdev ports of a NIC
+// and adds ports that have no netdev (e.g., InfiniBand without IPoIB).
+// A port is matched by the netdev in its GID table, then by the netdev's
+// dev_port (0-based) against the RDMA port number. Ports stay sorted by
+// number.
+func mergeRDMAPorts(pciAddr string, ports []PortInfo, dev *RDMADevice) []PortInfo {
+	for _, rdma := range dev.Ports {
+		idx := -1
+		for i, port := range ports {
+			if rdma.Netdev != "" && port.InterfaceName == rdma.Netdev {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			for i, port := range ports {
+				if port.DevPort != nil && *port.DevPort == rdma.Number-1 && port.RDMA == nil {
+					idx = i
+					break
+				}
+			}
+		}
+
+		if idx == -1 {
+			// RDMA-only ports keep their RDMA port number when it is free
+			number := rdma.Number
+			if portNumberUsed(ports, number) {
+				number = 0
+			}
+			ports = append(ports, PortInfo{
+				Number:     number,
+				State:      rdmaPortState(rdma.State),
+				Speed:      rdmaRateSpeed(rdma.Rate),
+				GUID:       rdma.PortGUID,
+				PCIAddress: pciAddr,
+				RDMA:       &rdma,
+			})
+			continue
+		}
+
+		if rdma.Netdev == "" {
+			rdma.Netdev = ports[idx].InterfaceName
+		}
+		ports[idx].GUID = rdma.PortGUID
+		ports[idx].RDMA = &rdma
+	}
+
+	numberPorts(ports)
+	return ports
+}
+
+// portNumberUsed reports whether a port already has the given number.
+func portNumberUsed(ports []PortInfo, number int) bool {
+	for _, port := range ports {
+		if port.Number == number {
+			return true
+		}
+	}
+	return false
+}
+
+// stripSysfsEnumPrefix turns "4: ACTIVE" into "ACTIVE".
+func stripSysfsEnumPrefix(value string) string {
+	if _, name, ok := strings.Cut(value, ": "); ok {
+		return name
+	}
+	return value
+}
+
+// gidInterfaceID returns the lower 64 bits of a GID
+// (e.g., "fe80:0000:0000:0000:a088:c203:004a:f31e" -> "a088:c203:004a:f31e").
+func gidInterfaceID(gid string) string {
+	groups := strings.Split(gid, ":")
+	if len(groups) != 8 {
+		return ""
+	}
+	return strings.Join(groups[4:], ":")
+}
+
+// rdmaPortState maps an RDMA port state to the netdev-style state used in PortInfo.
+func rdmaPortState(state string) string {
+	if state == "ACTIVE" {
+		return "up"
+	}
+	return "down"
+}
+
+// rdmaRateSpeed converts an RDMA rate (e.g., "400 Gb/sec (4X NDR)") to a
+// speed string such as "400G".
+func rdmaRateSpeed(rate string) string {
+	value, unit, ok := strings.Cut(rate, " ")
+	if !ok || !strings.HasPrefix(unit, "Gb/sec") {
+		return ""
+	}
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return ""
+	}
+	return value + "G"
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import "testing"
+
+func TestRDMARateSpeed(t *testing.T) {
+	tests := []struct {
+		rate string
+		want string
+	}{
+		{rate: "400 Gb/sec (4X NDR)", want: "400G"},
+		{rate: "200 Gb/sec (4X HDR)", want: "200G"},
+		{rate: "100 Gb/sec (4X EDR)", want: "100G"},
+		{rate: "2.5 Gb/sec (1X SDR)", want: "2.5G"},
+		{rate: "", want: ""},
+		{rate: "invalid", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rate, func(t *testing.T) {
+			if got := rdmaRateSpeed(tt.rate); got != tt.want {
+				t.Errorf("rdmaRateSpeed(%q) = %q, want %q", tt.rate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGIDInterfaceID(t *testing.T) {
+	tests := []struct {
+		gid  string
+		want string
+	}{
+		{gid: "fe80:0000:0000:0000:a088:c203:004a:f31e", want: "a088:c203:004a:f31e"},
+		{gid: "fe80::1", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := gidInterfaceID(tt.gid); got != tt.want {
+			t.Errorf("gidInterfaceID(%q) = %q, want %q", tt.gid, got, tt.want)
+		}
+	}
+}
+
+func TestStripSysfsEnumPrefix(t *testing.T) {
+	tests := map[string]string{
+		"4: ACTIVE":   "ACTIVE",
+		"5: LinkUp":   "LinkUp",
+		"3: Disabled": "Disabled",
+		"ACTIVE":      "ACTIVE",
+	}
+
+	for in, want := range tests {
+		if got := stripSysfsEnumPrefix(in); got != want {
+			t.Errorf("stripSysfsEnumPrefix(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+// LinkSettings holds a port's negotiated link, its link modes and FEC,
+// read with the ethtool link settings API.
+type LinkSettings struct {
+	// SpeedMbps is omitted while the link is down.
+	SpeedMbps int    `json:"speed_mbps,omitempty"`
+	Lanes     int    `json:"lanes,omitempty"`
+	Duplex    string `json:"duplex,omitempty"`
+	Autoneg   bool   `json:"autoneg"`
+	// Link modes are named as by ethtool (e.g., "100000baseSR4/Full").
+	SupportedModes  []string `json:"supported_modes,omitempty"`
+	AdvertisedModes []string `json:"advertised_modes,omitempty"`
+	PartnerModes    []string `json:"partner_modes,omitempty"`
+	// FEC is the active FEC encoding ("RS", "BaseR", "LLRS" or "off");
+	// FECModes are the encodings the port is configured to use.
+	FEC      string   `json:"fec,omitempty"`
+	FECModes []string `json:"fec_modes,omitempty"`
+	FECAuto  bool     `json:"fec_auto,omitempty"`
+}
+
+// collectLinkSettings reads the link settings and FEC of a netdev. It
+// returns nil if the driver does not support the ethtool link settings
+// API (e.g., IPoIB).
+func collectLinkSettings(h *host.Host, ifName string) *LinkSettings {
+	modes, err := h.Netlink.LinkModes(ifName)
+	if err != nil {
+		return nil
+	}
+
+	link := &LinkSettings{
+		SpeedMbps:       modes.Speed,
+		Lanes:           modes.Lanes,
+		Duplex:          modes.Duplex,
+		Autoneg:         modes.Autoneg,
+		SupportedModes:  modes.Supported,
+		AdvertisedModes: modes.Advertised,
+		PartnerModes:    modes.Peer,
+	}
+
+	// Not every driver implements FEC control
+	if fec, err := h.Netlink.FEC(ifName); err == nil {
+		link.FEC = fec.Active
+		link.FECModes = fec.Configured
+		link.FECAuto = fec.Auto
+	}
+
+	return link
+}
+
+// formatSpeed formats a speed in Mb/s as a port speed string (e.g.,
+// 100000 -> "100G", 2500 -> "2.5G", 100 -> "100M"). Unknown speeds (0 or
+// the -1 of a down link) are empty.
+func formatSpeed(mbps int) string {
+	switch {
+	case mbps <= 0:
+		return ""
+	case mbps < 1000:
+		return strconv.Itoa(mbps) + "M"
+	default:
+		return strconv.FormatFloat(float64(mbps)/1000, 'f', -1, 64) + "G"
+	}
+}
+
+// parseSpeed converts a port speed string back to Mb/s. It returns 0 for
+// strings it does not recognise.
+func parseSpeed(speed string) int {
+	multiplier := 1000.0
+	value, ok := strings.CutSuffix(speed, "G")
+	if !ok {
+		if value, ok = strings.CutSuffix(speed, "M"); !ok {
+			return 0
+		}
+		multiplier = 1
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f <= 0 {
+		return 0
+	}
+	return int(f * multiplier)
+}
+
+// portSpeeds maps exact speeds in Mb/s to the proto enum. Speeds the enum
+// cannot represent (e.g., 2.5G or 800G) are PORT_SPEED_UNSPECIFIED; the
+// exact speed is reported in the port's link settings.
+var portSpeeds = map[int]v1.PortSpeed{
+	1000:   v1.PortSpeed_PORT_SPEED_1G,
+	10000:  v1.PortSpeed_PORT_SPEED_10G,
+	25000:  v1.PortSpeed_PORT_SPEED_25G,
+	40000:  v1.PortSpeed_PORT_SPEED_40G,
+	50000:  v1.PortSpeed_PORT_SPEED_50G,
+	100000: v1.PortSpeed_PORT_SPEED_100G,
+	200000: v1.PortSpeed_PORT_SPEED_200G,
+	400000: v1.PortSpeed_PORT_SPEED_400G,
+}
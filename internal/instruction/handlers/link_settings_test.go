@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"testing"
+
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+func TestPortSpeed(t *testing.T) {
+	tests := []struct {
+		mbps  int
+		speed string
+		want  v1.PortSpeed
+	}{
+		{mbps: 100, speed: "100M", want: v1.PortSpeed_PORT_SPEED_UNSPECIFIED},
+		{mbps: 1000, speed: "1G", want: v1.PortSpeed_PORT_SPEED_1G},
+		{mbps: 2500, speed: "2.5G", want: v1.PortSpeed_PORT_SPEED_UNSPECIFIED},
+		{mbps: 5000, speed: "5G", want: v1.PortSpeed_PORT_SPEED_UNSPECIFIED},
+		{mbps: 25000, speed: "25G", want: v1.PortSpeed_PORT_SPEED_25G},
+		{mbps: 100000, speed: "100G", want: v1.PortSpeed_PORT_SPEED_100G},
+		{mbps: 400000, speed: "400G", want: v1.PortSpeed_PORT_SPEED_400G},
+		{mbps: 800000, speed: "800G", want: v1.PortSpeed_PORT_SPEED_UNSPECIFIED},
+		{mbps: -1, speed: "", want: v1.PortSpeed_PORT_SPEED_UNSPECIFIED},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.speed, func(t *testing.T) {
+			if got := formatSpeed(tt.mbps); got != tt.speed {
+				t.Errorf("formatSpeed(%d) = %q, want %q", tt.mbps, got, tt.speed)
+			}
+			if tt.mbps > 0 && parseSpeed(tt.speed) != tt.mbps {
+				t.Errorf("parseSpeed(%q) = %d, want %d", tt.speed, parseSpeed(tt.speed), tt.mbps)
+			}
+			if got := convertPortSpeed(tt.speed); got != tt.want {
+				t.Errorf("convertPortSpeed(%q) = %v, want %v", tt.speed, got, tt.want)
+			}
+		})
+	}
+
+	// RDMA rates are converted to the same strings
+	if got := convertPortSpeed(rdmaRateSpeed("200 Gb/sec (4X HDR)")); got != v1.PortSpeed_PORT_SPEED_200G {
+		t.Errorf("convertPortSpeed(HDR rate) = %v, want 200G", got)
+	}
+	if got := parseSpeed("fast"); got != 0 {
+		t.Errorf("parseSpeed(\"fast\") = %d, want 0", got)
+	}
+}
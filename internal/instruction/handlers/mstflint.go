@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+)
+
+// FirmwareInfo is the firmware record reported by `mstflint -d <pci> q`.
+type FirmwareInfo struct {
+	ImageType string `json:"image_type,omitempty"`
+	FWVersion string `json:"fw_version,omitempty"`
+	// RunningFWVersion is set when a newer image was burned but the device
+	// has not been reset yet.
+	RunningFWVersion   string    `json:"running_fw_version,omitempty"`
+	FWReleaseDate      string    `json:"fw_release_date,omitempty"`
+	ProductVersion     string    `json:"product_version,omitempty"`
+	PartNumber         string    `json:"part_number,omitempty"`
+	Description        string    `json:"description,omitempty"`
+	RomInfo            []RomInfo `json:"rom_info,omitempty"`
+	BaseGUID           string    `json:"base_guid,omitempty"`
+	GUIDCount          int       `json:"guid_count,omitempty"`
+	BaseMAC            string    `json:"base_mac,omitempty"`
+	MACCount           int       `json:"mac_count,omitempty"`
+	ImageVSD           string    `json:"image_vsd,omitempty"`
+	DeviceVSD          string    `json:"device_vsd,omitempty"`
+	PSID               string    `json:"psid,omitempty"`
+	DefaultPSID        string    `json:"default_psid,omitempty"`
+	SecurityAttributes string    `json:"security_attributes,omitempty"`
+	SecurityVersion    string    `json:"security_version,omitempty"`
+}
+
+// RomInfo describes an expansion ROM (PXE, UEFI, ...) in the firmware image.
+type RomInfo struct {
+	Type    string `json:"type"`
+	Version string `json:"version,omitempty"`
+	CPU     string `json:"cpu,omitempty"`
+}
+
+// MstflintError is an error reported by mstflint with a "-E-" line, e.g.,
+// when the device cannot be opened.
+type MstflintError struct {
+	Message string
+}
+
+// Error implements the error interface.
+func (e *MstflintError) Error() string {
+	return "mstflint: " + e.Message
+}
+
+// romInfoRegex matches one ROM entry; ROM types may contain spaces
+// (e.g., "UEFI Virtio net").
+var romInfoRegex = regexp.MustCompile(`type=(.+?)\s+version=(\S+)(?:\s+cpu=(\S+))?`)
+
+// queryFirmware runs a single mstflint query for a device and parses it.
+func queryFirmware(ctx context.Context, h *host.Host, pciAddr string) (*FirmwareInfo, error) {
+	output, err := h.Run(ctx, "mstflint", "-d", pciAddr, "q")
+	if err != nil {
+		// mstflint reports errors such as "-E- Cannot open Device" and
+		// exits non-zero; prefer its message when it is available
+		if msg := mstflintErrorMessage(string(output) + "\n" + err.Error()); msg != "" {
+			return nil, &MstflintError{Message: msg}
+		}
+		return nil, err
+	}
+	return parseMstflintQuery(string(output))
+}
+
+// parseMstflintQuery parses the "key: value" output of `mstflint q`. It
+// handles the FS2 layout (GUIDs/MACs lists) and the FS4/FS5 layout (base
+// GUID/MAC with counts), continuation lines of multi-line values (Rom Info)
+// and "N/A" values.
+func parseMstflintQuery(output string) (*FirmwareInfo, error) {
+	if msg := mstflintErrorMessage(output); msg != "" {
+		return nil, &MstflintError{Message: msg}
+	}
+
+	info := &FirmwareInfo{}
+	fields := 0
+	lastKey := ""
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		// Continuation of the previous value (e.g., further ROMs)
+		if line[0] == ' ' || line[0] == '\t' {
+			if lastKey == "Rom Info" {
+				info.RomInfo = append(info.RomInfo, parseRomInfo(line)...)
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		lastKey = key
+		if value == "N/A" {
+			value = ""
+		}
+		fields++
+
+		switch key {
+		case "Image type":
+			info.ImageType = value
+		case "FW Version":
+			info.FWVersion = value
+		case "FW Version(Running)":
+			info.RunningFWVersion = value
+		case "FW Release Date":
+			info.FWReleaseDate = value
+		case "Product Version":
+			info.ProductVersion = value
+		case "Part Number":
+			info.PartNumber = value
+		case "Description":
+			// Newer versions print the product description and reuse the
+			// key as the header of the GUID/MAC table ("UID GuidsNumber")
+			if !strings.HasPrefix(value, "UID") && !strings.HasPrefix(value, "Node") {
+				info.Description = value
+			}
+		case "Rom Info":
+			info.RomInfo = append(info.RomInfo, parseRomInfo(value)...)
+		case "Base GUID":
+			info.BaseGUID, info.GUIDCount = parseBaseAndCount(value)
+		case "Base MAC":
+			info.BaseMAC, info.MACCount = parseBaseAndCount(value)
+		case "GUIDs":
+			// FS2: one GUID per node/port
+			guids := strings.Fields(value)
+			if len(guids) > 0 {
+				info.BaseGUID, info.GUIDCount = guids[0], len(guids)
+			}
+		case "MACs":
+			macs := strings.Fields(value)
+			if len(macs) > 0 {
+				info.BaseMAC, info.MACCount = macs[0], len(macs)
+			}
+		case "Image VSD":
+			info.ImageVSD = value
+		case "Device VSD":
+			info.DeviceVSD = value
+		case "VSD":
+			info.ImageVSD = value
+		case "PSID":
+			info.PSID = value
+		case "Orig PSID":
+			info.DefaultPSID = value
+		case "Security Attributes":
+			info.SecurityAttributes = value
+		case "Security Ver":
+			info.SecurityVersion = value
+		default:
+			fields--
+		}
+	}
+
+	if fields == 0 {
+		return nil, fmt.Errorf("no firmware fields in mstflint output")
+	}
+
+	return info, nil
+}
+
+// parseRomInfo parses a Rom Info value, which may hold several entries.
+func parseRomInfo(value string) []RomInfo {
+	var roms []RomInfo
+	for _, m := range romInfoRegex.FindAllStringSubmatch(value, -1) {
+		roms = append(roms, RomInfo{Type: m[1], Version: m[2], CPU: m[3]})
+	}
+	return roms
+}
+
+// parseBaseAndCount parses "<base> <count>" (e.g., "b8cef60300a4d9ec 4").
+func parseBaseAndCount(value string) (string, int) {
+	parts := strings.Fields(value)
+	if len(parts) == 0 {
+		return "", 0
+	}
+	count := 0
+	if len(parts) > 1 {
+		count, _ = strconv.Atoi(parts[1])
+	}
+	return parts[0], count
+}
+
+// mstflintErrorMessage returns the first "-E-" message in output, if any.
+func mstflintErrorMessage(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if idx := strings.Index(line, "-E-"); idx != -1 {
+			return strings.TrimSpace(line[idx+3:])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/host/hosttest"
+)
+
+func TestParseMstflintQuery(t *testing.T) {
+	tests := []struct {
+		file    string
+		want    *FirmwareInfo
+		wantErr string
+	}{
+		{
+			file: "fs2_connectx3_4.14.txt",
+			want: &FirmwareInfo{
+				ImageType:      "FS2",
+				FWVersion:      "2.42.5000",
+				FWReleaseDate:  "5.9.2017",
+				ProductVersion: "02.42.50.00",
+				RomInfo:        []RomInfo{{Type: "PXE", Version: "3.4.752"}},
+				BaseGUID:       "e41d2d0300a2b3c0",
+				GUIDCount:      4,
+				BaseMAC:        "e41d2da2b3c1",
+				MACCount:       2,
+				PSID:           "MT_1090120019",
+			},
+		},
+		{
+			file: "fs4_connectx5_4.26.txt",
+			want: &FirmwareInfo{
+				ImageType:      "FS4",
+				FWVersion:      "16.35.3006",
+				FWReleaseDate:  "18.10.2023",
+				ProductVersion: "16.35.3006",
+				RomInfo: []RomInfo{
+					{Type: "UEFI", Version: "14.29.14", CPU: "AMD64,AARCH64"},
+					{Type: "PXE", Version: "3.6.902", CPU: "AMD64"},
+				},
+				BaseGUID:  "b8cef60300a4d9ec",
+				GUIDCount: 4,
+				BaseMAC:   "b8cef6a4d9ec",
+				MACCount:  4,
+				PSID:      "MT_0000000011",
+			},
+		},
+		{
+			file: "fs4_connectx6dx_custom_psid_4.24.txt",
+			want: &FirmwareInfo{
+				ImageType:        "FS4",
+				FWVersion:        "22.36.1010",
+				RunningFWVersion: "22.34.1002",
+				FWReleaseDate:    "7.3.2023",
+				ProductVersion:   "22.36.1010",
+				RomInfo: []RomInfo{
+					{Type: "UEFI", Version: "14.29.15", CPU: "AMD64,AARCH64"},
+					{Type: "PXE", Version: "3.6.804", CPU: "AMD64"},
+				},
+				BaseGUID:           "1070fd0300b3516c",
+				GUIDCount:          8,
+				BaseMAC:            "1070fdb3516c",
+				MACCount:           8,
+				PSID:               "DEL0000000027",
+				DefaultPSID:        "MT_0000000359",
+				SecurityAttributes: "secure-fw",
+			},
+		},
+		{
+			file: "fs5_bluefield3_4.28.txt",
+			want: &FirmwareInfo{
+				ImageType:      "FS5",
+				FWVersion:      "32.39.2048",
+				FWReleaseDate:  "13.12.2023",
+				ProductVersion: "32.39.2048",
+				PartNumber:     "900-9D3B6-00CV-AA0_Ax",
+				Description:    "NVIDIA BlueField-3 B3220 P-Series FHHL DPU; 200GbE (default mode) / NDR200 IB; Dual-port QSFP112; PCIe Gen5.0 x16",
+				RomInfo: []RomInfo{
+					{Type: "UEFI Virtio net", Version: "21.4.13", CPU: "AMD64,AARCH64"},
+					{Type: "UEFI Virtio blk", Version: "22.4.12", CPU: "AMD64,AARCH64"},
+					{Type: "UEFI", Version: "14.32.17", CPU: "AMD64,AARCH64"},
+					{Type: "PXE", Version: "3.7.300", CPU: "AMD64"},
+				},
+				BaseGUID:           "a088c20300f1a2b3",
+				GUIDCount:          38,
+				BaseMAC:            "a088c2f1a2b3",
+				MACCount:           38,
+				PSID:               "MT_0000000884",
+				SecurityAttributes: "secure-fw",
+				SecurityVersion:    "2",
+			},
+		},
+		{
+			file:    "error_cannot_open.txt",
+			wantErr: "mstflint: Cannot open Device: 0000:3b:00.0. No such file or directory. MFE_CR_ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "mstflint", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := parseMstflintQuery(string(data))
+			if tt.wantErr != "" {
+				var mstErr *MstflintError
+				if !errors.As(err, &mstErr) {
+					t.Fatalf("expected MstflintError, got %v", err)
+				}
+				if err.Error() != tt.wantErr {
+					t.Errorf("error = %q, want %q", err.Error(), tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMstflintQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMstflintQuery_NoFields(t *testing.T) {
+	if _, err := parseMstflintQuery("mstflint: unrecognized option\n"); err == nil {
+		t.Error("expected error for output without firmware fields")
+	}
+}
+
+func TestQueryFirmware(t *testing.T) {
+	runner := &hosttest.FakeRunner{
+		Errors: map[string]error{
+			"mstflint -d 0000:3b:00.0 q": errors.New("mstflint failed: exit status 1: -E- Cannot open Device: 0000:3b:00.0. MFE_CR_ERROR"),
+		},
+	}
+	h := host.New(t.TempDir(), runner)
+
+	_, err := queryFirmware(context.Background(), h, "0000:3b:00.0")
+	var mstErr *MstflintError
+	if !errors.As(err, &mstErr) {
+		t.Fatalf("expected MstflintError, got %v", err)
+	}
+	if mstErr.Message != "Cannot open Device: 0000:3b:00.0. MFE_CR_ERROR" {
+		t.Errorf("message = %q", mstErr.Message)
+	}
+
+	// mstflint not installed
+	if _, err := queryFirmware(context.Background(), h, "0000:3b:00.1"); err == nil || errors.As(err, &mstErr) {
+		t.Errorf("expected plain error for missing mstflint, got %v", err)
+	}
+
+	if calls := runner.Calls(); len(calls) != 2 {
+		t.Errorf("expected one mstflint run per query, got %v", calls)
+	}
+}
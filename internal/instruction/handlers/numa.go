@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+)
+
+// ConditionRemoteIRQAffinity is reported when IRQs of a NIC are affine only
+// to CPUs outside the NIC's NUMA node.
+const ConditionRemoteIRQAffinity = "remote_irq_affinity"
+
+// NUMAInfo describes the NUMA locality of a PCI function and its interrupts.
+type NUMAInfo struct {
+	// Node is the NUMA node of the function, or -1 if the platform does
+	// not report one.
+	Node         int       `json:"numa_node"`
+	LocalCPUList string    `json:"local_cpulist,omitempty"`
+	IRQs         []IRQInfo `json:"irqs,omitempty"`
+}
+
+// IRQInfo describes an MSI-X interrupt of a PCI function.
+type IRQInfo struct {
+	IRQ          int    `json:"irq"`
+	Name         string `json:"name,omitempty"`
+	AffinityList string `json:"smp_affinity_list,omitempty"`
+	// Remote is set when none of the CPUs the IRQ is affine to are local
+	// to the function's NUMA node.
+	Remote bool `json:"remote,omitempty"`
+}
+
+// ChannelInfo holds the number of combined (RX/TX) channels of a netdev.
+type ChannelInfo struct {
+	Combined    int `json:"combined"`
+	MaxCombined int `json:"max_combined,omitempty"`
+}
+
+// collectNUMAInfo reads the NUMA node, local CPUs and MSI-X IRQs of a PCI
+// function. It returns nil if sysfs does not report a NUMA node.
+func collectNUMAInfo(h *host.Host, pciAddr string) *NUMAInfo {
+	devPath := filepath.Join(sysBusPCIDevices, pciAddr)
+
+	nodeStr := h.ReadString(filepath.Join(devPath, "numa_node"))
+	if nodeStr == "" {
+		return nil
+	}
+	node, err := strconv.Atoi(nodeStr)
+	if err != nil {
+		return nil
+	}
+
+	info := &NUMAInfo{
+		Node:         node,
+		LocalCPUList: h.ReadString(filepath.Join(devPath, "local_cpulist")),
+	}
+
+	entries, err := h.ReadDir(filepath.Join(devPath, "msi_irqs"))
+	if err != nil {
+		return info
+	}
+
+	var localCPUs map[int]bool
+	if node >= 0 {
+		localCPUs = cpuSet(parseCPUList(info.LocalCPUList))
+	}
+	names := readIRQNames(h)
+
+	for _, entry := range entries {
+		irq, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		irqInfo := IRQInfo{
+			IRQ:          irq,
+			Name:         names[irq],
+			AffinityList: h.ReadString(fmt.Sprintf("/proc/irq/%d/smp_affinity_list", irq)),
+		}
+		if len(localCPUs) > 0 && irqInfo.AffinityList != "" {
+			irqInfo.Remote = true
+			for _, cpu := range parseCPUList(irqInfo.AffinityList) {
+				if localCPUs[cpu] {
+					irqInfo.Remote = false
+					break
+				}
+			}
+		}
+		info.IRQs = append(info.IRQs, irqInfo)
+	}
+
+	sort.Slice(info.IRQs, func(i, j int) bool {
+		return info.IRQs[i].IRQ < info.IRQs[j].IRQ
+	})
+
+	return info
+}
+
+// irqAffinityCondition returns a remote_irq_affinity condition if any IRQ of
+// the function is affine only to remote CPUs.
+func irqAffinityCondition(info *NUMAInfo) *Condition {
+	if info == nil {
+		return nil
+	}
+
+	var remote []string
+	for _, irq := range info.IRQs {
+		if irq.Remote {
+			remote = append(remote, fmt.Sprintf("%d (CPUs %s)", irq.IRQ, irq.AffinityList))
+		}
+	}
+	if len(remote) == 0 {
+		return nil
+	}
+
+	return &Condition{
+		Type: ConditionRemoteIRQAffinity,
+		Message: fmt.Sprintf("IRQs affine to CPUs outside NUMA node %d (local CPUs %s): %s",
+			info.Node, info.LocalCPUList, strings.Join(remote, ", ")),
+	}
+}
+
+// readIRQNames maps IRQ numbers to their action names (the last column of
+// /proc/interrupts, e.g., "mlx5_comp0@pci:0000:3b:00.0").
+func readIRQNames(h *host.Host) map[int]string {
+	names := make(map[int]string)
+
+	data, err := h.ReadFile("/proc/interrupts")
+	if err != nil {
+		return names
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		num, rest, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		irq, err := strconv.Atoi(num)
+		if err != nil {
+			continue
+		}
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			names[irq] = fields[len(fields)-1]
+		}
+	}
+
+	return names
+}
+
+// collectChannels returns the combined channel count of a netdev, read
+// over ethtool netlink, or the number of RX queues in sysfs if the driver
+// does not report channels.
+func collectChannels(h *host.Host, netPath, ifName string) *ChannelInfo {
+	if channels, err := h.Netlink.Channels(ifName); err == nil && channels.Combined > 0 {
+		return &ChannelInfo{Combined: channels.Combined, MaxCombined: channels.MaxCombined}
+	}
+
+	entries, err := h.ReadDir(filepath.Join(netPath, ifName, "queues"))
+	if err != nil {
+		return nil
+	}
+	rx := 0
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "rx-") {
+			rx++
+		}
+	}
+	if rx == 0 {
+		return nil
+	}
+	return &ChannelInfo{Combined: rx}
+}
+
+// parseCPUList parses a kernel CPU list such as "0-15,32-47".
+func parseCPUList(list string) []int {
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(list), ",") {
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			continue
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(hi); err != nil {
+				continue
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus
+}
+
+// cpuSet converts a CPU slice to a set.
+func cpuSet(cpus []int) map[int]bool {
+	set := make(map[int]bool, len(cpus))
+	for _, cpu := range cpus {
+		set[cpu] = true
+	}
+	return set
+}
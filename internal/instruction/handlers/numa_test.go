@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/host/hosttest"
+)
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		list string
+		want []int
+	}{
+		{list: "0-3,8", want: []int{0, 1, 2, 3, 8}},
+		{list: "64", want: []int{64}},
+		{list: "0-1,32-33\n", want: []int{0, 1, 32, 33}},
+		{list: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := parseCPUList(tt.list); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCPUList(%q) = %v, want %v", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestCollectChannels(t *testing.T) {
+	h := hosttest.Load(t, filepath.Join("testdata", "hosts", "cx5.txt"))
+	netPath := filepath.Join(sysBusPCIDevices, "0000:3b:00.0", "net")
+
+	want := &ChannelInfo{Combined: 3, MaxCombined: 63}
+	if got := collectChannels(h, netPath, "ens1f0np0"); !reflect.DeepEqual(got, want) {
+		t.Errorf("collectChannels() = %+v, want %+v", got, want)
+	}
+
+	// Without a channels reply, the RX queues are counted
+	want = &ChannelInfo{Combined: 2}
+	netPath = filepath.Join(sysBusPCIDevices, "0000:3b:00.1", "net")
+	if got := collectChannels(h, netPath, "ens1f1np1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("collectChannels() without netlink = %+v, want %+v", got, want)
+	}
+}
+
+func TestIRQAffinityCondition(t *testing.T) {
+	info := &NUMAInfo{
+		Node:         0,
+		LocalCPUList: "0-15",
+		IRQs: []IRQInfo{
+			{IRQ: 150, AffinityList: "0-63"},
+			{IRQ: 153, AffinityList: "20", Remote: true},
+		},
+	}
+
+	cond := irqAffinityCondition(info)
+	if cond == nil || cond.Type != ConditionRemoteIRQAffinity {
+		t.Fatalf("expected remote IRQ condition, got %+v", cond)
+	}
+	if want := "IRQs affine to CPUs outside NUMA node 0 (local CPUs 0-15): 153 (CPUs 20)"; cond.Message != want {
+		t.Errorf("message = %q, want %q", cond.Message, want)
+	}
+
+	info.IRQs = info.IRQs[:1]
+	if cond := irqAffinityCondition(info); cond != nil {
+		t.Errorf("expected no condition, got %+v", cond)
+	}
+}
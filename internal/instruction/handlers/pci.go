@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+)
+
+const (
+	// sysBusPCIDevices is the sysfs directory listing all PCI functions.
+	sysBusPCIDevices = "/sys/bus/pci/devices"
+
+	// mellanoxVendorID is the PCI vendor ID of Mellanox/NVIDIA networking devices.
+	mellanoxVendorID = "0x15b3"
+
+	// pciClassNetwork is the PCI base class of network controllers
+	// (Ethernet 0x0200, InfiniBand 0x0207, ...).
+	pciClassNetwork = "0x02"
+)
+
+// PCIDevice describes a PCI function as reported by sysfs.
+type PCIDevice struct {
+	Address           string `json:"address"`
+	VendorID          string `json:"vendor_id"`
+	DeviceID          string `json:"device_id"`
+	SubsystemVendorID string `json:"subsystem_vendor_id,omitempty"`
+	SubsystemDeviceID string `json:"subsystem_device_id,omitempty"`
+	Class             string `json:"class"`
+	Revision          string `json:"revision,omitempty"`
+	Driver            string `json:"driver,omitempty"`
+	IOMMUGroup        string `json:"iommu_group,omitempty"`
+	// PhysFn is the address of the physical function if this is an SR-IOV
+	// virtual function.
+	PhysFn string `json:"physfn,omitempty"`
+}
+
+// lspciAddressRegex matches the PCI address at the start of an `lspci -D` line.
+// Domains may have more than four digits (e.g., VMD domains) and the function
+// is matched as a hex number.
+var lspciAddressRegex = regexp.MustCompile(`^([0-9a-f]{4,}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-9a-f]+)\s`)
+
+// findMellanoxPCIDevices finds Mellanox physical functions by walking sysfs.
+// SR-IOV virtual functions are reported under their PF and are skipped.
+// If sysfs is not available, it falls back to lspci, which cannot tell
+// VFs apart.
+func findMellanoxPCIDevices(ctx context.Context, h *host.Host) ([]PCIDevice, error) {
+	devices, err := enumeratePCIDevices(h, mellanoxVendorID, pciClassNetwork)
+	if err == nil {
+		pfs := devices[:0]
+		for _, dev := range devices {
+			if dev.PhysFn == "" {
+				pfs = append(pfs, dev)
+			}
+		}
+		return pfs, nil
+	}
+
+	addresses, lspciErr := findMellanoxPCIDevicesLspci(ctx, h)
+	if lspciErr != nil {
+		return nil, fmt.Errorf("sysfs enumeration failed: %v; lspci fallback failed: %w", err, lspciErr)
+	}
+
+	devices = make([]PCIDevice, 0, len(addresses))
+	for _, addr := range addresses {
+		devices = append(devices, PCIDevice{Address: addr, VendorID: mellanoxVendorID})
+	}
+	return devices, nil
+}
+
+// enumeratePCIDevices lists the PCI functions in sysfs that match the
+// vendor ID and class prefix (e.g., "0x02" for network controllers).
+// Results are sorted by PCI address.
+func enumeratePCIDevices(h *host.Host, vendorID, classPrefix string) ([]PCIDevice, error) {
+	entries, err := h.ReadDir(sysBusPCIDevices)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", sysBusPCIDevices, err)
+	}
+
+	var devices []PCIDevice
+	for _, entry := range entries {
+		devPath := filepath.Join(sysBusPCIDevices, entry.Name())
+
+		vendor := h.ReadString(filepath.Join(devPath, "vendor"))
+		if !strings.EqualFold(vendor, vendorID) {
+			continue
+		}
+
+		class := h.ReadString(filepath.Join(devPath, "class"))
+		if !strings.HasPrefix(strings.ToLower(class), classPrefix) {
+			continue
+		}
+
+		devices = append(devices, readPCIDevice(h, devPath))
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Address < devices[j].Address
+	})
+
+	return devices, nil
+}
+
+// readPCIDevice reads the identification attributes of a PCI function.
+func readPCIDevice(h *host.Host, devPath string) PCIDevice {
+	return PCIDevice{
+		Address:           filepath.Base(devPath),
+		VendorID:          h.ReadString(filepath.Join(devPath, "vendor")),
+		DeviceID:          h.ReadString(filepath.Join(devPath, "device")),
+		SubsystemVendorID: h.ReadString(filepath.Join(devPath, "subsystem_vendor")),
+		SubsystemDeviceID: h.ReadString(filepath.Join(devPath, "subsystem_device")),
+		Class:             h.ReadString(filepath.Join(devPath, "class")),
+		Revision:          h.ReadString(filepath.Join(devPath, "revision")),
+		Driver:            h.LinkName(filepath.Join(devPath, "driver")),
+		IOMMUGroup:        h.LinkName(filepath.Join(devPath, "iommu_group")),
+		PhysFn:            h.LinkName(filepath.Join(devPath, "physfn")),
+	}
+}
+
+// findMellanoxPCIDevicesLspci finds Mellanox devices using lspci.
+// Used only when sysfs cannot be read.
+func findMellanoxPCIDevicesLspci(ctx context.Context, h *host.Host) ([]string, error) {
+	output, err := h.Run(ctx, "lspci", "-D", "-d", "15b3:")
+	if err != nil {
+		return nil, fmt.Errorf("lspci command failed: %w", err)
+	}
+
+	return parseLspciAddresses(string(output)), nil
+}
+
+// parseLspciAddresses extracts PCI addresses from `lspci -D` output.
+func parseLspciAddresses(output string) []string {
+	var devices []string
+	for _, line := range strings.Split(output, "\n") {
+		if matches := lspciAddressRegex.FindStringSubmatch(line); len(matches) > 1 {
+			devices = append(devices, matches[1])
+		}
+	}
+	return devices
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+)
+
+// writeSysfsPCIDevice creates a fake sysfs PCI function under dir.
+func writeSysfsPCIDevice(t *testing.T, dir, addr string, attrs map[string]string, links map[string]string) {
+	t.Helper()
+
+	devPath := filepath.Join(dir, addr)
+	if err := os.MkdirAll(devPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range attrs {
+		if err := os.WriteFile(filepath.Join(devPath, name), []byte(value+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(devPath, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEnumeratePCIDevices(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, sysBusPCIDevices)
+
+	writeSysfsPCIDevice(t, dir, "0000:03:00.1", map[string]string{
+		"vendor": "0x15b3", "device": "0x1017", "class": "0x020000",
+	}, nil)
+	writeSysfsPCIDevice(t, dir, "0000:03:00.0", map[string]string{
+		"vendor":           "0x15b3",
+		"device":           "0x1017",
+		"subsystem_vendor": "0x15b3",
+		"subsystem_device": "0x0007",
+		"class":            "0x020000",
+		"revision":         "0x00",
+	}, map[string]string{
+		"driver":      "../../../bus/pci/drivers/mlx5_core",
+		"iommu_group": "../../../kernel/iommu_groups/27",
+	})
+	// Function numbers above 9 (ARI) must be found
+	writeSysfsPCIDevice(t, dir, "10000:81:00.10", map[string]string{
+		"vendor": "0x15b3", "device": "0x101e", "class": "0x020700",
+	}, nil)
+	// Mellanox non-network function (e.g., BlueField management)
+	writeSysfsPCIDevice(t, dir, "0000:03:00.2", map[string]string{
+		"vendor": "0x15b3", "device": "0xc2d5", "class": "0x080000",
+	}, nil)
+	// Other vendor
+	writeSysfsPCIDevice(t, dir, "0000:00:1f.6", map[string]string{
+		"vendor": "0x8086", "device": "0x15bb", "class": "0x020000",
+	}, nil)
+
+	devices, err := enumeratePCIDevices(host.New(root, nil), mellanoxVendorID, pciClassNetwork)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []PCIDevice{
+		{
+			Address:           "0000:03:00.0",
+			VendorID:          "0x15b3",
+			DeviceID:          "0x1017",
+			SubsystemVendorID: "0x15b3",
+			SubsystemDeviceID: "0x0007",
+			Class:             "0x020000",
+			Revision:          "0x00",
+			Driver:            "mlx5_core",
+			IOMMUGroup:        "27",
+		},
+		{Address: "0000:03:00.1", VendorID: "0x15b3", DeviceID: "0x1017", Class: "0x020000"},
+		{Address: "10000:81:00.10", VendorID: "0x15b3", DeviceID: "0x101e", Class: "0x020700"},
+	}
+
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("devices = %+v, want %+v", devices, want)
+	}
+}
+
+func TestEnumeratePCIDevices_MissingDir(t *testing.T) {
+	_, err := enumeratePCIDevices(host.New(t.TempDir(), nil), mellanoxVendorID, pciClassNetwork)
+	if err == nil {
+		t.Error("expected error for missing sysfs directory")
+	}
+}
+
+func TestParseLspciAddresses(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{
+			name: "standard output",
+			output: "0000:03:00.0 Ethernet controller: Mellanox Technologies MT27800 Family [ConnectX-5]\n" +
+				"0000:03:00.1 Ethernet controller: Mellanox Technologies MT27800 Family [ConnectX-5]\n",
+			want: []string{"0000:03:00.0", "0000:03:00.1"},
+		},
+		{
+			name:   "multi-digit function and long domain",
+			output: "10000:81:00.10 Infiniband controller: Mellanox Technologies MT2910 Family [ConnectX-7]\n",
+			want:   []string{"10000:81:00.10"},
+		},
+		{
+			name:   "empty output",
+			output: "",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseLspciAddresses(tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLspciAddresses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+)
+
+// ConditionDegradedPCIeLink is reported when a NIC's PCIe link trained below
+// the speed or width the device supports.
+const ConditionDegradedPCIeLink = "degraded_pcie_link"
+
+// Condition is a problem detected on a NIC during hardware collection.
+type Condition struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// PCIeLink describes the negotiated and maximum link of a PCI function.
+type PCIeLink struct {
+	Address      string  `json:"address"`
+	CurrentSpeed float64 `json:"current_speed_gts"`
+	CurrentWidth int     `json:"current_width"`
+	MaxSpeed     float64 `json:"max_speed_gts"`
+	MaxWidth     int     `json:"max_width"`
+	// Degraded is set when the link trained below its maximum speed or width.
+	Degraded bool `json:"degraded"`
+	// Upstream is the link of the port the function is attached to
+	// (a root port or switch downstream port).
+	Upstream *PCIeLink `json:"upstream,omitempty"`
+}
+
+// pciAddressRegex matches a PCI address such as 0000:3b:00.0.
+var pciAddressRegex = regexp.MustCompile(`^[0-9a-f]{4,}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-9a-f]+$`)
+
+// collectPCIeLink reads the link attributes of a PCI function and its
+// upstream port. It returns nil if the function reports no link (e.g., an
+// integrated device).
+func collectPCIeLink(h *host.Host, pciAddr string) *PCIeLink {
+	devPath := filepath.Join(sysBusPCIDevices, pciAddr)
+	link := readPCIeLink(h, devPath, pciAddr)
+	if link == nil {
+		return nil
+	}
+
+	// The parent of the function in the /sys/devices hierarchy is its upstream port
+	if resolved, err := h.EvalSymlinks(devPath); err == nil {
+		parent := filepath.Dir(resolved)
+		if pciAddressRegex.MatchString(filepath.Base(parent)) {
+			link.Upstream = readPCIeLink(h, parent, filepath.Base(parent))
+		}
+	}
+
+	return link
+}
+
+// readPCIeLink reads current/max link speed and width from a device directory.
+func readPCIeLink(h *host.Host, devPath, addr string) *PCIeLink {
+	link := &PCIeLink{
+		Address:      addr,
+		CurrentSpeed: parsePCIeSpeed(h.ReadString(filepath.Join(devPath, "current_link_speed"))),
+		CurrentWidth: parsePCIeWidth(h.ReadString(filepath.Join(devPath, "current_link_width"))),
+		MaxSpeed:     parsePCIeSpeed(h.ReadString(filepath.Join(devPath, "max_link_speed"))),
+		MaxWidth:     parsePCIeWidth(h.ReadString(filepath.Join(devPath, "max_link_width"))),
+	}
+	if link.MaxSpeed == 0 || link.MaxWidth == 0 {
+		return nil
+	}
+
+	link.Degraded = link.CurrentSpeed < link.MaxSpeed || link.CurrentWidth < link.MaxWidth
+	return link
+}
+
+// pcieLinkCondition returns a degraded_pcie_link condition for link, or nil
+// if the link trained at the device's capability.
+func pcieLinkCondition(link *PCIeLink) *Condition {
+	if link == nil || !link.Degraded {
+		return nil
+	}
+
+	msg := fmt.Sprintf("PCIe link trained at %s, device supports %s",
+		formatPCIeLink(link.CurrentSpeed, link.CurrentWidth), formatPCIeLink(link.MaxSpeed, link.MaxWidth))
+
+	if up := link.Upstream; up != nil {
+		if up.MaxSpeed < link.MaxSpeed || up.MaxWidth < link.MaxWidth {
+			msg += fmt.Sprintf("; limited by upstream port %s (max %s)", up.Address, formatPCIeLink(up.MaxSpeed, up.MaxWidth))
+		}
+	}
+
+	return &Condition{Type: ConditionDegradedPCIeLink, Message: msg}
+}
+
+// parsePCIeSpeed parses a sysfs link speed such as "16.0 GT/s PCIe" or
+// "8 GT/s" (older kernels) into GT/s. Unknown speeds return 0.
+func parsePCIeSpeed(value string) float64 {
+	fields := strings.Fields(value)
+	if len(fields) < 2 || fields[1] != "GT/s" {
+		return 0
+	}
+	speed, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return speed
+}
+
+// parsePCIeWidth parses a sysfs link width such as "16" or "x16".
+func parsePCIeWidth(value string) int {
+	width, err := strconv.Atoi(strings.TrimPrefix(value, "x"))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// pcieGeneration returns the PCIe generation for a link speed in GT/s.
+func pcieGeneration(speed float64) int {
+	switch {
+	case speed >= 64:
+		return 6
+	case speed >= 32:
+		return 5
+	case speed >= 16:
+		return 4
+	case speed >= 8:
+		return 3
+	case speed >= 5:
+		return 2
+	case speed >= 2.5:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// formatPCIeLink formats a link as, e.g., "Gen4 x8 (16 GT/s)".
+func formatPCIeLink(speed float64, width int) string {
+	return fmt.Sprintf("Gen%d x%d (%s GT/s)", pcieGeneration(speed), width, strconv.FormatFloat(speed, 'f', -1, 64))
+}
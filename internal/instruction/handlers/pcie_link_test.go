@@ -0,0 +1,67 @@
+package handlers
+
+import "testing"
+
+func TestParsePCIeSpeed(t *testing.T) {
+	tests := map[string]float64{
+		"32.0 GT/s PCIe": 32,
+		"16.0 GT/s PCIe": 16,
+		"8 GT/s":         8,
+		"2.5 GT/s":       2.5,
+		"Unknown":        0,
+		"":               0,
+	}
+
+	for in, want := range tests {
+		if got := parsePCIeSpeed(in); got != want {
+			t.Errorf("parsePCIeSpeed(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestPCIeLinkCondition(t *testing.T) {
+	tests := []struct {
+		name string
+		link *PCIeLink
+		want string
+	}{
+		{
+			name: "no link",
+			link: nil,
+		},
+		{
+			name: "full link",
+			link: &PCIeLink{CurrentSpeed: 16, CurrentWidth: 16, MaxSpeed: 16, MaxWidth: 16},
+		},
+		{
+			name: "trained below capability",
+			link: &PCIeLink{CurrentSpeed: 8, CurrentWidth: 16, MaxSpeed: 16, MaxWidth: 16, Degraded: true,
+				Upstream: &PCIeLink{Address: "0000:00:03.1", MaxSpeed: 16, MaxWidth: 16}},
+			want: "PCIe link trained at Gen3 x16 (8 GT/s), device supports Gen4 x16 (16 GT/s)",
+		},
+		{
+			name: "slot limited",
+			link: &PCIeLink{CurrentSpeed: 16, CurrentWidth: 8, MaxSpeed: 16, MaxWidth: 16, Degraded: true,
+				Upstream: &PCIeLink{Address: "0000:97:02.0", MaxSpeed: 16, MaxWidth: 8}},
+			want: "PCIe link trained at Gen4 x8 (16 GT/s), device supports Gen4 x16 (16 GT/s); limited by upstream port 0000:97:02.0 (max Gen4 x8 (16 GT/s))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond := pcieLinkCondition(tt.link)
+			if tt.want == "" {
+				if cond != nil {
+					t.Errorf("expected no condition, got %+v", cond)
+				}
+				return
+			}
+			if cond == nil {
+				t.Fatal("expected condition")
+			}
+			if cond.Type != ConditionDegradedPCIeLink || cond.Message != tt.want {
+				t.Errorf("condition = %+v, want message %q", cond, tt.want)
+			}
+		})
+	}
+}
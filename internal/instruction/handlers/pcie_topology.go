@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"encoding/binary"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+)
+
+// PCIe device/port types (PCI Express Capabilities register, bits 7:4).
+var pciePortTypes = map[uint16]string{
+	0x0: "endpoint",
+	0x1: "legacy_endpoint",
+	0x4: "root_port",
+	0x5: "switch_upstream",
+	0x6: "switch_downstream",
+	0x7: "pcie_to_pci_bridge",
+	0x8: "pci_to_pcie_bridge",
+	0x9: "rc_integrated_endpoint",
+	0xa: "rc_event_collector",
+}
+
+// acsControls names the bits of the ACS Capability and Control registers.
+var acsControls = []string{
+	"source_validation",
+	"translation_blocking",
+	"p2p_request_redirect",
+	"p2p_completion_redirect",
+	"upstream_forwarding",
+	"p2p_egress_control",
+	"direct_translated_p2p",
+}
+
+const (
+	pciCapabilityList = 0x34
+	pciStatusCapList  = 0x10
+	pciCapIDExpress   = 0x10
+	pciExtCapIDACS    = 0x0d
+	pciExtCapStart    = 0x100
+	pciClassBridge    = "0x0604"
+
+	// maxPCIeDepth bounds the walk down a PCIe hierarchy.
+	maxPCIeDepth = 16
+)
+
+// PCIeNode is a PCI function in the PCIe hierarchy of the host bridges
+// the Mellanox NICs are attached to.
+type PCIeNode struct {
+	PCIDevice
+	// Type is the PCIe port type (e.g., "root_port", "switch_upstream",
+	// "switch_downstream", "endpoint").
+	Type string    `json:"type"`
+	Link *PCIeLink `json:"link,omitempty"`
+	ACS  *ACSInfo  `json:"acs,omitempty"`
+	// NIC marks the Mellanox network functions.
+	NIC      bool       `json:"nic,omitempty"`
+	Children []PCIeNode `json:"children,omitempty"`
+}
+
+// PCIeHostBridge is a PCI root complex with the root ports below it that
+// lead to a Mellanox NIC.
+type PCIeHostBridge struct {
+	// Name is the host bridge's sysfs name, e.g., "pci0000:3a".
+	Name      string     `json:"name"`
+	RootPorts []PCIeNode `json:"root_ports"`
+}
+
+// ACSInfo is the Access Control Services capability of a bridge. With
+// p2p_request_redirect or p2p_completion_redirect enabled, peer-to-peer
+// traffic between devices below the bridge goes through the root complex.
+type ACSInfo struct {
+	Capabilities []string `json:"capabilities"`
+	Enabled      []string `json:"enabled"`
+	P2PRedirect  bool     `json:"p2p_redirect"`
+}
+
+// collectPCIeTopology builds the PCIe tree of every root port a Mellanox
+// NIC is attached to, including all other devices below those root ports
+// (e.g., NVMe drives and GPUs behind the same switch). Root ports are
+// grouped under their host bridge. SR-IOV virtual functions are omitted.
+func collectPCIeTopology(ctx context.Context, h *host.Host, nics []NICInfo) []PCIeHostBridge {
+	nicAddrs := make(map[string]bool, len(nics))
+	// rootPorts maps each host bridge (e.g., /sys/devices/pci0000:3a) to
+	// the root ports below it that lead to a NIC
+	rootPorts := make(map[string]map[string]bool)
+	for _, nic := range nics {
+		nicAddrs[nic.PCIAddress] = true
+		for _, port := range nic.Ports {
+			if port.PCIAddress != "" {
+				nicAddrs[port.PCIAddress] = true
+			}
+		}
+	}
+	for addr := range nicAddrs {
+		devPath, err := h.EvalSymlinks(filepath.Join(sysBusPCIDevices, addr))
+		if err != nil {
+			continue
+		}
+		hostBridge, rootPort, ok := pcieRoot(devPath)
+		if !ok {
+			continue
+		}
+		if rootPorts[hostBridge] == nil {
+			rootPorts[hostBridge] = make(map[string]bool)
+		}
+		rootPorts[hostBridge][rootPort] = true
+	}
+
+	bridges := make([]string, 0, len(rootPorts))
+	for hostBridge := range rootPorts {
+		bridges = append(bridges, hostBridge)
+	}
+	sort.Strings(bridges)
+
+	var tree []PCIeHostBridge
+	for _, hostBridge := range bridges {
+		if ctx.Err() != nil {
+			return nil
+		}
+		bridge := PCIeHostBridge{Name: filepath.Base(hostBridge)}
+		ports := make([]string, 0, len(rootPorts[hostBridge]))
+		for port := range rootPorts[hostBridge] {
+			ports = append(ports, port)
+		}
+		sort.Strings(ports)
+		for _, port := range ports {
+			bridge.RootPorts = append(bridge.RootPorts, readPCIeNode(h, filepath.Join(hostBridge, port), "host_bridge", nicAddrs, 0))
+		}
+		tree = append(tree, bridge)
+	}
+	return tree
+}
+
+// pcieRoot splits the canonical sysfs path of a PCI function into its host
+// bridge directory and the address of its root port.
+func pcieRoot(devPath string) (hostBridge, rootPort string, ok bool) {
+	parts := strings.Split(devPath, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, "pci") && i+1 < len(parts) && pciAddressRegex.MatchString(parts[i+1]) {
+			return strings.Join(parts[:i+1], "/"), parts[i+1], true
+		}
+	}
+	return "", "", false
+}
+
+// readPCIeNode reads a PCI function and the functions below it. parentType
+// is the port type of the function's parent ("host_bridge" for root ports),
+// used to tell the port types apart when the configuration space cannot be
+// read.
+func readPCIeNode(h *host.Host, devPath, parentType string, nicAddrs map[string]bool, depth int) PCIeNode {
+	node := PCIeNode{
+		PCIDevice: readPCIDevice(h, devPath),
+		Link:      readPCIeLink(h, devPath, filepath.Base(devPath)),
+	}
+	node.NIC = nicAddrs[node.Address]
+
+	config, _ := h.ReadFile(filepath.Join(devPath, "config"))
+	portType, ok := pciePortType(config)
+	node.ACS = parseACS(config)
+	if ok {
+		node.Type = portType
+	} else {
+		node.Type = inferPCIePortType(node.Class, parentType)
+	}
+
+	if depth >= maxPCIeDepth {
+		return node
+	}
+	entries, err := h.ReadDir(devPath)
+	if err != nil {
+		return node
+	}
+	for _, entry := range entries {
+		if !pciAddressRegex.MatchString(entry.Name()) {
+			continue
+		}
+		childPath := filepath.Join(devPath, entry.Name())
+		if h.Exists(filepath.Join(childPath, "physfn")) {
+			continue
+		}
+		node.Children = append(node.Children, readPCIeNode(h, childPath, node.Type, nicAddrs, depth+1))
+	}
+	return node
+}
+
+// inferPCIePortType guesses the port type of a function from its class and
+// its parent: bridges below the host bridge are root ports, and switch
+// upstream and downstream ports alternate below them.
+func inferPCIePortType(class, parentType string) string {
+	if !strings.HasPrefix(strings.ToLower(class), pciClassBridge) {
+		return "endpoint"
+	}
+	switch parentType {
+	case "host_bridge":
+		return "root_port"
+	case "root_port", "switch_downstream":
+		return "switch_upstream"
+	default:
+		return "switch_downstream"
+	}
+}
+
+// pciePortType returns the port type from the PCI Express capability in a
+// function's configuration space.
+func pciePortType(config []byte) (string, bool) {
+	if len(config) < 0x40 || config[0x06]&pciStatusCapList == 0 {
+		return "", false
+	}
+
+	ptr := int(config[pciCapabilityList] &^ 3)
+	// The list has at most 48 capabilities in the 192 bytes after the header
+	for i := 0; i < 48 && ptr >= 0x40 && ptr+4 <= len(config); i++ {
+		if config[ptr] == pciCapIDExpress {
+			flags := binary.LittleEndian.Uint16(config[ptr+2:])
+			name, ok := pciePortTypes[(flags>>4)&0xf]
+			return name, ok
+		}
+		ptr = int(config[ptr+1] &^ 3)
+	}
+	return "", false
+}
+
+// parseACS returns the ACS capability from a function's extended
+// configuration space, or nil if it has none or the extended space cannot
+// be read (it is only readable with CAP_SYS_ADMIN).
+func parseACS(config []byte) *ACSInfo {
+	offset := pciExtCapStart
+	for i := 0; offset >= pciExtCapStart && offset+8 <= len(config) && i < (4096-pciExtCapStart)/8; i++ {
+		header := binary.LittleEndian.Uint32(config[offset:])
+		if header == 0 || header == 0xffffffff {
+			return nil
+		}
+		if header&0xffff == pciExtCapIDACS {
+			capability := binary.LittleEndian.Uint16(config[offset+4:])
+			control := binary.LittleEndian.Uint16(config[offset+6:])
+			acs := &ACSInfo{
+				Capabilities: acsBits(capability),
+				Enabled:      acsBits(control),
+			}
+			acs.P2PRedirect = control&(1<<2|1<<3) != 0
+			return acs
+		}
+		offset = int(header>>20) &^ 3
+	}
+	return nil
+}
+
+// acsBits names the set ACS control bits.
+func acsBits(bits uint16) []string {
+	names := []string{}
+	for i, name := range acsControls {
+		if bits&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestCollectPCIeTopology_Switch(t *testing.T) {
+	// A NIC, an NVMe drive and a GPU behind a PCIe switch; the NIC's VF is
+	// omitted and the config space of the switch ports is unreadable.
+	h := loadArchive(t, `-- link sys/bus/pci/devices/0000:1c:00.0 --
+../../../devices/pci0000:17/0000:17:02.0/0000:18:00.0/0000:19:08.0/0000:1c:00.0
+-- sys/devices/pci0000:17/0000:17:02.0/class --
+0x060400
+-- sys/devices/pci0000:17/0000:17:02.0/0000:18:00.0/class --
+0x060400
+-- sys/devices/pci0000:17/0000:17:02.0/0000:18:00.0/0000:19:00.0/class --
+0x060400
+-- sys/devices/pci0000:17/0000:17:02.0/0000:18:00.0/0000:19:00.0/0000:1a:00.0/class --
+0x010802
+-- sys/devices/pci0000:17/0000:17:02.0/0000:18:00.0/0000:19:04.0/class --
+0x060400
+-- sys/devices/pci0000:17/0000:17:02.0/0000:18:00.0/0000:19:04.0/0000:1b:00.0/class --
+0x030200
+-- sys/devices/pci0000:17/0000:17:02.0/0000:18:00.0/0000:19:08.0/class --
+0x060400
+-- sys/devices/pci0000:17/0000:17:02.0/0000:18:00.0/0000:19:08.0/0000:1c:00.0/class --
+0x020000
+-- sys/devices/pci0000:17/0000:17:02.0/0000:18:00.0/0000:19:08.0/0000:1c:00.2/class --
+0x020000
+-- link sys/devices/pci0000:17/0000:17:02.0/0000:18:00.0/0000:19:08.0/0000:1c:00.2/physfn --
+../0000:1c:00.0
+`)
+
+	got := collectPCIeTopology(context.Background(), h, []NICInfo{{PCIAddress: "0000:1c:00.0"}})
+
+	endpoint := func(addr, class string, nic bool) PCIeNode {
+		return PCIeNode{PCIDevice: PCIDevice{Address: addr, Class: class}, Type: "endpoint", NIC: nic}
+	}
+	downstream := func(addr string, child PCIeNode) PCIeNode {
+		return PCIeNode{PCIDevice: PCIDevice{Address: addr, Class: "0x060400"}, Type: "switch_downstream", Children: []PCIeNode{child}}
+	}
+	want := []PCIeHostBridge{{
+		Name: "pci0000:17",
+		RootPorts: []PCIeNode{{
+			PCIDevice: PCIDevice{Address: "0000:17:02.0", Class: "0x060400"},
+			Type:      "root_port",
+			Children: []PCIeNode{{
+				PCIDevice: PCIDevice{Address: "0000:18:00.0", Class: "0x060400"},
+				Type:      "switch_upstream",
+				Children: []PCIeNode{
+					downstream("0000:19:00.0", endpoint("0000:1a:00.0", "0x010802", false)),
+					downstream("0000:19:04.0", endpoint("0000:1b:00.0", "0x030200", false)),
+					downstream("0000:19:08.0", endpoint("0000:1c:00.0", "0x020000", true)),
+				},
+			}},
+		}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collectPCIeTopology() = %+v, want %+v", got, want)
+	}
+}
+
+// pcieConfig builds a bridge's configuration space with a PCI Express
+// capability of the given port type and an ACS capability behind an AER
+// capability.
+func pcieConfig(portType, acsCap, acsCtl uint16) []byte {
+	config := make([]byte, 0x150)
+	binary.LittleEndian.PutUint16(config[0x06:], pciStatusCapList)
+	config[pciCapabilityList] = 0x50
+	// Power management capability, then PCI Express
+	config[0x50], config[0x51] = 0x01, 0x70
+	config[0x70] = pciCapIDExpress
+	binary.LittleEndian.PutUint16(config[0x72:], 0x0002|portType<<4)
+	binary.LittleEndian.PutUint32(config[0x100:], 0x0001|1<<16|0x148<<20)
+	binary.LittleEndian.PutUint32(config[0x148:], pciExtCapIDACS|1<<16)
+	binary.LittleEndian.PutUint16(config[0x14c:], acsCap)
+	binary.LittleEndian.PutUint16(config[0x14e:], acsCtl)
+	return config
+}
+
+func TestPCIePortType(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   []byte
+		wantType string
+		wantOK   bool
+	}{
+		{name: "downstream port", config: pcieConfig(0x6, 0, 0), wantType: "switch_downstream", wantOK: true},
+		{name: "upstream port", config: pcieConfig(0x5, 0, 0), wantType: "switch_upstream", wantOK: true},
+		{name: "header only", config: pcieConfig(0x4, 0, 0)[:0x40]},
+		{name: "unreadable", config: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pciePortType(tt.config)
+			if got != tt.wantType || ok != tt.wantOK {
+				t.Errorf("pciePortType() = %q, %v, want %q, %v", got, ok, tt.wantType, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseACS(t *testing.T) {
+	tests := []struct {
+		name   string
+		config []byte
+		want   *ACSInfo
+	}{
+		{
+			name:   "completion redirect",
+			config: pcieConfig(0x6, 0x001f, 0x0009),
+			want: &ACSInfo{
+				Capabilities: []string{"source_validation", "translation_blocking", "p2p_request_redirect", "p2p_completion_redirect", "upstream_forwarding"},
+				Enabled:      []string{"source_validation", "p2p_completion_redirect"},
+				P2PRedirect:  true,
+			},
+		},
+		{
+			name:   "egress control only",
+			config: pcieConfig(0x6, 0x0020, 0x0020),
+			want: &ACSInfo{
+				Capabilities: []string{"p2p_egress_control"},
+				Enabled:      []string{"p2p_egress_control"},
+			},
+		},
+		{
+			// Without CAP_SYS_ADMIN sysfs returns only the first 64 bytes
+			name:   "unprivileged",
+			config: pcieConfig(0x6, 0x001f, 0x001d)[:0x40],
+		},
+		{
+			name:   "no extended capabilities",
+			config: make([]byte, 0x1000),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseACS(tt.config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseACS() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -27,12 +27,16 @@ type PollIntervalCallback func(interval time.Duration)
 // PollIntervalHandler handles POLL_INTERVAL instructions.
 type PollIntervalHandler struct {
 	callback PollIntervalCallback
+	current  func() time.Duration
 }
 
-// NewPollIntervalHandler creates a new poll interval handler with the given callback.
-func NewPollIntervalHandler(callback PollIntervalCallback) *PollIntervalHandler {
+// NewPollIntervalHandler creates a new poll interval handler with the given
+// callback. current returns the interval in effect; it is recorded in the
+// result so that Undo can restore it. A nil current disables undo.
+func NewPollIntervalHandler(callback PollIntervalCallback, current func() time.Duration) *PollIntervalHandler {
 	return &PollIntervalHandler{
 		callback: callback,
+		current:  current,
 	}
 }
 
@@ -54,6 +58,11 @@ func (h *PollIntervalHandler) Execute(ctx context.Context, instruction *v1.Instr
 			payload.IntervalSeconds, MinPollInterval, MaxPollInterval)
 	}
 
+	var previous time.Duration
+	if h.current != nil {
+		previous = h.current()
+	}
+
 	// Call the callback to update the agent's poll interval
 	if h.callback != nil {
 		h.callback(time.Duration(payload.IntervalSeconds) * time.Second)
@@ -64,6 +73,9 @@ func (h *PollIntervalHandler) Execute(ctx context.Context, instruction *v1.Instr
 		"status":           "ok",
 		"interval_seconds": payload.IntervalSeconds,
 	}
+	if previous > 0 {
+		result["previous_interval_seconds"] = int(previous / time.Second)
+	}
 	resultJSON, err := json.Marshal(result)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal result: %w", err)
@@ -71,3 +83,22 @@ func (h *PollIntervalHandler) Execute(ctx context.Context, instruction *v1.Instr
 
 	return string(resultJSON), nil
 }
+
+// Undo restores the poll interval that was in effect before Execute, as
+// recorded in its result.
+func (h *PollIntervalHandler) Undo(ctx context.Context, instruction *v1.Instruction, result string) error {
+	var executed struct {
+		PreviousIntervalSeconds int `json:"previous_interval_seconds"`
+	}
+	if err := json.Unmarshal([]byte(result), &executed); err != nil {
+		return fmt.Errorf("failed to parse poll interval result: %w", err)
+	}
+	if executed.PreviousIntervalSeconds <= 0 {
+		return fmt.Errorf("previous poll interval is unknown")
+	}
+
+	if h.callback != nil {
+		h.callback(time.Duration(executed.PreviousIntervalSeconds) * time.Second)
+	}
+	return nil
+}
@@ -11,7 +11,7 @@ import (
 
 func TestNewPollIntervalHandler(t *testing.T) {
 	callback := func(interval time.Duration) {}
-	handler := NewPollIntervalHandler(callback)
+	handler := NewPollIntervalHandler(callback, nil)
 
 	if handler == nil {
 		t.Fatal("NewPollIntervalHandler returned nil")
@@ -120,7 +120,7 @@ func TestPollIntervalHandler_Execute(t *testing.T) {
 				receivedInterval = interval
 			}
 
-			handler := NewPollIntervalHandler(callback)
+			handler := NewPollIntervalHandler(callback, nil)
 			ctx := context.Background()
 
 			result, err := handler.Execute(ctx, tt.instruction)
@@ -159,7 +159,7 @@ func TestPollIntervalHandler_Execute(t *testing.T) {
 }
 
 func TestPollIntervalHandler_Execute_NilCallback(t *testing.T) {
-	handler := NewPollIntervalHandler(nil)
+	handler := NewPollIntervalHandler(nil, nil)
 	ctx := context.Background()
 
 	instruction := &v1.Instruction{
@@ -185,7 +185,7 @@ func TestPollIntervalHandler_Execute_ContextCancellation(t *testing.T) {
 		called = true
 	}
 
-	handler := NewPollIntervalHandler(callback)
+	handler := NewPollIntervalHandler(callback, nil)
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // Cancel context immediately
 
@@ -205,3 +205,36 @@ func TestPollIntervalHandler_Execute_ContextCancellation(t *testing.T) {
 		t.Error("callback was not called with cancelled context")
 	}
 }
+
+func TestPollIntervalHandler_Undo(t *testing.T) {
+	interval := 60 * time.Second
+	handler := NewPollIntervalHandler(
+		func(d time.Duration) { interval = d },
+		func() time.Duration { return interval },
+	)
+
+	instruction := &v1.Instruction{
+		Id:      "test-undo",
+		Type:    v1.InstructionType_INSTRUCTION_TYPE_POLL_INTERVAL,
+		Payload: `{"interval_seconds": 30}`,
+	}
+	result, err := handler.Execute(context.Background(), instruction)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if interval != 30*time.Second {
+		t.Fatalf("interval after Execute = %v, want 30s", interval)
+	}
+
+	if err := handler.Undo(context.Background(), instruction, result); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if interval != 60*time.Second {
+		t.Errorf("interval after Undo = %v, want 60s", interval)
+	}
+
+	// Without the previous interval there is nothing to restore
+	if err := handler.Undo(context.Background(), instruction, `{"status":"ok","interval_seconds":30}`); err == nil {
+		t.Error("Undo() without previous interval succeeded")
+	}
+}
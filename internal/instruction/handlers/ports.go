@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+)
+
+// Representor is a switchdev representor netdev of a PCI function: the
+// e-switch side of a PF, VF or SF. Representors are not physical ports.
+type Representor struct {
+	InterfaceName string `json:"interface_name"`
+	PhysPortName  string `json:"phys_port_name"`
+	// Flavour is "pcipf", "pcivf" or "pcisf", as in devlink.
+	Flavour      string `json:"flavour"`
+	Controller   *int   `json:"controller,omitempty"`
+	PFNumber     int    `json:"pf_number"`
+	VFNumber     *int   `json:"vf_number,omitempty"`
+	SFNumber     *int   `json:"sf_number,omitempty"`
+	PhysSwitchID string `json:"phys_switch_id,omitempty"`
+	State        string `json:"state,omitempty"`
+	MACAddress   string `json:"mac_address,omitempty"`
+}
+
+// representorNameRegex matches the phys_port_name of representors:
+// "pf0", "pf0vf3", "pf0sf88", or with an external controller "c1pf0vf3".
+var representorNameRegex = regexp.MustCompile(`^(?:c(\d+))?pf(\d+)(?:(vf|sf)(\d+))?$`)
+
+// uplinkNameRegex matches the phys_port_name of physical uplinks: "p0",
+// "p1", ...
+var uplinkNameRegex = regexp.MustCompile(`^p(\d+)$`)
+
+// listNetdevs reads the port identity (dev_port, phys_port_name,
+// phys_port_id, phys_switch_id) of the netdevs of a PCI function and
+// separates physical ports from representors and child interfaces.
+// Physical ports are numbered by phys_port_name (port 1 is "p0") or, without
+// one, by the PCI function number plus dev_port (port 1 is dev_port 0 of
+// function 0), so renaming interfaces does not change port numbers. mlx5
+// has one PCI function per port and reports dev_port 0 on all of them, so
+// its ports are told apart by phys_port_name or, in legacy mode on kernels
+// without one, by the function. Netdevs with neither take the next free
+// numbers in name order. Ports are returned sorted by number.
+func listNetdevs(h *host.Host, pciAddr string) ([]PortInfo, []Representor, error) {
+	netPath := filepath.Join(sysBusPCIDevices, pciAddr, "net")
+	entries, err := h.ReadDir(netPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ports []PortInfo
+	var representors []Representor
+	for _, entry := range entries {
+		ifName := entry.Name()
+		ifPath := filepath.Join(netPath, ifName)
+		// IPoIB children are reported in the uppers of their parent port
+		if isChildNetdev(h, ifPath) {
+			continue
+		}
+		physPortName := h.ReadString(filepath.Join(ifPath, "phys_port_name"))
+
+		if rep, ok := parseRepresentor(physPortName); ok {
+			rep.InterfaceName = ifName
+			rep.PhysSwitchID = h.ReadString(filepath.Join(ifPath, "phys_switch_id"))
+			rep.State = h.ReadString(filepath.Join(ifPath, "operstate"))
+			rep.MACAddress = h.ReadString(filepath.Join(ifPath, "address"))
+			representors = append(representors, rep)
+			continue
+		}
+
+		port := PortInfo{
+			InterfaceName: ifName,
+			PCIAddress:    pciAddr,
+			PhysPortName:  physPortName,
+			PhysPortID:    h.ReadString(filepath.Join(ifPath, "phys_port_id")),
+			PhysSwitchID:  h.ReadString(filepath.Join(ifPath, "phys_switch_id")),
+		}
+		if devPort, err := strconv.Atoi(h.ReadString(filepath.Join(ifPath, "dev_port"))); err == nil {
+			port.DevPort = &devPort
+			port.Number = devPort + 1
+			if fn, ok := pciFunction(pciAddr); ok && physPortName == "" {
+				port.Number += fn
+			}
+		}
+		if m := uplinkNameRegex.FindStringSubmatch(physPortName); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				port.Number = n + 1
+			}
+		}
+		ports = append(ports, port)
+	}
+
+	numberPorts(ports)
+	return ports, representors, nil
+}
+
+// pciFunction returns the function number of a PCI address, e.g., 1 for
+// "0000:3b:00.1".
+func pciFunction(pciAddr string) (int, bool) {
+	i := strings.LastIndexByte(pciAddr, '.')
+	if i < 0 {
+		return 0, false
+	}
+	fn, err := strconv.Atoi(pciAddr[i+1:])
+	if err != nil {
+		return 0, false
+	}
+	return fn, true
+}
+
+// PortInterfaces returns the netdevs of the physical ports of every
+// Mellanox physical function, such as the interfaces to listen for LLDP on.
+func PortInterfaces(ctx context.Context, h *host.Host) ([]string, error) {
+	pciDevices, err := findMellanoxPCIDevices(ctx, h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Mellanox devices: %w", err)
+	}
+
+	var interfaces []string
+	for _, pciDev := range pciDevices {
+		ports, _, err := listNetdevs(h, pciDev.Address)
+		if err != nil {
+			continue
+		}
+		for _, port := range ports {
+			interfaces = append(interfaces, port.InterfaceName)
+		}
+	}
+	return interfaces, nil
+}
+
+// numberPorts gives ports without a number (no dev_port, or one already
+// taken) the lowest free numbers, then sorts ports by number.
+func numberPorts(ports []PortInfo) {
+	used := make(map[int]bool)
+	for i := range ports {
+		if ports[i].Number > 0 && used[ports[i].Number] {
+			ports[i].Number = 0
+		}
+		used[ports[i].Number] = true
+	}
+
+	next := 1
+	for i := range ports {
+		if ports[i].Number > 0 {
+			continue
+		}
+		for used[next] {
+			next++
+		}
+		ports[i].Number = next
+		used[next] = true
+	}
+
+	sort.SliceStable(ports, func(i, j int) bool {
+		return ports[i].Number < ports[j].Number
+	})
+}
+
+// parseRepresentor parses a representor's phys_port_name.
+func parseRepresentor(physPortName string) (Representor, bool) {
+	m := representorNameRegex.FindStringSubmatch(physPortName)
+	if m == nil {
+		return Representor{}, false
+	}
+
+	rep := Representor{PhysPortName: physPortName, Flavour: "pcipf"}
+	if m[1] != "" {
+		controller, _ := strconv.Atoi(m[1])
+		rep.Controller = &controller
+	}
+	rep.PFNumber, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		num, _ := strconv.Atoi(m[4])
+		switch m[3] {
+		case "vf":
+			rep.Flavour = "pcivf"
+			rep.VFNumber = &num
+		case "sf":
+			rep.Flavour = "pcisf"
+			rep.SFNumber = &num
+		}
+	}
+	return rep, true
+}
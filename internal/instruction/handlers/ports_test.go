@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/host/hosttest"
+)
+
+func TestListNetdevs(t *testing.T) {
+	root := t.TempDir()
+	netPath := filepath.Join(root, sysBusPCIDevices, "0000:03:00.0", "net")
+
+	// Renamed interfaces sort differently from their dev_port order
+	for path, value := range map[string]string{
+		"wan/dev_port":             "1",
+		"wan/phys_port_name":       "p1",
+		"lan/dev_port":             "0",
+		"lan/phys_port_name":       "p0",
+		"lan/phys_switch_id":       "ecd9a40003f6ceb8",
+		"eth9/operstate":           "up",
+		"rep0/phys_port_name":      "pf0vf3",
+		"rep0/phys_switch_id":      "ecd9a40003f6ceb8",
+		"rep0/operstate":           "up",
+		"rep1/phys_port_name":      "c1pf0sf88",
+		"rep1/address":             "02:00:00:00:00:58",
+		"uplinkrep/phys_port_name": "pf0",
+	} {
+		if err := os.MkdirAll(filepath.Join(netPath, filepath.Dir(path)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(netPath, path), []byte(value+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ports, representors, err := listNetdevs(host.New(root, nil), "0000:03:00.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantPorts := []struct {
+		number int
+		ifName string
+	}{
+		{1, "lan"},
+		{2, "wan"},
+		{3, "eth9"}, // no dev_port
+	}
+	if len(ports) != len(wantPorts) {
+		t.Fatalf("got %d ports, want %d: %+v", len(ports), len(wantPorts), ports)
+	}
+	for i, want := range wantPorts {
+		if ports[i].Number != want.number || ports[i].InterfaceName != want.ifName {
+			t.Errorf("port %d = %d %s, want %d %s", i, ports[i].Number, ports[i].InterfaceName, want.number, want.ifName)
+		}
+	}
+	if ports[0].PhysPortName != "p0" || ports[0].PhysSwitchID != "ecd9a40003f6ceb8" || ports[0].DevPort == nil || *ports[0].DevPort != 0 {
+		t.Errorf("port 1 identity = %+v", ports[0])
+	}
+
+	if len(representors) != 3 {
+		t.Fatalf("got %d representors, want 3: %+v", len(representors), representors)
+	}
+	vf := representors[0]
+	if vf.InterfaceName != "rep0" || vf.Flavour != "pcivf" || vf.VFNumber == nil || *vf.VFNumber != 3 || vf.State != "up" || vf.PhysSwitchID != "ecd9a40003f6ceb8" {
+		t.Errorf("VF representor = %+v", vf)
+	}
+	sf := representors[1]
+	if sf.Flavour != "pcisf" || sf.SFNumber == nil || *sf.SFNumber != 88 || sf.Controller == nil || *sf.Controller != 1 || sf.MACAddress != "02:00:00:00:00:58" {
+		t.Errorf("SF representor = %+v", sf)
+	}
+	if pf := representors[2]; pf.Flavour != "pcipf" || pf.PFNumber != 0 || pf.VFNumber != nil {
+		t.Errorf("PF representor = %+v", pf)
+	}
+}
+
+func TestListNetdevs_PortPerPF(t *testing.T) {
+	// mlx5 reports dev_port 0 on both PFs of a dual-port NIC; the legacy
+	// mode capture has no phys_port_name either
+	for _, tt := range []struct {
+		capture string
+		pciAddr string
+		ifName  string
+		number  int
+	}{
+		{"cx5.txt", "0000:3b:00.0", "ens1f0np0", 1},
+		{"cx5.txt", "0000:3b:00.1", "ens1f1np1", 2},
+		{"cx4lx_legacy.txt", "0000:5e:00.0", "ens3f0", 1},
+		{"cx4lx_legacy.txt", "0000:5e:00.1", "ens3f1", 2},
+	} {
+		h := hosttest.Load(t, filepath.Join("testdata", "hosts", tt.capture))
+		ports, _, err := listNetdevs(h, tt.pciAddr)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.pciAddr, err)
+		}
+		if len(ports) != 1 || ports[0].InterfaceName != tt.ifName || ports[0].Number != tt.number {
+			t.Errorf("%s: ports = %+v, want %s as port %d", tt.pciAddr, ports, tt.ifName, tt.number)
+		}
+		if ports[0].DevPort == nil || *ports[0].DevPort != 0 {
+			t.Errorf("%s: dev_port = %v, want 0", tt.pciAddr, ports[0].DevPort)
+		}
+	}
+}
+
+func TestNumberPorts(t *testing.T) {
+	ports := []PortInfo{
+		{InterfaceName: "a", Number: 2},
+		{InterfaceName: "b", Number: 2}, // duplicate dev_port
+		{InterfaceName: "c"},
+		{InterfaceName: "d", Number: 4},
+	}
+	numberPorts(ports)
+
+	want := []string{"b", "a", "c", "d"}
+	for i, port := range ports {
+		if port.Number != i+1 || port.InterfaceName != want[i] {
+			t.Errorf("port %d = %d %s, want %d %s", i, port.Number, port.InterfaceName, i+1, want[i])
+		}
+	}
+}
+
+func TestPortInterfaces(t *testing.T) {
+	h := hosttest.Load(t, filepath.Join("testdata", "hosts", "cx5.txt"))
+
+	interfaces, err := PortInterfaces(context.Background(), h)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Representors are not ports
+	if want := []string{"ens1f0np0", "ens1f1np1"}; !reflect.DeepEqual(interfaces, want) {
+		t.Errorf("PortInterfaces() = %v, want %v", interfaces, want)
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/filanov/netctrl-agent/internal/instruction"
+	"github.com/filanov/netctrl-agent/internal/scheduler"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+// Schedule actions.
+const (
+	ScheduleActionAdd    = "add"
+	ScheduleActionRemove = "remove"
+	ScheduleActionList   = "list"
+)
+
+// SchedulePayload represents the JSON payload for SCHEDULE instructions.
+type SchedulePayload struct {
+	// Action is "add" (default), "remove" or "list".
+	Action string `json:"action,omitempty"`
+	// ID identifies the schedule; adding an existing ID replaces it.
+	ID          string                `json:"id"`
+	Instruction *ScheduledInstruction `json:"instruction,omitempty"`
+	NotBefore   time.Time             `json:"not_before,omitempty"`
+	ExpiresAt   time.Time             `json:"expires_at,omitempty"`
+	Recurrence  *scheduler.Recurrence `json:"recurrence,omitempty"`
+}
+
+// ScheduledInstruction is the instruction executed by a schedule.
+type ScheduledInstruction struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// ScheduleHandler handles SCHEDULE instructions by managing entries of the
+// agent's local scheduler.
+type ScheduleHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+// NewScheduleHandler creates a new schedule handler for the given scheduler.
+func NewScheduleHandler(s *scheduler.Scheduler) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduler: s,
+	}
+}
+
+// Execute processes a SCHEDULE instruction.
+func (h *ScheduleHandler) Execute(ctx context.Context, inst *v1.Instruction) (string, error) {
+	if inst == nil {
+		return "", fmt.Errorf("instruction is nil")
+	}
+
+	// Parse payload
+	var payload SchedulePayload
+	if err := json.Unmarshal([]byte(inst.Payload), &payload); err != nil {
+		return "", fmt.Errorf("failed to parse schedule payload: %w", err)
+	}
+
+	var result interface{}
+	switch payload.Action {
+	case "", ScheduleActionAdd:
+		entry, err := h.add(payload)
+		if err != nil {
+			return "", err
+		}
+		result = map[string]interface{}{
+			"status":   "scheduled",
+			"id":       entry.ID,
+			"next_run": entry.NextRun.UTC().Format(time.RFC3339),
+		}
+
+	case ScheduleActionRemove:
+		if payload.ID == "" {
+			return "", fmt.Errorf("schedule id is required")
+		}
+		removed, err := h.scheduler.Remove(payload.ID)
+		if err != nil {
+			return "", err
+		}
+		result = map[string]interface{}{
+			"status":  "ok",
+			"id":      payload.ID,
+			"removed": removed,
+		}
+
+	case ScheduleActionList:
+		result = map[string]interface{}{
+			"status":  "ok",
+			"entries": h.scheduler.Entries(),
+		}
+
+	default:
+		return "", fmt.Errorf("invalid schedule action %q", payload.Action)
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return string(resultJSON), nil
+}
+
+// add validates the payload and adds it to the scheduler.
+func (h *ScheduleHandler) add(payload SchedulePayload) (*scheduler.Entry, error) {
+	if payload.Instruction == nil {
+		return nil, fmt.Errorf("instruction is required")
+	}
+
+	instructionType, err := instruction.ParseType(payload.Instruction.Type)
+	if err != nil {
+		return nil, err
+	}
+	if instructionType == instruction.TypeSchedule || instructionType == instruction.TypeCancel {
+		return nil, fmt.Errorf("instruction type %s cannot be scheduled", payload.Instruction.Type)
+	}
+
+	instructionPayload, err := stepPayloadString(payload.Instruction.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.scheduler.Add(scheduler.Entry{
+		ID:         payload.ID,
+		Type:       instructionType,
+		Payload:    instructionPayload,
+		NotBefore:  payload.NotBefore,
+		ExpiresAt:  payload.ExpiresAt,
+		Recurrence: payload.Recurrence,
+	})
+}
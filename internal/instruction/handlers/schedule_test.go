@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/instruction"
+	"github.com/filanov/netctrl-agent/internal/scheduler"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+func TestScheduleHandler_Execute(t *testing.T) {
+	s, err := scheduler.New(t.TempDir(), instruction.NewRegistry())
+	if err != nil {
+		t.Fatalf("scheduler.New() failed: %v", err)
+	}
+	handler := NewScheduleHandler(s)
+
+	tests := []struct {
+		name          string
+		payload       string
+		expectedError bool
+		expectedCount int
+	}{
+		{
+			name:          "add recurring",
+			payload:       `{"id": "hourly", "instruction": {"type": "COLLECT_HARDWARE", "payload": {}}, "recurrence": {"interval_seconds": 3600}}`,
+			expectedCount: 1,
+		},
+		{
+			name:          "add cron with expiry",
+			payload:       `{"id": "daily", "instruction": {"type": "HEALTH_CHECK"}, "expires_at": "2099-01-01T00:00:00Z", "recurrence": {"cron": "0 2 * * *"}}`,
+			expectedCount: 2,
+		},
+		{
+			name:          "replace existing",
+			payload:       `{"action": "add", "id": "daily", "instruction": {"type": "HEALTH_CHECK"}, "recurrence": {"cron": "0 3 * * *"}}`,
+			expectedCount: 2,
+		},
+		{
+			name:          "remove",
+			payload:       `{"action": "remove", "id": "hourly"}`,
+			expectedCount: 1,
+		},
+		{
+			name:          "list",
+			payload:       `{"action": "list"}`,
+			expectedCount: 1,
+		},
+		{
+			name:          "missing instruction",
+			payload:       `{"id": "x"}`,
+			expectedError: true,
+			expectedCount: 1,
+		},
+		{
+			name:          "unknown type",
+			payload:       `{"id": "x", "instruction": {"type": "REBOOT"}}`,
+			expectedError: true,
+			expectedCount: 1,
+		},
+		{
+			name:          "schedule cannot be scheduled",
+			payload:       `{"id": "x", "instruction": {"type": "SCHEDULE"}}`,
+			expectedError: true,
+			expectedCount: 1,
+		},
+		{
+			name:          "invalid action",
+			payload:       `{"action": "pause", "id": "daily"}`,
+			expectedError: true,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := handler.Execute(context.Background(), &v1.Instruction{
+				Id:      "schedule-1",
+				Type:    instruction.TypeSchedule,
+				Payload: tt.payload,
+			})
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Execute() error = %v, expectedError %v", err, tt.expectedError)
+			}
+			if !tt.expectedError && !json.Valid([]byte(result)) {
+				t.Errorf("result is not valid JSON: %s", result)
+			}
+			if got := len(s.Entries()); got != tt.expectedCount {
+				t.Errorf("entries = %d, want %d", got, tt.expectedCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/netlink"
+)
+
+// SRIOVInfo describes the SR-IOV configuration of a physical function and
+// the virtual functions it has created.
+type SRIOVInfo struct {
+	TotalVFs         int        `json:"total_vfs"`
+	NumVFs           int        `json:"num_vfs"`
+	DriversAutoprobe bool       `json:"drivers_autoprobe"`
+	VFs              []VFDevice `json:"vfs,omitempty"`
+	// VFInfoError is set when the VF configuration could not be read from
+	// the PF netdev over netlink.
+	VFInfoError string `json:"vf_info_error,omitempty"`
+}
+
+// VFDevice is a virtual function of a PF: its PCI function from sysfs and
+// its configuration from the PF's IFLA_VFINFO_LIST.
+type VFDevice struct {
+	Index      int    `json:"index"`
+	PCIAddress string `json:"pci_address"`
+	Driver     string `json:"driver,omitempty"`
+	Netdev     string `json:"netdev,omitempty"`
+	MAC        string `json:"mac,omitempty"`
+	VLAN       int    `json:"vlan,omitempty"`
+	QoS        int    `json:"qos,omitempty"`
+	SpoofCheck *bool  `json:"spoof_check,omitempty"`
+	Trust      *bool  `json:"trust,omitempty"`
+	LinkState  string `json:"link_state,omitempty"`
+	MinTxRate  int    `json:"min_tx_rate,omitempty"`
+	MaxTxRate  int    `json:"max_tx_rate,omitempty"`
+}
+
+// collectSRIOV reads the SR-IOV attributes and VFs of a physical function.
+// pfNetdev is the PF's network interface, used to query VF settings; it may
+// be empty. It returns nil if the function does not support SR-IOV.
+func collectSRIOV(h *host.Host, pciAddr, pfNetdev string) *SRIOVInfo {
+	devPath := filepath.Join(sysBusPCIDevices, pciAddr)
+
+	totalVFs, err := strconv.Atoi(h.ReadString(filepath.Join(devPath, "sriov_totalvfs")))
+	if err != nil || totalVFs == 0 {
+		return nil
+	}
+
+	info := &SRIOVInfo{
+		TotalVFs:         totalVFs,
+		DriversAutoprobe: h.ReadString(filepath.Join(devPath, "sriov_drivers_autoprobe")) == "1",
+	}
+	info.NumVFs, _ = strconv.Atoi(h.ReadString(filepath.Join(devPath, "sriov_numvfs")))
+
+	entries, err := h.ReadDir(devPath)
+	if err != nil {
+		return info
+	}
+	for _, entry := range entries {
+		// virtfn<N> links point to the VF's PCI function
+		index, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "virtfn"))
+		if err != nil || !strings.HasPrefix(entry.Name(), "virtfn") {
+			continue
+		}
+		vfAddr := h.LinkName(filepath.Join(devPath, entry.Name()))
+		if vfAddr == "" {
+			continue
+		}
+		vfPath := filepath.Join(sysBusPCIDevices, vfAddr)
+
+		vf := VFDevice{
+			Index:      index,
+			PCIAddress: vfAddr,
+			Driver:     h.LinkName(filepath.Join(vfPath, "driver")),
+		}
+		if netdevs, err := h.ReadDir(filepath.Join(vfPath, "net")); err == nil && len(netdevs) > 0 {
+			vf.Netdev = netdevs[0].Name()
+		}
+		info.VFs = append(info.VFs, vf)
+	}
+
+	sort.Slice(info.VFs, func(i, j int) bool {
+		return info.VFs[i].Index < info.VFs[j].Index
+	})
+
+	if len(info.VFs) == 0 || pfNetdev == "" {
+		return info
+	}
+
+	vfInfos, err := h.Netlink.LinkVFs(pfNetdev)
+	if err != nil {
+		info.VFInfoError = err.Error()
+		return info
+	}
+	applyVFInfo(info.VFs, vfInfos)
+
+	return info
+}
+
+// applyVFInfo copies netlink VF settings onto the VFs with the same index.
+func applyVFInfo(vfs []VFDevice, infos []netlink.VFInfo) {
+	for _, vfInfo := range infos {
+		for i := range vfs {
+			if vfs[i].Index != vfInfo.Index {
+				continue
+			}
+			vfs[i].MAC = vfInfo.MAC
+			vfs[i].VLAN = vfInfo.VLAN
+			vfs[i].QoS = vfInfo.QoS
+			vfs[i].SpoofCheck = vfInfo.SpoofCheck
+			vfs[i].Trust = vfInfo.Trust
+			vfs[i].LinkState = vfInfo.LinkState
+			vfs[i].MinTxRate = vfInfo.MinTxRate
+			vfs[i].MaxTxRate = vfInfo.MaxTxRate
+		}
+	}
+}
+
+// primaryNetdev returns the first port with a network interface, used to
+// query PF-level netlink attributes.
+func primaryNetdev(ports []PortInfo) string {
+	for _, port := range ports {
+		if port.InterfaceName != "" {
+			return port.InterfaceName
+		}
+	}
+	return ""
+}
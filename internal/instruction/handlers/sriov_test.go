@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/host/hosttest"
+)
+
+// writeSRIOVDevices creates a PF at 0000:03:00.0 with one VF at 0000:03:00.2.
+func writeSRIOVDevices(t *testing.T, root string) {
+	t.Helper()
+	dir := filepath.Join(root, sysBusPCIDevices)
+
+	writeSysfsPCIDevice(t, dir, "0000:03:00.0", map[string]string{
+		"vendor": "0x15b3", "device": "0x101d", "class": "0x020000",
+		"sriov_totalvfs": "16", "sriov_numvfs": "1", "sriov_drivers_autoprobe": "0",
+	}, map[string]string{
+		"virtfn0": "../0000:03:00.2",
+	})
+	writeSysfsPCIDevice(t, dir, "0000:03:00.2", map[string]string{
+		"vendor": "0x15b3", "device": "0x101e", "class": "0x020000",
+	}, map[string]string{
+		"physfn": "../0000:03:00.0",
+	})
+}
+
+func TestFindMellanoxPCIDevices_SkipsVFs(t *testing.T) {
+	root := t.TempDir()
+	writeSRIOVDevices(t, root)
+
+	devices, err := findMellanoxPCIDevices(context.Background(), host.New(root, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Address != "0000:03:00.0" {
+		t.Errorf("findMellanoxPCIDevices() = %+v, want only the PF", devices)
+	}
+}
+
+func TestCollectSRIOV(t *testing.T) {
+	root := t.TempDir()
+	writeSRIOVDevices(t, root)
+
+	h := host.New(root, nil)
+	h.Netlink = &hosttest.FakeNetlink{Replies: map[string]string{
+		"vfs ens3f0np0": `[{"index": 0, "mac": "02:00:00:00:00:01", "vlan": 5, "link_state": "disable"}]`,
+	}}
+
+	info := collectSRIOV(h, "0000:03:00.0", "ens3f0np0")
+	if info == nil {
+		t.Fatal("collectSRIOV() = nil")
+	}
+	if info.TotalVFs != 16 || info.NumVFs != 1 || info.DriversAutoprobe {
+		t.Errorf("collectSRIOV() = %+v", info)
+	}
+	if len(info.VFs) != 1 {
+		t.Fatalf("got %d VFs, want 1", len(info.VFs))
+	}
+	vf := info.VFs[0]
+	if vf.PCIAddress != "0000:03:00.2" || vf.MAC != "02:00:00:00:00:01" || vf.VLAN != 5 || vf.LinkState != "disable" {
+		t.Errorf("VF = %+v", vf)
+	}
+
+	// The VF configuration is optional; a netlink failure is reported
+	info = collectSRIOV(h, "0000:03:00.0", "ens3f1np1")
+	if info == nil || len(info.VFs) != 1 || !strings.Contains(info.VFInfoError, "no such device") {
+		t.Errorf("collectSRIOV() with netlink error = %+v", info)
+	}
+
+	// VFs do not support SR-IOV themselves
+	if info := collectSRIOV(h, "0000:03:00.2", ""); info != nil {
+		t.Errorf("collectSRIOV() on VF = %+v, want nil", info)
+	}
+}
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+)
+
+// sysClassNet lists every netdev, including virtual ones (bonds, bridges,
+// VLANs) that have no PCI device.
+const sysClassNet = "/sys/class/net"
+
+// maxUpperDepth bounds the walk up the netdev stack.
+const maxUpperDepth = 8
+
+// arphrdInfiniBand is the netdev type of IPoIB interfaces.
+const arphrdInfiniBand = "32"
+
+// UpperDevice is a netdev stacked on a port or on another upper device: a
+// bond, bridge, OVS datapath, VLAN, macvlan or IPoIB child interface.
+type UpperDevice struct {
+	InterfaceName string `json:"interface_name"`
+	// Kind is the link type (e.g., "bond", "bridge", "openvswitch",
+	// "vlan", "macvlan", "ipoib").
+	Kind  string `json:"kind,omitempty"`
+	State string `json:"state,omitempty"`
+	// Lowers are all netdevs the device is stacked on (e.g., every slave
+	// of a bond), not only the one it was reached from.
+	Lowers []string `json:"lowers,omitempty"`
+	// Master is true for bonds, bridges and OVS, which enslave their lowers.
+	Master bool        `json:"master,omitempty"`
+	Bond   *BondInfo   `json:"bond,omitempty"`
+	Bridge *BridgeInfo `json:"bridge,omitempty"`
+	// BondSlave and BridgePort are the state of the lower device this
+	// entry was reached from as a member of the bond or bridge.
+	BondSlave   *BondSlave  `json:"bond_slave,omitempty"`
+	BridgePort  *BridgePort `json:"bridge_port,omitempty"`
+	VLAN        *VLANInfo   `json:"vlan,omitempty"`
+	MacvlanMode string      `json:"macvlan_mode,omitempty"`
+	IPoIB       *IPoIBInfo  `json:"ipoib,omitempty"`
+	// Uppers are the devices stacked on this one.
+	Uppers []UpperDevice `json:"uppers,omitempty"`
+}
+
+// BondInfo holds the configuration and state of a bond
+// (/sys/class/net/<bond>/bonding).
+type BondInfo struct {
+	// Mode is the bonding mode (e.g., "802.3ad", "active-backup").
+	Mode           string   `json:"mode"`
+	Slaves         []string `json:"slaves,omitempty"`
+	ActiveSlave    string   `json:"active_slave,omitempty"`
+	MIIMonMs       int      `json:"miimon_ms,omitempty"`
+	XmitHashPolicy string   `json:"xmit_hash_policy,omitempty"`
+	// LACPRate, ActiveAggregator and PartnerMAC are only reported in
+	// 802.3ad mode. PartnerMAC is the system ID of the LACP partner
+	// (all zeroes without a partner).
+	LACPRate         string `json:"lacp_rate,omitempty"`
+	ActiveAggregator int    `json:"active_aggregator,omitempty"`
+	PartnerMAC       string `json:"partner_mac,omitempty"`
+}
+
+// BondSlave is the state of a netdev in its bond
+// (/sys/class/net/<slave>/bonding_slave).
+type BondSlave struct {
+	// State is "active" or "backup".
+	State            string `json:"state,omitempty"`
+	MIIStatus        string `json:"mii_status,omitempty"`
+	LinkFailureCount int    `json:"link_failure_count"`
+	// AggregatorID, ActorState and PartnerState are only reported in
+	// 802.3ad mode. The LACP port states are decoded into flags (e.g.,
+	// "synchronization", "collecting", "distributing").
+	AggregatorID int      `json:"aggregator_id,omitempty"`
+	ActorState   []string `json:"actor_state,omitempty"`
+	PartnerState []string `json:"partner_state,omitempty"`
+}
+
+// BridgeInfo holds the configuration of a Linux bridge.
+type BridgeInfo struct {
+	STP           bool `json:"stp"`
+	VLANFiltering bool `json:"vlan_filtering"`
+}
+
+// BridgePort is the state of a netdev in its bridge
+// (/sys/class/net/<port>/brport).
+type BridgePort struct {
+	// State is the STP port state (e.g., "forwarding", "blocking").
+	State string `json:"state,omitempty"`
+}
+
+// VLANInfo holds the tag of a VLAN device.
+type VLANInfo struct {
+	ID       int    `json:"id"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// IPoIBInfo holds the partition of an IPoIB interface.
+type IPoIBInfo struct {
+	// PKey is the partition key (e.g., "0x8001").
+	PKey string `json:"pkey"`
+	// Mode is "datagram" or "connected".
+	Mode string `json:"mode,omitempty"`
+}
+
+// lacpPortStateFlags names the bits of an LACP port state (IEEE 802.1AX).
+var lacpPortStateFlags = []string{
+	"activity",
+	"short_timeout",
+	"aggregation",
+	"synchronization",
+	"collecting",
+	"distributing",
+	"defaulted",
+	"expired",
+}
+
+// bridgePortStates maps brport/state to STP port state names.
+var bridgePortStates = map[string]string{
+	"0": "disabled",
+	"1": "listening",
+	"2": "learning",
+	"3": "forwarding",
+	"4": "blocking",
+}
+
+// collectUppers builds the stack of devices over a port from the
+// upper_<name> links in sysfs, with each device's type-specific settings
+// from netlink. IPoIB child interfaces are found by their iflink.
+func collectUppers(h *host.Host, pciAddr, ifName string) []UpperDevice {
+	netPath := filepath.Join(sysBusPCIDevices, pciAddr, "net")
+	uppers := walkUppers(h, filepath.Join(netPath, ifName), 0)
+
+	for _, child := range ipoibChildren(h, netPath, ifName) {
+		if !hasUpper(uppers, child) {
+			upper := describeUpper(h, child, filepath.Join(netPath, child))
+			upper.Uppers = walkUppers(h, filepath.Join(netPath, child), 1)
+			uppers = append(uppers, upper)
+		}
+	}
+
+	return uppers
+}
+
+// walkUppers returns the devices stacked directly on the netdev at
+// lowerPath, each with its own uppers.
+func walkUppers(h *host.Host, lowerPath string, depth int) []UpperDevice {
+	if depth >= maxUpperDepth {
+		return nil
+	}
+	entries, err := h.ReadDir(lowerPath)
+	if err != nil {
+		return nil
+	}
+
+	var uppers []UpperDevice
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), "upper_")
+		if !ok {
+			continue
+		}
+		upperPath := filepath.Join(sysClassNet, name)
+
+		upper := describeUpper(h, name, upperPath)
+		switch upper.Kind {
+		case "bond":
+			upper.BondSlave = readBondSlave(h, filepath.Join(lowerPath, "bonding_slave"))
+		case "bridge":
+			upper.BridgePort = readBridgePort(h, filepath.Join(lowerPath, "brport"))
+		}
+		upper.Uppers = walkUppers(h, upperPath, depth+1)
+		uppers = append(uppers, upper)
+	}
+	return uppers
+}
+
+// describeUpper reads the kind, state, lowers and type-specific settings
+// of a netdev. The kind comes from netlink, or from sysfs when netlink is
+// unavailable, in which case VLAN IDs and macvlan modes are not reported.
+func describeUpper(h *host.Host, name, path string) UpperDevice {
+	upper := UpperDevice{
+		InterfaceName: name,
+		State:         h.ReadString(filepath.Join(path, "operstate")),
+	}
+
+	if entries, err := h.ReadDir(path); err == nil {
+		for _, entry := range entries {
+			if lower, ok := strings.CutPrefix(entry.Name(), "lower_"); ok {
+				upper.Lowers = append(upper.Lowers, lower)
+			}
+		}
+	}
+
+	if info, err := h.Netlink.LinkInfo(name); err == nil {
+		upper.Kind = info.Kind
+		if info.Kind == "vlan" {
+			upper.VLAN = &VLANInfo{ID: info.VLANID, Protocol: info.VLANProtocol}
+		}
+		upper.MacvlanMode = info.MacvlanMode
+	}
+	if upper.Kind == "" {
+		upper.Kind = sysfsLinkKind(h, path)
+	}
+
+	switch upper.Kind {
+	case "bond":
+		upper.Master = true
+		upper.Bond = readBond(h, filepath.Join(path, "bonding"))
+	case "bridge":
+		upper.Master = true
+		upper.Bridge = &BridgeInfo{
+			STP:           stpEnabled(h.ReadString(filepath.Join(path, "bridge", "stp_state"))),
+			VLANFiltering: h.ReadString(filepath.Join(path, "bridge", "vlan_filtering")) == "1",
+		}
+	case "openvswitch":
+		upper.Master = true
+	case "ipoib":
+		upper.IPoIB = &IPoIBInfo{
+			PKey: h.ReadString(filepath.Join(path, "pkey")),
+			Mode: h.ReadString(filepath.Join(path, "mode")),
+		}
+	}
+
+	return upper
+}
+
+// sysfsLinkKind infers the kind of a netdev from its sysfs attributes.
+func sysfsLinkKind(h *host.Host, path string) string {
+	switch {
+	case h.Exists(filepath.Join(path, "bonding")):
+		return "bond"
+	case h.Exists(filepath.Join(path, "bridge")):
+		return "bridge"
+	case h.ReadString(filepath.Join(path, "type")) == arphrdInfiniBand:
+		return "ipoib"
+	}
+	return ""
+}
+
+// readBond reads the bonding directory of a bond.
+func readBond(h *host.Host, dir string) *BondInfo {
+	bond := &BondInfo{
+		Mode:           sysfsEnumName(h.ReadString(filepath.Join(dir, "mode"))),
+		Slaves:         strings.Fields(h.ReadString(filepath.Join(dir, "slaves"))),
+		ActiveSlave:    h.ReadString(filepath.Join(dir, "active_slave")),
+		XmitHashPolicy: sysfsEnumName(h.ReadString(filepath.Join(dir, "xmit_hash_policy"))),
+	}
+	bond.MIIMonMs, _ = strconv.Atoi(h.ReadString(filepath.Join(dir, "miimon")))
+
+	if bond.Mode == "802.3ad" {
+		bond.LACPRate = sysfsEnumName(h.ReadString(filepath.Join(dir, "lacp_rate")))
+		bond.ActiveAggregator, _ = strconv.Atoi(h.ReadString(filepath.Join(dir, "ad_aggregator")))
+		bond.PartnerMAC = h.ReadString(filepath.Join(dir, "ad_partner_mac"))
+	}
+	return bond
+}
+
+// readBondSlave reads the bonding_slave directory of a bond member.
+func readBondSlave(h *host.Host, dir string) *BondSlave {
+	if !h.Exists(dir) {
+		return nil
+	}
+
+	slave := &BondSlave{
+		State:     h.ReadString(filepath.Join(dir, "state")),
+		MIIStatus: h.ReadString(filepath.Join(dir, "mii_status")),
+	}
+	slave.LinkFailureCount, _ = strconv.Atoi(h.ReadString(filepath.Join(dir, "link_failure_count")))
+	slave.AggregatorID, _ = strconv.Atoi(h.ReadString(filepath.Join(dir, "ad_aggregator_id")))
+	slave.ActorState = lacpPortState(h.ReadString(filepath.Join(dir, "ad_actor_oper_port_state")))
+	slave.PartnerState = lacpPortState(h.ReadString(filepath.Join(dir, "ad_partner_oper_port_state")))
+	return slave
+}
+
+// readBridgePort reads the brport directory of a bridge port.
+func readBridgePort(h *host.Host, dir string) *BridgePort {
+	state := h.ReadString(filepath.Join(dir, "state"))
+	if state == "" {
+		return nil
+	}
+	if name, ok := bridgePortStates[state]; ok {
+		state = name
+	}
+	return &BridgePort{State: state}
+}
+
+// stpEnabled reports whether a bridge's stp_state runs STP: 1 in the
+// kernel, 2 in a user space daemon (e.g., mstpd). 0 and unreadable states
+// are disabled.
+func stpEnabled(state string) bool {
+	return state == "1" || state == "2"
+}
+
+// lacpPortState decodes an LACP port state byte into its set flags.
+func lacpPortState(value string) []string {
+	state, err := strconv.ParseUint(value, 10, 8)
+	if err != nil {
+		return nil
+	}
+
+	flags := []string{}
+	for bit, name := range lacpPortStateFlags {
+		if state&(1<<bit) != 0 {
+			flags = append(flags, name)
+		}
+	}
+	return flags
+}
+
+// sysfsEnumName turns a bonding option such as "802.3ad 4" into its name.
+func sysfsEnumName(value string) string {
+	name, _, _ := strings.Cut(value, " ")
+	return name
+}
+
+// ipoibChildren returns the IPoIB child interfaces (P_Key partitions) of
+// a netdev. Children are registered under the same PCI function and link
+// to their parent through iflink.
+func ipoibChildren(h *host.Host, netPath, ifName string) []string {
+	parentIndex := h.ReadString(filepath.Join(netPath, ifName, "ifindex"))
+	if parentIndex == "" {
+		return nil
+	}
+	entries, err := h.ReadDir(netPath)
+	if err != nil {
+		return nil
+	}
+
+	var children []string
+	for _, entry := range entries {
+		if entry.Name() == ifName {
+			continue
+		}
+		if h.ReadString(filepath.Join(netPath, entry.Name(), "iflink")) == parentIndex {
+			children = append(children, entry.Name())
+		}
+	}
+	return children
+}
+
+// isChildNetdev reports whether a netdev is a child of another netdev on
+// the same PCI function (its iflink is not its own ifindex), such as an
+// IPoIB P_Key interface.
+func isChildNetdev(h *host.Host, ifPath string) bool {
+	ifIndex := h.ReadString(filepath.Join(ifPath, "ifindex"))
+	ifLink := h.ReadString(filepath.Join(ifPath, "iflink"))
+	return ifIndex != "" && ifLink != "" && ifIndex != ifLink
+}
+
+// hasUpper reports whether uppers contains a device named name.
+func hasUpper(uppers []UpperDevice, name string) bool {
+	for _, upper := range uppers {
+		if upper.InterfaceName == name {
+			return true
+		}
+	}
+	return false
+}
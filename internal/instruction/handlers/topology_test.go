@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/host/hosttest"
+)
+
+func TestSTPEnabled(t *testing.T) {
+	for state, want := range map[string]bool{"0": false, "1": true, "2": true, "": false} {
+		if got := stpEnabled(state); got != want {
+			t.Errorf("stpEnabled(%q) = %v, want %v", state, got, want)
+		}
+	}
+}
+
+func TestLACPPortState(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{value: "61", want: []string{"activity", "aggregation", "synchronization", "collecting", "distributing"}},
+		{value: "71", want: []string{"activity", "short_timeout", "aggregation", "defaulted"}},
+		{value: "0", want: []string{}},
+		{value: "", want: nil},
+		{value: "256", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := lacpPortState(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lacpPortState(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestCollectUppers_SysfsFallback checks that the stack is still built
+// from sysfs when netlink is unavailable.
+func TestCollectUppers_SysfsFallback(t *testing.T) {
+	h := hosttest.Load(t, filepath.Join("testdata", "hosts", "cx6.txt"))
+	h.Netlink = &hosttest.FakeNetlink{}
+
+	uppers := collectUppers(h, "0000:98:00.0", "ens2f0np0")
+	if len(uppers) != 1 {
+		t.Fatalf("got %d uppers, want 1: %+v", len(uppers), uppers)
+	}
+	bond := uppers[0]
+	if bond.Kind != "bond" || bond.Bond == nil || bond.Bond.Mode != "802.3ad" || bond.BondSlave == nil || bond.BondSlave.State != "active" {
+		t.Errorf("bond0 = %+v", bond)
+	}
+	if len(bond.Uppers) != 2 {
+		t.Fatalf("got %d uppers of bond0, want 2: %+v", len(bond.Uppers), bond.Uppers)
+	}
+
+	// VLAN and macvlan settings are only available over netlink
+	vlan := bond.Uppers[0]
+	if vlan.InterfaceName != "bond0.100" || vlan.Kind != "" || vlan.VLAN != nil {
+		t.Errorf("bond0.100 = %+v", vlan)
+	}
+	if len(vlan.Uppers) != 1 || vlan.Uppers[0].Kind != "bridge" || vlan.Uppers[0].BridgePort == nil {
+		t.Errorf("uppers of bond0.100 = %+v, want bridge br100", vlan.Uppers)
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/netlink"
+	"github.com/filanov/netctrl-agent/internal/transceiver"
+)
+
+// collectModule reads and decodes the transceiver module or cable plugged
+// into a port, over netlink or, on older kernels, the ethtool ioctl. It
+// returns nil without an error if the driver does not expose module EEPROM
+// (e.g., virtual functions or IPoIB).
+func collectModule(h *host.Host, ifName string) (*transceiver.Module, error) {
+	read := func(i2cAddr, page, bank uint8, offset, length int) ([]byte, error) {
+		return h.Netlink.ModuleEEPROM(ifName, netlink.EEPROMRequest{
+			I2CAddress: i2cAddr,
+			Page:       page,
+			Bank:       bank,
+			Offset:     uint32(offset),
+			Length:     uint32(length),
+		})
+	}
+
+	module, err := transceiver.Decode(read)
+	if netlink.IsNotSupported(err) {
+		return nil, nil
+	}
+	return module, err
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/instruction"
+	"github.com/filanov/netctrl-agent/internal/lldp"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+// Cabling verdicts of a port.
+const (
+	// CablingCorrect means the port sees the expected switch port.
+	CablingCorrect = "correct"
+	// CablingMiswired means the port sees a different switch port.
+	CablingMiswired = "miswired"
+	// CablingMissingNeighbor means the port is in the plan but sees no
+	// LLDP neighbor.
+	CablingMissingNeighbor = "missing_neighbor"
+	// CablingUnexpectedNeighbor means the port is not in the plan but
+	// sees an LLDP neighbor.
+	CablingUnexpectedNeighbor = "unexpected_neighbor"
+	// CablingUnknownPort means the plan names a port this host does not have.
+	CablingUnknownPort = "unknown_port"
+)
+
+// VerifyCablingPayload represents the JSON payload for VERIFY_CABLING
+// instructions: the expected cabling of the host's ports.
+type VerifyCablingPayload struct {
+	Links []CablingLink `json:"links"`
+}
+
+// CablingLink is the expected switch port of a local port. The local port
+// is given by its interface name, or by the PCI address of its NIC and
+// its port number.
+type CablingLink struct {
+	Interface  string `json:"interface,omitempty"`
+	PCIAddress string `json:"pci_address,omitempty"`
+	Port       int    `json:"port,omitempty"`
+	// Switch is the switch's system name or chassis ID.
+	Switch string `json:"switch"`
+	// SwitchPort is the switch's port ID or port description.
+	SwitchPort string `json:"switch_port"`
+}
+
+// CablingReport is the result document of VERIFY_CABLING.
+type CablingReport struct {
+	Ports []PortCabling `json:"ports"`
+	// Summary counts the ports of each verdict.
+	Summary map[string]int `json:"summary"`
+	// Correct is true if every planned port is correct and no other port
+	// sees a neighbor.
+	Correct bool `json:"correct"`
+}
+
+// PortCabling is the verdict for one port.
+type PortCabling struct {
+	PCIAddress    string `json:"pci_address,omitempty"`
+	Port          int    `json:"port,omitempty"`
+	InterfaceName string `json:"interface_name,omitempty"`
+	Verdict       string `json:"verdict"`
+	// Expected is the switch port from the plan.
+	Expected *SwitchPort `json:"expected,omitempty"`
+	// Neighbors are the switch ports seen over LLDP.
+	Neighbors []SwitchPort `json:"neighbors,omitempty"`
+}
+
+// SwitchPort identifies a switch port.
+type SwitchPort struct {
+	Switch          string `json:"switch"`
+	ChassisID       string `json:"chassis_id,omitempty"`
+	Port            string `json:"port"`
+	PortDescription string `json:"port_description,omitempty"`
+}
+
+// VerifyCablingHandler handles VERIFY_CABLING instructions.
+type VerifyCablingHandler struct {
+	host *host.Host
+}
+
+// NewVerifyCablingHandler creates a new cabling verification handler that
+// compares the LLDP neighbors recorded in h with the plan. A nil h uses
+// the real host.
+func NewVerifyCablingHandler(h *host.Host) *VerifyCablingHandler {
+	if h == nil {
+		h = host.Default()
+	}
+	return &VerifyCablingHandler{
+		host: h,
+	}
+}
+
+// Execute verifies the cabling of the Mellanox NIC ports against the plan.
+func (h *VerifyCablingHandler) Execute(ctx context.Context, inst *v1.Instruction) (string, error) {
+	if inst == nil {
+		return "", fmt.Errorf("instruction is nil")
+	}
+
+	var payload VerifyCablingPayload
+	if err := json.Unmarshal([]byte(inst.Payload), &payload); err != nil {
+		return "", fmt.Errorf("failed to parse cabling payload: %w", err)
+	}
+	if err := validateCablingPlan(payload.Links); err != nil {
+		return "", err
+	}
+	if h.host.LLDP == nil {
+		return "", errors.New("LLDP neighbor discovery is not enabled")
+	}
+
+	report, err := verifyCabling(ctx, h.host, payload.Links)
+	if err != nil {
+		return "", err
+	}
+
+	resultJSON, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cabling report: %w", err)
+	}
+
+	return string(resultJSON), nil
+}
+
+// validateCablingPlan checks that every link names one local port and a
+// switch port, and that no local port is planned twice.
+func validateCablingPlan(links []CablingLink) error {
+	if len(links) == 0 {
+		return errors.New("cabling plan has no links")
+	}
+
+	seen := make(map[string]bool, len(links))
+	for i, link := range links {
+		key := link.localPort()
+		switch {
+		case link.Interface != "" && link.PCIAddress != "":
+			return fmt.Errorf("link %d: interface and pci_address are mutually exclusive", i)
+		case link.Interface == "" && (link.PCIAddress == "" || link.Port <= 0):
+			return fmt.Errorf("link %d: interface or pci_address and port are required", i)
+		case link.Switch == "" || link.SwitchPort == "":
+			return fmt.Errorf("link %s: switch and switch_port are required", key)
+		case seen[key]:
+			return fmt.Errorf("link %s: port is planned more than once", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// localPort returns the local port of a link as "<interface>" or
+// "<pci address>/<port>".
+func (l CablingLink) localPort() string {
+	if l.Interface != "" {
+		return l.Interface
+	}
+	return fmt.Sprintf("%s/%d", l.PCIAddress, l.Port)
+}
+
+// verifyCabling gives a verdict for every planned port and every other
+// port that sees a neighbor. Ports are reported in NIC and port order,
+// followed by planned ports that were not found.
+func verifyCabling(ctx context.Context, h *host.Host, links []CablingLink) (*CablingReport, error) {
+	pciDevices, err := findMellanoxPCIDevices(ctx, h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Mellanox devices: %w", err)
+	}
+
+	planned := make(map[string]int, len(links))
+	for i, link := range links {
+		planned[link.localPort()] = i
+	}
+	found := make([]bool, len(links))
+
+	report := &CablingReport{Ports: []PortCabling{}, Summary: make(map[string]int)}
+	for i, pciDev := range pciDevices {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		instruction.ReportProgress(ctx, i*100/len(pciDevices), "verify",
+			fmt.Sprintf("device %d/%d %s", i+1, len(pciDevices), pciDev.Address))
+
+		ports, _, err := listNetdevs(h, pciDev.Address)
+		if err != nil {
+			continue
+		}
+		for _, port := range ports {
+			result := PortCabling{
+				PCIAddress:    port.PCIAddress,
+				Port:          port.Number,
+				InterfaceName: port.InterfaceName,
+			}
+			neighbors := h.LLDP.Neighbors(port.InterfaceName)
+			for _, n := range neighbors {
+				result.Neighbors = append(result.Neighbors, neighborSwitchPort(n))
+			}
+
+			idx, ok := planned[port.InterfaceName]
+			if !ok {
+				idx, ok = planned[fmt.Sprintf("%s/%d", port.PCIAddress, port.Number)]
+			}
+			switch {
+			case ok:
+				found[idx] = true
+				link := links[idx]
+				result.Expected = &SwitchPort{Switch: link.Switch, Port: link.SwitchPort}
+				result.Verdict = cablingVerdict(link, neighbors)
+			case len(result.Neighbors) > 0:
+				result.Verdict = CablingUnexpectedNeighbor
+			default:
+				continue
+			}
+			report.Ports = append(report.Ports, result)
+		}
+	}
+
+	for i, link := range links {
+		if found[i] {
+			continue
+		}
+		report.Ports = append(report.Ports, PortCabling{
+			PCIAddress:    link.PCIAddress,
+			Port:          link.Port,
+			InterfaceName: link.Interface,
+			Verdict:       CablingUnknownPort,
+			Expected:      &SwitchPort{Switch: link.Switch, Port: link.SwitchPort},
+		})
+	}
+
+	report.Correct = true
+	for _, port := range report.Ports {
+		report.Summary[port.Verdict]++
+		if port.Verdict != CablingCorrect {
+			report.Correct = false
+		}
+	}
+
+	instruction.ReportProgress(ctx, 100, "done", fmt.Sprintf("verified cabling of %d ports", len(report.Ports)))
+
+	return report, nil
+}
+
+// cablingVerdict compares the neighbors of a planned port with the plan.
+// A port with several neighbors is correct if any of them matches.
+func cablingVerdict(link CablingLink, neighbors []lldp.Neighbor) string {
+	if len(neighbors) == 0 {
+		return CablingMissingNeighbor
+	}
+	for _, n := range neighbors {
+		if matchSwitch(link.Switch, n) && matchSwitchPort(link.SwitchPort, n) {
+			return CablingCorrect
+		}
+	}
+	return CablingMiswired
+}
+
+// matchSwitch reports whether a neighbor is the named switch. Names match
+// case-insensitively, and a short host name matches a fully qualified
+// system name (e.g., "leaf01" matches "leaf01.dc1"). The chassis ID also
+// matches, for switches that do not send their system name.
+func matchSwitch(name string, n lldp.Neighbor) bool {
+	if strings.EqualFold(name, n.ChassisID) {
+		return true
+	}
+	if n.SystemName == "" {
+		return false
+	}
+	if strings.EqualFold(name, n.SystemName) {
+		return true
+	}
+	short, _, _ := strings.Cut(n.SystemName, ".")
+	wantShort, _, _ := strings.Cut(name, ".")
+	return strings.EqualFold(short, wantShort) && (short == n.SystemName || wantShort == name)
+}
+
+// matchSwitchPort reports whether a neighbor's port ID or port
+// description is the named port, case-insensitively.
+func matchSwitchPort(port string, n lldp.Neighbor) bool {
+	return strings.EqualFold(port, n.PortID) || (n.PortDescription != "" && strings.EqualFold(port, n.PortDescription))
+}
+
+// neighborSwitchPort returns the switch port of an LLDP neighbor.
+func neighborSwitchPort(n lldp.Neighbor) SwitchPort {
+	sp := SwitchPort{
+		Switch:    n.SystemName,
+		ChassisID: n.ChassisID,
+		Port:      n.PortID,
+	}
+	if n.PortDescription != n.PortID {
+		sp.PortDescription = n.PortDescription
+	}
+	return sp
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+	"github.com/filanov/netctrl-agent/internal/host/hosttest"
+	"github.com/filanov/netctrl-agent/internal/lldp"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+func TestVerifyCablingHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		capture     string
+		payload     string
+		wantVerdict map[string]string
+		wantCorrect bool
+	}{
+		{
+			name:        "correct",
+			capture:     "cx6.txt",
+			payload:     `{"links": [{"interface": "ens2f0np0", "switch": "leaf01", "switch_port": "swp1"}, {"pci_address": "0000:98:00.1", "port": 1, "switch": "LEAF01", "switch_port": "swp9"}]}`,
+			wantVerdict: map[string]string{"ens2f0np0": CablingCorrect, "ens2f1np1": CablingCorrect},
+			wantCorrect: true,
+		},
+		{
+			name:        "swapped cables",
+			capture:     "cx6.txt",
+			payload:     `{"links": [{"interface": "ens2f0np0", "switch": "leaf01", "switch_port": "swp9"}, {"interface": "ens2f1np1", "switch": "leaf01", "switch_port": "swp1"}]}`,
+			wantVerdict: map[string]string{"ens2f0np0": CablingMiswired, "ens2f1np1": CablingMiswired},
+		},
+		{
+			name:        "unplanned port with neighbor",
+			capture:     "cx6.txt",
+			payload:     `{"links": [{"interface": "ens2f0np0", "switch": "leaf01", "switch_port": "swp1"}]}`,
+			wantVerdict: map[string]string{"ens2f0np0": CablingCorrect, "ens2f1np1": CablingUnexpectedNeighbor},
+		},
+		{
+			name:        "fully qualified name and missing neighbor",
+			capture:     "cx5.txt",
+			payload:     `{"links": [{"interface": "ens1f0np0", "switch": "leaf-r12-a", "switch_port": "Ethernet12/1"}, {"interface": "ens1f1np1", "switch": "leaf-r12-a", "switch_port": "Ethernet12/2"}]}`,
+			wantVerdict: map[string]string{"ens1f0np0": CablingCorrect, "ens1f1np1": CablingMissingNeighbor},
+		},
+		{
+			name:        "chassis ID and unknown port",
+			capture:     "cx5.txt",
+			payload:     `{"links": [{"interface": "ens1f0np0", "switch": "28:99:3A:4D:12:00", "switch_port": "Ethernet12/1"}, {"interface": "ens9f0", "switch": "leaf-r12-a", "switch_port": "Ethernet12/3"}]}`,
+			wantVerdict: map[string]string{"ens1f0np0": CablingCorrect, "ens9f0": CablingUnknownPort},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := hosttest.Load(t, filepath.Join("testdata", "hosts", tt.capture))
+
+			report := executeVerifyCabling(t, h, tt.payload)
+
+			verdicts := make(map[string]string)
+			for _, port := range report.Ports {
+				verdicts[port.InterfaceName] = port.Verdict
+			}
+			if !reflect.DeepEqual(verdicts, tt.wantVerdict) {
+				t.Errorf("verdicts = %v, want %v", verdicts, tt.wantVerdict)
+			}
+			if report.Correct != tt.wantCorrect {
+				t.Errorf("correct = %v, want %v", report.Correct, tt.wantCorrect)
+			}
+		})
+	}
+}
+
+func TestVerifyCablingHandler_MiswiredNeighbor(t *testing.T) {
+	h := hosttest.Load(t, filepath.Join("testdata", "hosts", "cx5.txt"))
+
+	report := executeVerifyCabling(t, h, `{"links": [{"interface": "ens1f0np0", "switch": "leaf-r12-b", "switch_port": "Ethernet12/1"}]}`)
+	if len(report.Ports) != 1 {
+		t.Fatalf("got %d ports, want 1: %+v", len(report.Ports), report.Ports)
+	}
+
+	port := report.Ports[0]
+	if port.Verdict != CablingMiswired || port.PCIAddress != "0000:3b:00.0" || port.Port != 1 {
+		t.Errorf("port = %+v", port)
+	}
+	want := []SwitchPort{{
+		Switch:          "leaf-r12-a.dc1",
+		ChassisID:       "28:99:3a:4d:12:00",
+		Port:            "Ethernet12/1",
+		PortDescription: "host-r12-07 ens1f0np0",
+	}}
+	if !reflect.DeepEqual(port.Neighbors, want) {
+		t.Errorf("neighbors = %+v, want %+v", port.Neighbors, want)
+	}
+	if report.Summary[CablingMiswired] != 1 || report.Correct {
+		t.Errorf("summary = %v, correct = %v", report.Summary, report.Correct)
+	}
+}
+
+func TestVerifyCablingHandler_Errors(t *testing.T) {
+	withLLDP := hosttest.Load(t, filepath.Join("testdata", "hosts", "cx5.txt"))
+	withoutLLDP := host.New(t.TempDir(), nil)
+
+	tests := []struct {
+		name    string
+		host    *host.Host
+		payload string
+		wantErr string
+	}{
+		{name: "invalid json", host: withLLDP, payload: `{`, wantErr: "failed to parse cabling payload"},
+		{name: "no links", host: withLLDP, payload: `{"links": []}`, wantErr: "no links"},
+		{name: "no local port", host: withLLDP, payload: `{"links": [{"pci_address": "0000:3b:00.0", "switch": "a", "switch_port": "b"}]}`, wantErr: "interface or pci_address and port are required"},
+		{name: "both local ports", host: withLLDP, payload: `{"links": [{"interface": "x", "pci_address": "0000:3b:00.0", "port": 1, "switch": "a", "switch_port": "b"}]}`, wantErr: "mutually exclusive"},
+		{name: "no switch port", host: withLLDP, payload: `{"links": [{"interface": "x", "switch": "a"}]}`, wantErr: "link x: switch and switch_port are required"},
+		{name: "duplicate", host: withLLDP, payload: `{"links": [{"interface": "x", "switch": "a", "switch_port": "b"}, {"interface": "x", "switch": "a", "switch_port": "c"}]}`, wantErr: "planned more than once"},
+		{name: "lldp disabled", host: withoutLLDP, payload: `{"links": [{"interface": "x", "switch": "a", "switch_port": "b"}]}`, wantErr: "not enabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifyCablingHandler(tt.host).Execute(context.Background(), &v1.Instruction{Payload: tt.payload})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatchSwitch(t *testing.T) {
+	tests := []struct {
+		name       string
+		systemName string
+		want       bool
+	}{
+		{name: "leaf01", systemName: "leaf01", want: true},
+		{name: "leaf01", systemName: "LEAF01.dc1", want: true},
+		{name: "leaf01.dc1", systemName: "leaf01", want: true},
+		{name: "leaf01.dc1", systemName: "leaf01.dc2", want: false},
+		{name: "leaf01", systemName: "leaf011", want: false},
+		{name: "leaf01", systemName: "", want: false},
+		{name: "1c:34:da:5c:a1:00", systemName: "leaf01", want: true},
+	}
+
+	for _, tt := range tests {
+		n := lldp.Neighbor{ChassisID: "1c:34:da:5c:a1:00", SystemName: tt.systemName}
+		if got := matchSwitch(tt.name, n); got != tt.want {
+			t.Errorf("matchSwitch(%q, %q) = %v, want %v", tt.name, tt.systemName, got, tt.want)
+		}
+	}
+}
+
+func executeVerifyCabling(t *testing.T, h *host.Host, payload string) CablingReport {
+	t.Helper()
+
+	result, err := NewVerifyCablingHandler(h).Execute(context.Background(), &v1.Instruction{Payload: payload})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var report CablingReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		t.Fatalf("failed to parse result: %v", err)
+	}
+	return report
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/filanov/netctrl-agent/internal/host"
+)
+
+// PCI VPD resource tags (PCI Local Bus Specification 3.0, section 6.4 and appendix I).
+const (
+	vpdTagIdentifier = 0x02 // large resource: identifier string
+	vpdTagReadOnly   = 0x10 // large resource: VPD-R
+	vpdTagReadWrite  = 0x11 // large resource: VPD-W
+	vpdTagEnd        = 0x0f // small resource: end tag
+)
+
+// VPD holds the fields of a PCI device's Vital Product Data.
+type VPD struct {
+	// Identifier is the product name from the identifier string resource.
+	Identifier        string `json:"identifier,omitempty"`
+	PartNumber        string `json:"part_number,omitempty"`
+	EngineeringChange string `json:"engineering_change,omitempty"`
+	SerialNumber      string `json:"serial_number,omitempty"`
+	Manufacturer      string `json:"manufacturer,omitempty"`
+	// Vendor holds the vendor-specific fields V0-VZ of the VPD-R and VPD-W
+	// resources; a read-only field wins over a writable one.
+	Vendor map[string]string `json:"vendor,omitempty"`
+	// ChecksumValid reports whether the RV checksum over the identifier and
+	// read-only resources is correct. Read-only fields are only trustworthy
+	// when it is true.
+	ChecksumValid bool `json:"checksum_valid"`
+}
+
+// readVPD reads and parses the VPD of a PCI function from sysfs.
+func readVPD(h *host.Host, pciAddr string) (*VPD, error) {
+	data, err := h.ReadFile(filepath.Join(sysBusPCIDevices, pciAddr, "vpd"))
+	if err != nil {
+		return nil, err
+	}
+	return ParseVPD(data)
+}
+
+// ParseVPD parses PCI VPD: a sequence of resources, each a small (1-byte
+// header) or large (3-byte header with a little-endian length) tag, ending
+// with the end tag. The VPD-R and VPD-W resources contain keyword fields
+// with a 3-byte header: a 2-character keyword and a 1-byte length.
+func ParseVPD(data []byte) (*VPD, error) {
+	vpd := &VPD{}
+	sawReadOnly := false
+
+	for offset := 0; offset < len(data); {
+		tag := data[offset]
+
+		// Small resource
+		if tag&0x80 == 0 {
+			name := (tag >> 3) & 0x0f
+			if name == vpdTagEnd {
+				break
+			}
+			offset += 1 + int(tag&0x07)
+			continue
+		}
+
+		// Large resource
+		if offset+3 > len(data) {
+			return nil, fmt.Errorf("truncated VPD resource header at offset %d", offset)
+		}
+		name := tag & 0x7f
+		length := int(binary.LittleEndian.Uint16(data[offset+1 : offset+3]))
+		start := offset + 3
+		end := start + length
+		if end > len(data) {
+			return nil, fmt.Errorf("VPD resource 0x%02x at offset %d exceeds data (%d > %d)", tag, offset, end, len(data))
+		}
+
+		switch name {
+		case vpdTagIdentifier:
+			vpd.Identifier = cleanVPDString(data[start:end])
+		case vpdTagReadOnly:
+			// The RV checksum covers all bytes from the start of the VPD
+			valid, err := vpd.parseFields(data[start:end], data[:start], true)
+			if err != nil {
+				return nil, err
+			}
+			vpd.ChecksumValid = valid
+			sawReadOnly = true
+		case vpdTagReadWrite:
+			if _, err := vpd.parseFields(data[start:end], nil, false); err != nil {
+				return nil, err
+			}
+		}
+
+		offset = end
+	}
+
+	if vpd.Identifier == "" && !sawReadOnly {
+		return nil, fmt.Errorf("no VPD resources found")
+	}
+
+	return vpd, nil
+}
+
+// parseFields parses the keyword fields of a VPD-R or VPD-W resource.
+// For VPD-R, prefix is the VPD data preceding the resource, used to validate
+// the RV checksum; it returns whether the checksum is valid.
+func (v *VPD) parseFields(fields, prefix []byte, readOnly bool) (bool, error) {
+	checksumValid := false
+
+	for offset := 0; offset < len(fields); {
+		if offset+3 > len(fields) {
+			return false, fmt.Errorf("truncated VPD field header at offset %d", offset)
+		}
+		keyword := string(fields[offset : offset+2])
+		length := int(fields[offset+2])
+		start := offset + 3
+		end := start + length
+		if end > len(fields) {
+			return false, fmt.Errorf("VPD field %q exceeds resource (%d > %d)", keyword, end, len(fields))
+		}
+		value := fields[start:end]
+
+		switch {
+		case keyword == "RV" && readOnly:
+			// The first byte of RV is the checksum; the sum of all bytes up
+			// to and including it must be zero. RV ends the read-only fields.
+			if length == 0 {
+				return false, fmt.Errorf("empty RV field")
+			}
+			var sum byte
+			for _, b := range prefix {
+				sum += b
+			}
+			for _, b := range fields[:start+1] {
+				sum += b
+			}
+			checksumValid = sum == 0
+			return checksumValid, nil
+		case keyword == "RW":
+			// Remaining writable space
+		case !readOnly:
+			// VPD-W carries vendor (Vx) and system (Yx) fields; identity
+			// fields are never taken from it, and vendor fields do not
+			// override read-only ones
+			if _, ok := v.Vendor[keyword]; keyword[0] == 'V' && !ok {
+				v.setField(keyword, cleanVPDString(value))
+			}
+		default:
+			v.setField(keyword, cleanVPDString(value))
+		}
+
+		offset = end
+	}
+
+	return checksumValid, nil
+}
+
+// setField stores a keyword field.
+func (v *VPD) setField(keyword, value string) {
+	switch keyword {
+	case "PN":
+		v.PartNumber = value
+	case "EC":
+		v.EngineeringChange = value
+	case "SN":
+		v.SerialNumber = value
+	case "MN":
+		v.Manufacturer = value
+	default:
+		if len(keyword) == 2 && keyword[0] == 'V' && isVPDKeywordChar(keyword[1]) {
+			if v.Vendor == nil {
+				v.Vendor = make(map[string]string)
+			}
+			v.Vendor[keyword] = value
+		}
+	}
+}
+
+// isVPDKeywordChar reports whether c is a valid second keyword character (0-9, A-Z).
+func isVPDKeywordChar(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z')
+}
+
+// cleanVPDString trims padding (spaces and NULs) and drops non-printable
+// characters from a VPD value.
+func cleanVPDString(b []byte) string {
+	s := strings.Map(func(r rune) rune {
+		if r >= 32 && r < 127 {
+			return r
+		}
+		return -1
+	}, string(b))
+	return strings.TrimSpace(s)
+}
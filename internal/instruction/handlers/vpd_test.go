@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// vpdField is a keyword field for buildVPD.
+type vpdField struct {
+	keyword, value string
+}
+
+// buildVPD encodes an identifier string, VPD-R fields with a correct RV
+// checksum (corrupted if badChecksum is set), VPD-W fields and the end tag.
+func buildVPD(identifier string, readOnly, readWrite []vpdField, badChecksum bool) []byte {
+	var buf bytes.Buffer
+
+	buf.Write([]byte{0x82, byte(len(identifier)), byte(len(identifier) >> 8)})
+	buf.WriteString(identifier)
+
+	var fields bytes.Buffer
+	for _, f := range readOnly {
+		fields.WriteString(f.keyword)
+		fields.WriteByte(byte(len(f.value)))
+		fields.WriteString(f.value)
+	}
+	// RV: checksum byte plus one reserved byte
+	fields.Write([]byte{'R', 'V', 2})
+	length := fields.Len() + 2
+	buf.Write([]byte{0x90, byte(length), byte(length >> 8)})
+	buf.Write(fields.Bytes())
+
+	var sum byte
+	for _, b := range buf.Bytes() {
+		sum += b
+	}
+	checksum := -sum
+	if badChecksum {
+		checksum++
+	}
+	buf.Write([]byte{checksum, 0})
+
+	if len(readWrite) > 0 {
+		var rw bytes.Buffer
+		for _, f := range readWrite {
+			rw.WriteString(f.keyword)
+			rw.WriteByte(byte(len(f.value)))
+			rw.WriteString(f.value)
+		}
+		buf.Write([]byte{0x91, byte(rw.Len()), byte(rw.Len() >> 8)})
+		buf.Write(rw.Bytes())
+	}
+
+	buf.WriteByte(0x78)
+	return buf.Bytes()
+}
+
+func TestParseVPD(t *testing.T) {
+	readOnly := []vpdField{
+		{"PN", "MCX516A-CCAT"},
+		{"EC", "A7"},
+		{"SN", "MT2113X00123"},
+		{"MN", "MLNX"},
+		{"V0", "PCIeGen3 x16"},
+		{"V3", "8a2ab1cbe3d9eb11"},
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    *VPD
+		wantErr bool
+	}{
+		{
+			name: "complete VPD",
+			data: buildVPD("CX516A - ConnectX-5 QSFP28", readOnly, nil, false),
+			want: &VPD{
+				Identifier:        "CX516A - ConnectX-5 QSFP28",
+				PartNumber:        "MCX516A-CCAT",
+				EngineeringChange: "A7",
+				SerialNumber:      "MT2113X00123",
+				Manufacturer:      "MLNX",
+				Vendor:            map[string]string{"V0": "PCIeGen3 x16", "V3": "8a2ab1cbe3d9eb11"},
+				ChecksumValid:     true,
+			},
+		},
+		{
+			name: "SN inside identifier is not a field",
+			data: buildVPD("SNAP DPU", []vpdField{{"SN", "MT0000000001"}}, nil, false),
+			want: &VPD{Identifier: "SNAP DPU", SerialNumber: "MT0000000001", ChecksumValid: true},
+		},
+		{
+			name: "bad checksum",
+			data: buildVPD("ConnectX-6", []vpdField{{"PN", "MCX653106A-HDAT"}}, nil, true),
+			want: &VPD{Identifier: "ConnectX-6", PartNumber: "MCX653106A-HDAT", ChecksumValid: false},
+		},
+		{
+			name: "writable fields do not override read-only fields",
+			data: buildVPD("ConnectX-7", []vpdField{{"SN", "MT2318XZ04DK"}},
+				[]vpdField{{"SN", "bogus"}, {"PN", "bogus"}, {"EC", "bogus"}, {"MN", "bogus"}, {"RW", "\x00\x00\x00"}}, false),
+			want: &VPD{Identifier: "ConnectX-7", SerialNumber: "MT2318XZ04DK", ChecksumValid: true},
+		},
+		{
+			name: "writable vendor fields",
+			data: buildVPD("ConnectX-7", []vpdField{{"V0", "PCIeGen5 x16"}},
+				[]vpdField{{"V0", "bogus"}, {"V1", "custom"}, {"YA", "asset-42"}, {"RW", "\x00\x00\x00"}}, false),
+			want: &VPD{
+				Identifier:    "ConnectX-7",
+				Vendor:        map[string]string{"V0": "PCIeGen5 x16", "V1": "custom"},
+				ChecksumValid: true,
+			},
+		},
+		{
+			name: "padding after end tag and in values",
+			data: append(buildVPD("BlueField-2", []vpdField{{"PN", "MBF2H332A-AECOT   "}}, nil, false), 0xff, 0xff, 0xff),
+			want: &VPD{Identifier: "BlueField-2", PartNumber: "MBF2H332A-AECOT", ChecksumValid: true},
+		},
+		{
+			name:    "truncated resource",
+			data:    buildVPD("ConnectX-5", readOnly, nil, false)[:40],
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			data:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "all ones (unreadable VPD)",
+			data:    bytes.Repeat([]byte{0xff}, 16),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseVPD(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseVPD() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package instruction
+
+import (
+	"context"
+)
+
+// Progress is an interim status update emitted by a long-running handler.
+type Progress struct {
+	// Percent is the estimated completion percentage (0-100).
+	Percent int `json:"percent"`
+	// Phase names the current stage of the operation (e.g., "enumerate").
+	Phase string `json:"phase"`
+	// Message is a human-readable detail for operators.
+	Message string `json:"message,omitempty"`
+}
+
+// ProgressReporter receives progress updates from handlers.
+type ProgressReporter interface {
+	ReportProgress(progress Progress)
+}
+
+// ProgressReporterFunc adapts a function to the ProgressReporter interface.
+type ProgressReporterFunc func(progress Progress)
+
+// ReportProgress calls f(progress).
+func (f ProgressReporterFunc) ReportProgress(progress Progress) {
+	f(progress)
+}
+
+type progressKey struct{}
+
+// WithProgressReporter returns a context that carries the given reporter.
+func WithProgressReporter(ctx context.Context, reporter ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressKey{}, reporter)
+}
+
+// ReportProgress sends a progress update to the reporter carried by ctx.
+// It is a no-op if the context has no reporter, so handlers can call it
+// unconditionally.
+func ReportProgress(ctx context.Context, percent int, phase, message string) {
+	reporter, ok := ctx.Value(progressKey{}).(ProgressReporter)
+	if !ok || reporter == nil {
+		return
+	}
+
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+
+	reporter.ReportProgress(Progress{
+		Percent: percent,
+		Phase:   phase,
+		Message: message,
+	})
+}
@@ -0,0 +1,36 @@
+package instruction
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReportProgress(t *testing.T) {
+	var got []Progress
+	ctx := WithProgressReporter(context.Background(), ProgressReporterFunc(func(p Progress) {
+		got = append(got, p)
+	}))
+
+	ReportProgress(ctx, 40, "collect", "device 2/5")
+	ReportProgress(ctx, 150, "done", "")
+	ReportProgress(ctx, -1, "start", "")
+
+	want := []Progress{
+		{Percent: 40, Phase: "collect", Message: "device 2/5"},
+		{Percent: 100, Phase: "done"},
+		{Percent: 0, Phase: "start"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d updates, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("update %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReportProgress_NoReporter(t *testing.T) {
+	// Must not panic without a reporter in the context
+	ReportProgress(context.Background(), 50, "collect", "")
+}
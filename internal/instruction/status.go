@@ -0,0 +1,60 @@
+package instruction
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Status describes the outcome of an instruction execution.
+type Status string
+
+const (
+	// StatusSucceeded indicates the handler completed without error.
+	StatusSucceeded Status = "SUCCEEDED"
+	// StatusFailed indicates the handler returned an error.
+	StatusFailed Status = "FAILED"
+	// StatusPolicyDenied indicates the local execution policy rejected the instruction.
+	StatusPolicyDenied Status = "POLICY_DENIED"
+	// StatusCancelled indicates the instruction was cancelled while running.
+	StatusCancelled Status = "CANCELLED"
+)
+
+// StatusError is an error that carries an explicit instruction status.
+type StatusError struct {
+	Status Status
+	Err    error
+}
+
+// NewStatusError creates a StatusError with a formatted message.
+func NewStatusError(status Status, format string, args ...interface{}) *StatusError {
+	return &StatusError{
+		Status: status,
+		Err:    fmt.Errorf(format, args...),
+	}
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Status, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// StatusOf returns the status for an instruction execution error.
+// A nil error maps to StatusSucceeded, errors without an explicit status
+// map to StatusFailed.
+func StatusOf(err error) Status {
+	if err == nil {
+		return StatusSucceeded
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Status
+	}
+
+	return StatusFailed
+}
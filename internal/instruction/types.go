@@ -0,0 +1,73 @@
+package instruction
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+const typePrefix = "INSTRUCTION_TYPE_"
+
+// Agent extension instruction types. The server API does not define these
+// yet; they use values well above the range the proto reserves for future
+// types so they cannot collide with it.
+const (
+	// TypeCancel requests cancellation of a running instruction.
+	TypeCancel v1.InstructionType = 100
+	// TypeBatch executes an ordered list of sub-instructions.
+	TypeBatch v1.InstructionType = 101
+	// TypeSchedule adds or removes locally scheduled instructions.
+	TypeSchedule v1.InstructionType = 102
+	// TypeCollectCounters collects per-port traffic and error counters.
+	TypeCollectCounters v1.InstructionType = 103
+	// TypeVerifyCabling compares LLDP neighbors with an expected cabling plan.
+	TypeVerifyCabling v1.InstructionType = 104
+)
+
+// extensionTypeNames maps agent extension types to their short names.
+var extensionTypeNames = map[v1.InstructionType]string{
+	TypeCancel:          "CANCEL",
+	TypeBatch:           "BATCH",
+	TypeSchedule:        "SCHEDULE",
+	TypeCollectCounters: "COLLECT_COUNTERS",
+	TypeVerifyCabling:   "VERIFY_CABLING",
+}
+
+// IsExtensionType reports whether t is an agent extension type that has no
+// dedicated result message in the server API.
+func IsExtensionType(t v1.InstructionType) bool {
+	_, ok := extensionTypeNames[t]
+	return ok
+}
+
+// TypeName returns the short name of an instruction type (e.g., "COLLECT_HARDWARE").
+func TypeName(instructionType v1.InstructionType) string {
+	if name, ok := extensionTypeNames[instructionType]; ok {
+		return name
+	}
+	if name, ok := v1.InstructionType_name[int32(instructionType)]; ok {
+		return strings.TrimPrefix(name, typePrefix)
+	}
+	return fmt.Sprintf("%d", int32(instructionType))
+}
+
+// ParseType parses an instruction type name. Both the short form
+// ("COLLECT_HARDWARE") and the full enum name ("INSTRUCTION_TYPE_COLLECT_HARDWARE")
+// are accepted, case-insensitively.
+func ParseType(name string) (v1.InstructionType, error) {
+	upper := strings.ToUpper(strings.TrimSpace(name))
+	short := strings.TrimPrefix(upper, typePrefix)
+
+	for t, extName := range extensionTypeNames {
+		if extName == short {
+			return t, nil
+		}
+	}
+
+	if value, ok := v1.InstructionType_value[typePrefix+short]; ok {
+		return v1.InstructionType(value), nil
+	}
+
+	return v1.InstructionType_INSTRUCTION_TYPE_UNSPECIFIED, fmt.Errorf("unknown instruction type: %s", name)
+}
@@ -0,0 +1,136 @@
+package lldp
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultRescanInterval is how often the listener looks for interfaces
+// that appeared or went away.
+const DefaultRescanInterval = time.Minute
+
+// maxFrameSize is the receive buffer size; LLDPDUs fit in one Ethernet frame.
+const maxFrameSize = 9216
+
+// Listener receives LLDP frames on a changing set of interfaces and
+// records the neighbors in a Table.
+type Listener struct {
+	// Table receives the neighbors.
+	Table *Table
+	// Interfaces returns the interfaces to listen on.
+	Interfaces func(ctx context.Context) ([]string, error)
+	// RescanInterval is how often Interfaces is called; zero uses
+	// DefaultRescanInterval.
+	RescanInterval time.Duration
+}
+
+// Run listens until ctx is cancelled. Interfaces that cannot be opened or
+// fail are retried on the next rescan; receivers on interfaces that are
+// no longer listed are stopped.
+func (l *Listener) Run(ctx context.Context) {
+	interval := l.RescanInterval
+	if interval <= 0 {
+		interval = DefaultRescanInterval
+	}
+
+	r := &receivers{listening: make(map[string]*context.CancelFunc)}
+	defer r.wg.Wait()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if interfaces, err := l.Interfaces(ctx); err != nil {
+			log.Printf("LLDP: failed to list interfaces: %v", err)
+		} else {
+			r.reconcile(ctx, interfaces, l.listen)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// receivers tracks the receiver goroutine of each interface.
+type receivers struct {
+	wg sync.WaitGroup
+	mu sync.Mutex
+	// listening maps each interface to the cancel function of its
+	// receiver; receivers remove themselves when they stop.
+	listening map[string]*context.CancelFunc
+}
+
+// reconcile starts receivers on the interfaces without one and stops the
+// receivers of interfaces not in the list.
+func (r *receivers) reconcile(ctx context.Context, interfaces []string, listen func(context.Context, string) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := make(map[string]bool, len(interfaces))
+	for _, ifName := range interfaces {
+		wanted[ifName] = true
+		if _, ok := r.listening[ifName]; ok {
+			continue
+		}
+
+		ifCtx, cancel := context.WithCancel(ctx)
+		self := &cancel
+		r.listening[ifName] = self
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			defer cancel()
+			if err := listen(ifCtx, ifName); err != nil {
+				log.Printf("LLDP: stopped listening on %s: %v", ifName, err)
+			}
+			r.mu.Lock()
+			if r.listening[ifName] == self {
+				delete(r.listening, ifName)
+			}
+			r.mu.Unlock()
+		}()
+	}
+
+	for ifName, cancel := range r.listening {
+		if !wanted[ifName] {
+			(*cancel)()
+			delete(r.listening, ifName)
+		}
+	}
+}
+
+// listen receives frames on one interface until ctx is cancelled.
+func (l *Listener) listen(ctx context.Context, ifName string) error {
+	sock, err := openSocket(ifName)
+	if err != nil {
+		return err
+	}
+	defer sock.close()
+
+	buf := make([]byte, maxFrameSize)
+	for ctx.Err() == nil {
+		n, err := sock.receive(buf)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+
+		neighbor, err := Parse(buf[:n])
+		if err != nil {
+			if !errors.Is(err, ErrNotLLDP) {
+				log.Printf("LLDP: invalid frame on %s: %v", ifName, err)
+			}
+			continue
+		}
+		l.Table.Update(ifName, *neighbor)
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+// Package lldp receives and decodes LLDP (IEEE 802.1AB) frames sent by the
+// switches the host's ports are cabled to. It decodes the mandatory and
+// optional TLVs and the IEEE 802.1 and 802.3 organizationally specific
+// TLVs, and keeps a table of neighbors per interface that expire with
+// their time to live.
+package lldp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// EtherType is the ethertype of LLDP frames.
+const EtherType = 0x88cc
+
+// etherTypeVLAN is the ethertype of an 802.1Q tag.
+const etherTypeVLAN = 0x8100
+
+// MulticastAddress is the nearest-bridge destination of LLDP frames.
+var MulticastAddress = net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e}
+
+// TLV types.
+const (
+	tlvEnd                  = 0
+	tlvChassisID            = 1
+	tlvPortID               = 2
+	tlvTTL                  = 3
+	tlvPortDescription      = 4
+	tlvSystemName           = 5
+	tlvSystemDescription    = 6
+	tlvSystemCapabilities   = 7
+	tlvManagementAddress    = 8
+	tlvOrganizationSpecific = 127
+)
+
+// Organizationally unique identifiers of the organizationally specific TLVs.
+var (
+	oui8021 = [3]byte{0x00, 0x80, 0xc2}
+	oui8023 = [3]byte{0x00, 0x12, 0x0f}
+)
+
+// IEEE 802.1 TLV subtypes.
+const (
+	dot1PortVLANID      = 1
+	dot1VLANName        = 3
+	dot1LinkAggregation = 7
+	dot1PFCConfig       = 0x0b
+)
+
+// IEEE 802.3 TLV subtypes.
+const (
+	dot3MACPHY          = 1
+	dot3LinkAggregation = 3
+	dot3MaxFrameSize    = 4
+)
+
+// Chassis ID subtypes.
+var chassisIDSubtypes = map[byte]string{
+	1: "chassis_component",
+	2: "interface_alias",
+	3: "port_component",
+	4: "mac_address",
+	5: "network_address",
+	6: "interface_name",
+	7: "local",
+}
+
+// Port ID subtypes.
+var portIDSubtypes = map[byte]string{
+	1: "interface_alias",
+	2: "port_component",
+	3: "mac_address",
+	4: "network_address",
+	5: "interface_name",
+	6: "agent_circuit_id",
+	7: "local",
+}
+
+// systemCapabilities names the bits of the system capabilities TLV.
+var systemCapabilities = []string{
+	"other",
+	"repeater",
+	"bridge",
+	"wlan_access_point",
+	"router",
+	"telephone",
+	"docsis",
+	"station",
+	"customer_vlan",
+	"service_vlan",
+	"two_port_mac_relay",
+}
+
+// IANA address families used in management addresses and network address
+// IDs.
+const (
+	addressFamilyIPv4 = 1
+	addressFamilyIPv6 = 2
+	addressFamilyMAC  = 6
+)
+
+// ErrNotLLDP is returned for frames that are not LLDP frames.
+var ErrNotLLDP = errors.New("not an LLDP frame")
+
+// Neighbor is the device at the other end of a link, as described by its
+// LLDP frames.
+type Neighbor struct {
+	// SourceMAC is the source address of the frame (the switch port's MAC).
+	SourceMAC        string `json:"source_mac"`
+	ChassisID        string `json:"chassis_id"`
+	ChassisIDSubtype string `json:"chassis_id_subtype"`
+	PortID           string `json:"port_id"`
+	PortIDSubtype    string `json:"port_id_subtype"`
+	// TTL is how long the neighbor information is valid, in seconds.
+	// A TTL of 0 announces that the neighbor is shutting down.
+	TTL                 int      `json:"ttl"`
+	PortDescription     string   `json:"port_description,omitempty"`
+	SystemName          string   `json:"system_name,omitempty"`
+	SystemDescription   string   `json:"system_description,omitempty"`
+	Capabilities        []string `json:"capabilities,omitempty"`
+	EnabledCapabilities []string `json:"enabled_capabilities,omitempty"`
+	// ManagementAddresses are IPv4, IPv6 or MAC addresses.
+	ManagementAddresses []string `json:"management_addresses,omitempty"`
+
+	// PortVLANID is the untagged VLAN of the switch port (IEEE 802.1).
+	PortVLANID int    `json:"port_vlan_id,omitempty"`
+	VLANs      []VLAN `json:"vlans,omitempty"`
+	// PFC is the switch port's priority flow control configuration
+	// (IEEE 802.1Qaz DCBX).
+	PFC *PFC `json:"pfc,omitempty"`
+
+	// MACPHY is the switch port's autonegotiation and MAU type (IEEE 802.3).
+	MACPHY          *MACPHY          `json:"mac_phy,omitempty"`
+	LinkAggregation *LinkAggregation `json:"link_aggregation,omitempty"`
+	MaxFrameSize    int              `json:"max_frame_size,omitempty"`
+
+	// LastSeen is when the neighbor's last frame was received.
+	LastSeen time.Time `json:"last_seen,omitzero"`
+}
+
+// VLAN is a VLAN the switch port is a member of.
+type VLAN struct {
+	ID   int    `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// PFC is a priority flow control configuration.
+type PFC struct {
+	Willing bool `json:"willing"`
+	// Capability is the number of traffic classes that can be PFC enabled.
+	Capability int `json:"capability"`
+	// Priorities are the priorities (0-7) with PFC enabled.
+	Priorities []int `json:"priorities"`
+}
+
+// MACPHY is the MAC/PHY configuration of a port.
+type MACPHY struct {
+	AutonegSupported bool `json:"autoneg_supported"`
+	AutonegEnabled   bool `json:"autoneg_enabled"`
+	// MAUType is the operational MAU type (IANA ifMauType); 0 if unknown.
+	MAUType int `json:"mau_type,omitempty"`
+}
+
+// LinkAggregation is the link aggregation status of a port.
+type LinkAggregation struct {
+	Capable bool `json:"capable"`
+	Enabled bool `json:"enabled"`
+	// PortID is the ifIndex of the aggregated port, when enabled.
+	PortID int `json:"port_id,omitempty"`
+}
+
+// Parse decodes an Ethernet frame carrying an LLDPDU. Frames may carry
+// an 802.1Q tag. It returns ErrNotLLDP for other frames.
+func Parse(frame []byte) (*Neighbor, error) {
+	if len(frame) < 14 {
+		return nil, ErrNotLLDP
+	}
+	source := net.HardwareAddr(frame[6:12])
+	etherType := binary.BigEndian.Uint16(frame[12:14])
+	payload := frame[14:]
+	if etherType == etherTypeVLAN && len(frame) >= 18 {
+		etherType = binary.BigEndian.Uint16(frame[16:18])
+		payload = frame[18:]
+	}
+	if etherType != EtherType {
+		return nil, ErrNotLLDP
+	}
+
+	n, err := ParseLLDPDU(payload)
+	if err != nil {
+		return nil, err
+	}
+	n.SourceMAC = source.String()
+	return n, nil
+}
+
+// ParseLLDPDU decodes the TLVs of an LLDPDU. The chassis ID, port ID and
+// TTL TLVs are required, in that order.
+func ParseLLDPDU(data []byte) (*Neighbor, error) {
+	n := &Neighbor{}
+	for i := 0; len(data) > 0; i++ {
+		if len(data) < 2 {
+			return nil, fmt.Errorf("truncated TLV header")
+		}
+		header := binary.BigEndian.Uint16(data)
+		typ, length := int(header>>9), int(header&0x1ff)
+		if len(data) < 2+length {
+			return nil, fmt.Errorf("TLV type %d: length %d exceeds frame", typ, length)
+		}
+		value := data[2 : 2+length]
+		data = data[2+length:]
+
+		// The first three TLVs identify the neighbor
+		if i < 3 && typ != i+1 {
+			return nil, fmt.Errorf("TLV %d has type %d, want %d", i+1, typ, i+1)
+		}
+
+		switch typ {
+		case tlvEnd:
+			return n, nil
+		case tlvChassisID:
+			if length < 2 {
+				return nil, fmt.Errorf("chassis ID TLV too short")
+			}
+			n.ChassisIDSubtype = subtypeName(chassisIDSubtypes, value[0])
+			n.ChassisID = formatID(value[0] == 4, value[0] == 5, value[1:])
+		case tlvPortID:
+			if length < 2 {
+				return nil, fmt.Errorf("port ID TLV too short")
+			}
+			n.PortIDSubtype = subtypeName(portIDSubtypes, value[0])
+			n.PortID = formatID(value[0] == 3, value[0] == 4, value[1:])
+		case tlvTTL:
+			if length < 2 {
+				return nil, fmt.Errorf("TTL TLV too short")
+			}
+			n.TTL = int(binary.BigEndian.Uint16(value))
+		case tlvPortDescription:
+			n.PortDescription = tlvString(value)
+		case tlvSystemName:
+			n.SystemName = tlvString(value)
+		case tlvSystemDescription:
+			n.SystemDescription = tlvString(value)
+		case tlvSystemCapabilities:
+			if length >= 4 {
+				n.Capabilities = capabilityNames(binary.BigEndian.Uint16(value[0:2]))
+				n.EnabledCapabilities = capabilityNames(binary.BigEndian.Uint16(value[2:4]))
+			}
+		case tlvManagementAddress:
+			if addr, ok := parseManagementAddress(value); ok {
+				n.ManagementAddresses = append(n.ManagementAddresses, addr)
+			}
+		case tlvOrganizationSpecific:
+			if length >= 4 {
+				parseOrganizationSpecific(n, [3]byte(value[0:3]), value[3], value[4:])
+			}
+		}
+	}
+
+	if n.ChassisID == "" {
+		return nil, fmt.Errorf("LLDPDU without chassis ID")
+	}
+	// The end TLV is optional in LLDP-MED and some implementations omit it
+	return n, nil
+}
+
+// parseManagementAddress decodes a management address TLV value:
+// address string length, address subtype, address, interface numbering
+// and OID.
+func parseManagementAddress(value []byte) (string, bool) {
+	if len(value) < 2 {
+		return "", false
+	}
+	length := int(value[0])
+	if length < 2 || len(value) < 1+length {
+		return "", false
+	}
+	return formatNetworkAddress(value[1 : 1+length]), true
+}
+
+// parseOrganizationSpecific decodes the IEEE 802.1 and 802.3 TLVs the
+// agent reports. Other organizations' TLVs are ignored.
+func parseOrganizationSpecific(n *Neighbor, oui [3]byte, subtype byte, value []byte) {
+	switch {
+	case oui == oui8021 && subtype == dot1PortVLANID && len(value) >= 2:
+		n.PortVLANID = int(binary.BigEndian.Uint16(value))
+	case oui == oui8021 && subtype == dot1VLANName && len(value) >= 3:
+		nameLen := int(value[2])
+		if len(value) < 3+nameLen {
+			return
+		}
+		n.VLANs = append(n.VLANs, VLAN{
+			ID:   int(binary.BigEndian.Uint16(value)),
+			Name: tlvString(value[3 : 3+nameLen]),
+		})
+	case oui == oui8021 && subtype == dot1PFCConfig && len(value) >= 2:
+		pfc := &PFC{
+			Willing:    value[0]&0x80 != 0,
+			Capability: int(value[0] & 0x0f),
+			Priorities: []int{},
+		}
+		for prio := 0; prio < 8; prio++ {
+			if value[1]&(1<<prio) != 0 {
+				pfc.Priorities = append(pfc.Priorities, prio)
+			}
+		}
+		n.PFC = pfc
+	case ((oui == oui8021 && subtype == dot1LinkAggregation) || (oui == oui8023 && subtype == dot3LinkAggregation)) && len(value) >= 5:
+		n.LinkAggregation = &LinkAggregation{
+			Capable: value[0]&0x01 != 0,
+			Enabled: value[0]&0x02 != 0,
+			PortID:  int(binary.BigEndian.Uint32(value[1:5])),
+		}
+	case oui == oui8023 && subtype == dot3MACPHY && len(value) >= 5:
+		n.MACPHY = &MACPHY{
+			AutonegSupported: value[0]&0x01 != 0,
+			AutonegEnabled:   value[0]&0x02 != 0,
+			MAUType:          int(binary.BigEndian.Uint16(value[3:5])),
+		}
+	case oui == oui8023 && subtype == dot3MaxFrameSize && len(value) >= 2:
+		n.MaxFrameSize = int(binary.BigEndian.Uint16(value))
+	}
+}
+
+// formatID formats a chassis or port ID: MAC addresses and network
+// addresses are formatted, other subtypes are strings.
+func formatID(isMAC, isNetworkAddress bool, id []byte) string {
+	switch {
+	case isMAC && len(id) == 6:
+		return net.HardwareAddr(id).String()
+	case isNetworkAddress:
+		return formatNetworkAddress(id)
+	}
+	return tlvString(id)
+}
+
+// formatNetworkAddress formats an address prefixed by its IANA address
+// family.
+func formatNetworkAddress(b []byte) string {
+	if len(b) < 2 {
+		return ""
+	}
+	family, addr := b[0], b[1:]
+	switch {
+	case family == addressFamilyIPv4 && len(addr) == net.IPv4len,
+		family == addressFamilyIPv6 && len(addr) == net.IPv6len:
+		return net.IP(addr).String()
+	case family == addressFamilyMAC && len(addr) == 6:
+		return net.HardwareAddr(addr).String()
+	}
+	return fmt.Sprintf("%x", addr)
+}
+
+// subtypeName names a chassis or port ID subtype.
+func subtypeName(names map[byte]string, subtype byte) string {
+	if name, ok := names[subtype]; ok {
+		return name
+	}
+	return fmt.Sprintf("reserved(%d)", subtype)
+}
+
+// capabilityNames decodes a system capabilities bitmap.
+func capabilityNames(bits uint16) []string {
+	var names []string
+	for i, name := range systemCapabilities {
+		if bits&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// tlvString returns a TLV string value without trailing NULs and spaces.
+func tlvString(b []byte) string {
+	return strings.TrimRight(string(b), "\x00 ")
+}
@@ -0,0 +1,121 @@
+package lldp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// loadFrame reads a captured frame: hex bytes after "#" comment lines.
+func loadFrame(t *testing.T, path string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hexData strings.Builder
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			hexData.WriteString(strings.Join(strings.Fields(line), ""))
+		}
+	}
+	frame, err := hex.DecodeString(hexData.String())
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return frame
+}
+
+func TestParse_Golden(t *testing.T) {
+	frames, err := filepath.Glob("testdata/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) == 0 {
+		t.Fatal("no LLDP frames in testdata")
+	}
+
+	for _, path := range frames {
+		name := strings.TrimSuffix(filepath.Base(path), ".txt")
+		t.Run(name, func(t *testing.T) {
+			neighbor, err := Parse(loadFrame(t, path))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			got, err := json.MarshalIndent(neighbor, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("parsed neighbor does not match %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestParse_VLANTagged(t *testing.T) {
+	frame := loadFrame(t, filepath.Join("testdata", "arista_eos.txt"))
+
+	// Insert an 802.1Q tag for VLAN 100 after the source address
+	tagged := append([]byte{}, frame[:12]...)
+	tagged = append(tagged, 0x81, 0x00, 0x00, 0x64)
+	tagged = append(tagged, frame[12:]...)
+
+	neighbor, err := Parse(tagged)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if neighbor.SystemName != "leaf-r12-a.dc1" || neighbor.PortID != "Ethernet12/1" {
+		t.Errorf("Parse() = %+v", neighbor)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	frame := loadFrame(t, filepath.Join("testdata", "arista_eos.txt"))
+
+	// Not LLDP (IPv4)
+	ipv4 := append([]byte{}, frame...)
+	ipv4[12], ipv4[13] = 0x08, 0x00
+	if _, err := Parse(ipv4); !errors.Is(err, ErrNotLLDP) {
+		t.Errorf("Parse(IPv4) error = %v, want ErrNotLLDP", err)
+	}
+	if _, err := Parse(frame[:10]); !errors.Is(err, ErrNotLLDP) {
+		t.Errorf("Parse(short frame) error = %v, want ErrNotLLDP", err)
+	}
+
+	// Truncated in the middle of the system description
+	if _, err := Parse(frame[:80]); err == nil || errors.Is(err, ErrNotLLDP) {
+		t.Errorf("Parse(truncated) error = %v, want TLV error", err)
+	}
+
+	// Port ID before chassis ID
+	swapped := append([]byte{}, frame[:14]...)
+	swapped = append(swapped, 0x04, 0x03, 0x05, 'x', 'y')
+	swapped = append(swapped, frame[14:]...)
+	if _, err := Parse(swapped); err == nil {
+		t.Error("Parse() expected error when the first TLV is not the chassis ID")
+	}
+}
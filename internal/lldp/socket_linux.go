@@ -0,0 +1,121 @@
+//go:build linux
+
+package lldp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+// receiveTimeout bounds how long a receive blocks, so listeners notice
+// cancellation.
+const receiveTimeout = time.Second
+
+// filter is a classic BPF program that accepts LLDP frames, untagged or
+// with one 802.1Q tag, and drops everything else in the kernel.
+var filter = []syscall.SockFilter{
+	// ldh [12]
+	{Code: syscall.BPF_LD | syscall.BPF_H | syscall.BPF_ABS, K: 12},
+	// jeq #0x88cc, accept
+	{Code: syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, Jt: 3, Jf: 0, K: EtherType},
+	// jeq #0x8100, tagged, drop
+	{Code: syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, Jt: 0, Jf: 3, K: etherTypeVLAN},
+	// tagged: ldh [16]
+	{Code: syscall.BPF_LD | syscall.BPF_H | syscall.BPF_ABS, K: 16},
+	// jeq #0x88cc, accept, drop
+	{Code: syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, Jt: 0, Jf: 1, K: EtherType},
+	// accept: ret #65535
+	{Code: syscall.BPF_RET | syscall.BPF_K, K: 0xffff},
+	// drop: ret #0
+	{Code: syscall.BPF_RET | syscall.BPF_K, K: 0},
+}
+
+// socket is an AF_PACKET socket that receives the LLDP frames arriving on
+// one interface.
+type socket struct {
+	fd int
+}
+
+// openSocket opens a packet socket on ifName, filtered to LLDP frames and
+// subscribed to the LLDP multicast address.
+func openSocket(ifName string) (*socket, error) {
+	iface, err := net.InterfaceByName(ifName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Protocol 0 receives nothing until the socket is bound, so no frames
+	// arrive before the filter is attached
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open packet socket: %w", err)
+	}
+	s := &socket{fd: fd}
+
+	if err := syscall.AttachLsf(fd, filter); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to attach LLDP filter: %w", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrLinklayer{Protocol: htons(syscall.ETH_P_ALL), Ifindex: iface.Index}); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to bind packet socket to %s: %w", ifName, err)
+	}
+
+	// struct packet_mreq
+	mreq := make([]byte, 16)
+	binary.NativeEndian.PutUint32(mreq[0:], uint32(iface.Index))
+	binary.NativeEndian.PutUint16(mreq[4:], syscall.PACKET_MR_MULTICAST)
+	binary.NativeEndian.PutUint16(mreq[6:], uint16(len(MulticastAddress)))
+	copy(mreq[8:], MulticastAddress)
+	if err := syscall.SetsockoptString(fd, syscall.SOL_PACKET, syscall.PACKET_ADD_MEMBERSHIP, string(mreq)); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to join LLDP multicast group on %s: %w", ifName, err)
+	}
+
+	tv := syscall.NsecToTimeval(receiveTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to set receive timeout: %w", err)
+	}
+
+	return s, nil
+}
+
+// receive reads one frame into buf. It returns 0 without an error when
+// no frame arrived within the receive timeout, or when the frame was sent
+// by this host: the socket sees outgoing frames too, such as the LLDPDUs of
+// lldpad or the NIC firmware's LLDP agent, which are not neighbors.
+func (s *socket) receive(buf []byte) (int, error) {
+	n, from, err := syscall.Recvfrom(s.fd, buf, 0)
+	if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if outgoing(from) {
+		return 0, nil
+	}
+	return n, nil
+}
+
+// outgoing reports whether a frame's source address marks it as sent by
+// this host.
+func outgoing(from syscall.Sockaddr) bool {
+	ll, ok := from.(*syscall.SockaddrLinklayer)
+	return ok && ll.Pkttype == syscall.PACKET_OUTGOING
+}
+
+// close closes the socket.
+func (s *socket) close() error {
+	return syscall.Close(s.fd)
+}
+
+// htons converts a 16-bit value to network byte order.
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
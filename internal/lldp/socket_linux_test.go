@@ -0,0 +1,27 @@
+package lldp
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestOutgoing(t *testing.T) {
+	tests := []struct {
+		name string
+		from syscall.Sockaddr
+		want bool
+	}{
+		{name: "sent by this host", from: &syscall.SockaddrLinklayer{Pkttype: syscall.PACKET_OUTGOING}, want: true},
+		{name: "multicast from a neighbor", from: &syscall.SockaddrLinklayer{Pkttype: syscall.PACKET_MULTICAST}},
+		{name: "addressed to this host", from: &syscall.SockaddrLinklayer{Pkttype: syscall.PACKET_HOST}},
+		{name: "no address", from: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := outgoing(tt.from); got != tt.want {
+				t.Errorf("outgoing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
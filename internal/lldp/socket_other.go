@@ -0,0 +1,26 @@
+//go:build !linux
+
+package lldp
+
+import "errors"
+
+// errNotSupported is returned on platforms without AF_PACKET sockets.
+var errNotSupported = errors.New("LLDP listening is only supported on Linux")
+
+// socket is not implemented on this platform.
+type socket struct{}
+
+// openSocket returns errNotSupported on this platform.
+func openSocket(ifName string) (*socket, error) {
+	return nil, errNotSupported
+}
+
+// receive returns errNotSupported on this platform.
+func (s *socket) receive(buf []byte) (int, error) {
+	return 0, errNotSupported
+}
+
+// close does nothing on this platform.
+func (s *socket) close() error {
+	return nil
+}
@@ -0,0 +1,76 @@
+package lldp
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Table holds the neighbors seen on each interface. A neighbor is
+// identified by its chassis ID and port ID and expires when its TTL
+// elapses without a new frame. It is safe for concurrent use.
+type Table struct {
+	mu        sync.Mutex
+	neighbors map[string]map[neighborKey]Neighbor
+	now       func() time.Time
+}
+
+// neighborKey is the MSAP identifier of a neighbor.
+type neighborKey struct {
+	chassisID string
+	portID    string
+}
+
+// NewTable creates an empty neighbor table. A nil now uses time.Now.
+func NewTable(now func() time.Time) *Table {
+	if now == nil {
+		now = time.Now
+	}
+	return &Table{
+		neighbors: make(map[string]map[neighborKey]Neighbor),
+		now:       now,
+	}
+}
+
+// Update records a neighbor seen on ifName. A TTL of 0 removes it.
+func (t *Table) Update(ifName string, n Neighbor) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := neighborKey{chassisID: n.ChassisID, portID: n.PortID}
+	if n.TTL == 0 {
+		delete(t.neighbors[ifName], key)
+		return
+	}
+
+	if t.neighbors[ifName] == nil {
+		t.neighbors[ifName] = make(map[neighborKey]Neighbor)
+	}
+	n.LastSeen = t.now().UTC()
+	t.neighbors[ifName][key] = n
+}
+
+// Neighbors returns the neighbors seen on ifName whose TTL has not
+// expired, sorted by chassis ID and port ID. Expired neighbors are removed.
+func (t *Table) Neighbors(ifName string) []Neighbor {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	var neighbors []Neighbor
+	for key, n := range t.neighbors[ifName] {
+		if now.After(n.LastSeen.Add(time.Duration(n.TTL) * time.Second)) {
+			delete(t.neighbors[ifName], key)
+			continue
+		}
+		neighbors = append(neighbors, n)
+	}
+
+	sort.Slice(neighbors, func(i, j int) bool {
+		if neighbors[i].ChassisID != neighbors[j].ChassisID {
+			return neighbors[i].ChassisID < neighbors[j].ChassisID
+		}
+		return neighbors[i].PortID < neighbors[j].PortID
+	})
+	return neighbors
+}
@@ -0,0 +1,46 @@
+package lldp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTable(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	table := NewTable(func() time.Time { return now })
+
+	leaf := Neighbor{ChassisID: "1c:34:da:5c:a1:00", PortID: "swp1", TTL: 120, SystemName: "leaf01"}
+	spine := Neighbor{ChassisID: "28:99:3a:4d:12:00", PortID: "Ethernet12/1", TTL: 30}
+	table.Update("ens1f0np0", spine)
+	table.Update("ens1f0np0", leaf)
+
+	got := table.Neighbors("ens1f0np0")
+	if len(got) != 2 || got[0].PortID != "swp1" || got[1].PortID != "Ethernet12/1" {
+		t.Fatalf("Neighbors() = %+v, want leaf01 then the Arista", got)
+	}
+	if !got[0].LastSeen.Equal(now) {
+		t.Errorf("LastSeen = %v, want %v", got[0].LastSeen, now)
+	}
+	if n := table.Neighbors("ens1f1np1"); len(n) != 0 {
+		t.Errorf("Neighbors(ens1f1np1) = %+v, want none", n)
+	}
+
+	// A new frame from the same port replaces the entry
+	now = now.Add(20 * time.Second)
+	leaf.SystemName = "leaf01.dc1"
+	table.Update("ens1f0np0", leaf)
+
+	// The Arista's TTL of 30 seconds expires
+	now = now.Add(11 * time.Second)
+	got = table.Neighbors("ens1f0np0")
+	if len(got) != 1 || got[0].SystemName != "leaf01.dc1" {
+		t.Fatalf("Neighbors() after expiry = %+v, want only leaf01.dc1", got)
+	}
+
+	// A shutdown LLDPDU removes the neighbor immediately
+	leaf.TTL = 0
+	table.Update("ens1f0np0", leaf)
+	if n := table.Neighbors("ens1f0np0"); len(n) != 0 {
+		t.Errorf("Neighbors() after shutdown = %+v, want none", n)
+	}
+}
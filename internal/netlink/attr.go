@@ -0,0 +1,147 @@
+package netlink
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Attribute header flags.
+const (
+	attrNested       = 0x8000
+	attrNetByteorder = 0x4000
+	attrTypeMask     = ^uint16(attrNested | attrNetByteorder)
+	attrHeaderLen    = 4
+)
+
+// Attribute is a netlink attribute (struct nlattr and its payload).
+type Attribute struct {
+	// Type is the attribute type with the nested and byte-order flags cleared.
+	Type uint16
+	Data []byte
+}
+
+// align rounds n up to the netlink 4-byte alignment.
+func align(n int) int {
+	return (n + 3) &^ 3
+}
+
+// ParseAttributes parses a sequence of netlink attributes.
+func ParseAttributes(b []byte) ([]Attribute, error) {
+	var attrs []Attribute
+	for len(b) >= attrHeaderLen {
+		length := int(binary.NativeEndian.Uint16(b[0:2]))
+		typ := binary.NativeEndian.Uint16(b[2:4])
+		if length < attrHeaderLen || length > len(b) {
+			return nil, fmt.Errorf("invalid attribute length %d (%d bytes left)", length, len(b))
+		}
+
+		attrs = append(attrs, Attribute{
+			Type: typ & attrTypeMask,
+			Data: b[attrHeaderLen:length],
+		})
+
+		next := align(length)
+		if next > len(b) {
+			break
+		}
+		b = b[next:]
+	}
+	return attrs, nil
+}
+
+// Uint8 returns the attribute payload as a uint8.
+func (a Attribute) Uint8() uint8 {
+	if len(a.Data) < 1 {
+		return 0
+	}
+	return a.Data[0]
+}
+
+// Uint16 returns the attribute payload as a native-endian uint16.
+func (a Attribute) Uint16() uint16 {
+	if len(a.Data) < 2 {
+		return 0
+	}
+	return binary.NativeEndian.Uint16(a.Data)
+}
+
+// Uint32 returns the attribute payload as a native-endian uint32.
+func (a Attribute) Uint32() uint32 {
+	if len(a.Data) < 4 {
+		return 0
+	}
+	return binary.NativeEndian.Uint32(a.Data)
+}
+
+// Uint64 returns the attribute payload as a native-endian uint64.
+func (a Attribute) Uint64() uint64 {
+	if len(a.Data) < 8 {
+		return 0
+	}
+	return binary.NativeEndian.Uint64(a.Data)
+}
+
+// String returns the attribute payload as a NUL-terminated string.
+func (a Attribute) String() string {
+	data := a.Data
+	for i, c := range data {
+		if c == 0 {
+			data = data[:i]
+			break
+		}
+	}
+	return string(data)
+}
+
+// AttributeEncoder builds a sequence of netlink attributes.
+type AttributeEncoder struct {
+	buf []byte
+}
+
+// Bytes appends an attribute with a raw payload.
+func (e *AttributeEncoder) Bytes(typ uint16, data []byte) {
+	length := attrHeaderLen + len(data)
+	header := make([]byte, attrHeaderLen)
+	binary.NativeEndian.PutUint16(header[0:2], uint16(length))
+	binary.NativeEndian.PutUint16(header[2:4], typ)
+
+	e.buf = append(e.buf, header...)
+	e.buf = append(e.buf, data...)
+	e.buf = append(e.buf, make([]byte, align(length)-length)...)
+}
+
+// Uint8 appends a uint8 attribute.
+func (e *AttributeEncoder) Uint8(typ uint16, v uint8) {
+	e.Bytes(typ, []byte{v})
+}
+
+// Uint16 appends a native-endian uint16 attribute.
+func (e *AttributeEncoder) Uint16(typ uint16, v uint16) {
+	b := make([]byte, 2)
+	binary.NativeEndian.PutUint16(b, v)
+	e.Bytes(typ, b)
+}
+
+// Uint32 appends a native-endian uint32 attribute.
+func (e *AttributeEncoder) Uint32(typ uint16, v uint32) {
+	b := make([]byte, 4)
+	binary.NativeEndian.PutUint32(b, v)
+	e.Bytes(typ, b)
+}
+
+// String appends a NUL-terminated string attribute.
+func (e *AttributeEncoder) String(typ uint16, s string) {
+	e.Bytes(typ, append([]byte(s), 0))
+}
+
+// Nested appends a nested attribute whose payload is built by fn.
+func (e *AttributeEncoder) Nested(typ uint16, fn func(*AttributeEncoder)) {
+	var nested AttributeEncoder
+	fn(&nested)
+	e.Bytes(typ|attrNested, nested.Encode())
+}
+
+// Encode returns the encoded attributes.
+func (e *AttributeEncoder) Encode() []byte {
+	return e.buf
+}
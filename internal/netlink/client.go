@@ -0,0 +1,228 @@
+package netlink
+
+import (
+	"fmt"
+)
+
+// Client is the set of netlink queries the agent performs. It is an
+// interface so collectors can be tested against recorded replies.
+type Client interface {
+	// LinkVFs returns the SR-IOV VF configuration of a PF netdev.
+	LinkVFs(ifName string) ([]VFInfo, error)
+	// LinkInfo returns the link type of a netdev and its type-specific
+	// settings (VLAN ID, macvlan mode, IPoIB P_Key).
+	LinkInfo(ifName string) (*LinkInfo, error)
+	// DevlinkEswitch returns the e-switch configuration of a PCI devlink
+	// device (e.g., "0000:3b:00.0").
+	DevlinkEswitch(device string) (*DevlinkEswitch, error)
+	// DevlinkPorts returns the ports of a PCI devlink device.
+	DevlinkPorts(device string) ([]DevlinkPort, error)
+	// DevlinkHealthReporters returns the health reporters of a PCI devlink
+	// device and its ports.
+	DevlinkHealthReporters(device string) ([]DevlinkHealthReporter, error)
+	// ModuleEEPROM reads a page range of the transceiver module EEPROM
+	// plugged into a netdev's port.
+	ModuleEEPROM(ifName string, req EEPROMRequest) ([]byte, error)
+	// LinkModes returns the speed, duplex, autonegotiation and link modes
+	// of a netdev.
+	LinkModes(ifName string) (*LinkModes, error)
+	// FEC returns the forward error correction settings of a netdev.
+	FEC(ifName string) (*FECSettings, error)
+	// Channels returns the RX, TX and combined channel counts of a netdev.
+	Channels(ifName string) (*Channels, error)
+	// EthtoolStats returns the driver statistics of a netdev by name, as
+	// shown by `ethtool -S`.
+	EthtoolStats(ifName string) (map[string]uint64, error)
+	// DriverInfo returns the driver and firmware of a netdev, as shown by
+	// `ethtool -i`.
+	DriverInfo(ifName string) (*DriverInfo, error)
+}
+
+// System is a Client backed by the kernel. Each query opens its own socket.
+type System struct{}
+
+// LinkVFs implements Client.
+func (System) LinkVFs(ifName string) ([]VFInfo, error) {
+	conn, err := Dial(FamilyRoute)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	msgs, err := conn.Execute(getLinkRequest(ifName, rtextFilterVF))
+	if err != nil {
+		return nil, err
+	}
+
+	var vfs []VFInfo
+	for _, msg := range msgs {
+		parsed, err := parseLinkVFs(msg)
+		if err != nil {
+			return nil, err
+		}
+		vfs = append(vfs, parsed...)
+	}
+	return vfs, nil
+}
+
+// LinkInfo implements Client.
+func (System) LinkInfo(ifName string) (*LinkInfo, error) {
+	conn, err := Dial(FamilyRoute)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	msgs, err := conn.Execute(getLinkRequest(ifName, 0))
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, fmt.Errorf("no link reply for %s", ifName)
+	}
+	return parseLinkInfo(msgs[0])
+}
+
+// DevlinkEswitch implements Client.
+func (System) DevlinkEswitch(device string) (*DevlinkEswitch, error) {
+	replies, err := devlinkQuery(device, devlinkCmdEswitchGet, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(replies) == 0 {
+		return nil, fmt.Errorf("no eswitch reply for %s", device)
+	}
+	return parseDevlinkEswitch(replies[0]), nil
+}
+
+// DevlinkPorts implements Client.
+func (System) DevlinkPorts(device string) ([]DevlinkPort, error) {
+	replies, err := devlinkQuery(device, devlinkCmdPortGet, FlagDump)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := make([]DevlinkPort, 0, len(replies))
+	for _, attrs := range replies {
+		ports = append(ports, parseDevlinkPort(attrs))
+	}
+	return ports, nil
+}
+
+// DevlinkHealthReporters implements Client.
+func (System) DevlinkHealthReporters(device string) ([]DevlinkHealthReporter, error) {
+	replies, err := devlinkQuery(device, devlinkCmdHealthReporterGet, FlagDump)
+	if err != nil {
+		return nil, err
+	}
+
+	var reporters []DevlinkHealthReporter
+	for _, attrs := range replies {
+		if reporter, ok := parseDevlinkHealthReporter(attrs); ok {
+			reporters = append(reporters, reporter)
+		}
+	}
+	return reporters, nil
+}
+
+// ModuleEEPROM implements Client. On kernels without
+// ETHTOOL_MSG_MODULE_EEPROM_GET, the EEPROM is read with the
+// ETHTOOL_GMODULEEEPROM ioctl.
+func (System) ModuleEEPROM(ifName string, req EEPROMRequest) ([]byte, error) {
+	msg, err := ethtoolQuery(ifName, func(family uint16) Message {
+		return moduleEEPROMRequest(family, ifName, req)
+	})
+	if IsNotSupported(err) {
+		return ethtoolModuleEEPROM(ifName, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseModuleEEPROM(msg)
+}
+
+// LinkModes implements Client. Without ethtool netlink, the link settings
+// are read with the ETHTOOL_GLINKSETTINGS ioctl.
+func (System) LinkModes(ifName string) (*LinkModes, error) {
+	msg, err := ethtoolQuery(ifName, func(family uint16) Message {
+		return ethtoolRequest(family, ethtoolMsgLinkModesGet, ifName, nil)
+	})
+	if IsNotSupported(err) {
+		return ethtoolLinkSettings(ifName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := genlAttributes(msg)
+	if err != nil {
+		return nil, err
+	}
+	return parseLinkModes(attrs)
+}
+
+// FEC implements Client. Without ethtool netlink, the FEC settings are
+// read with the ETHTOOL_GFECPARAM ioctl.
+func (System) FEC(ifName string) (*FECSettings, error) {
+	msg, err := ethtoolQuery(ifName, func(family uint16) Message {
+		return ethtoolRequest(family, ethtoolMsgFECGet, ifName, nil)
+	})
+	if IsNotSupported(err) {
+		return ethtoolFECParam(ifName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := genlAttributes(msg)
+	if err != nil {
+		return nil, err
+	}
+	return parseFEC(attrs)
+}
+
+// Channels implements Client. Without ethtool netlink, the channel counts
+// are read with the ETHTOOL_GCHANNELS ioctl.
+func (System) Channels(ifName string) (*Channels, error) {
+	msg, err := ethtoolQuery(ifName, func(family uint16) Message {
+		return ethtoolRequest(family, ethtoolMsgChannelsGet, ifName, nil)
+	})
+	if IsNotSupported(err) {
+		return ethtoolChannels(ifName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := genlAttributes(msg)
+	if err != nil {
+		return nil, err
+	}
+	return parseChannels(attrs), nil
+}
+
+// ethtoolQuery sends the ethtool request built for the resolved family
+// and returns the reply.
+func ethtoolQuery(ifName string, request func(family uint16) Message) (Message, error) {
+	conn, family, err := dialGeneric(ethtoolFamilyName)
+	if err != nil {
+		return Message{}, err
+	}
+	defer conn.Close()
+
+	msgs, err := conn.Execute(request(family))
+	if err != nil {
+		return Message{}, err
+	}
+	if len(msgs) == 0 {
+		return Message{}, fmt.Errorf("no ethtool reply for %s", ifName)
+	}
+	return msgs[0], nil
+}
+
+// EthtoolStats implements Client.
+func (System) EthtoolStats(ifName string) (map[string]uint64, error) {
+	return ethtoolStats(ifName)
+}
+
+// DriverInfo implements Client.
+func (System) DriverInfo(ifName string) (*DriverInfo, error) {
+	return ethtoolDriverInfo(ifName)
+}
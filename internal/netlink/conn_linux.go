@@ -0,0 +1,108 @@
+//go:build linux
+
+package netlink
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// receiveTimeout bounds how long Execute waits for a reply.
+const receiveTimeout = 5 * time.Second
+
+// Conn is a netlink socket.
+type Conn struct {
+	fd  int
+	seq uint32
+}
+
+// Dial opens a netlink socket for a protocol family (e.g., NETLINK_ROUTE).
+func Dial(family int) (*Conn, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, family)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netlink socket: %w", err)
+	}
+
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to bind netlink socket: %w", err)
+	}
+
+	tv := syscall.NsecToTimeval(receiveTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to set netlink receive timeout: %w", err)
+	}
+
+	return &Conn{fd: fd, seq: uint32(time.Now().Unix())}, nil
+}
+
+// Close closes the socket.
+func (c *Conn) Close() error {
+	return syscall.Close(c.fd)
+}
+
+// Execute sends a request and returns the reply messages. Dump replies are
+// collected until NLMSG_DONE; an NLMSG_ERROR reply is returned as *Error.
+func (c *Conn) Execute(m Message) ([]Message, error) {
+	c.seq++
+	m.Header.Sequence = c.seq
+	m.Header.Flags |= FlagRequest
+
+	if err := syscall.Sendto(c.fd, m.marshal(), 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("failed to send netlink request: %w", err)
+	}
+
+	var replies []Message
+	for {
+		msgs, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, msg := range msgs {
+			if msg.Header.Sequence != c.seq {
+				continue
+			}
+			switch msg.Header.Type {
+			case msgNoop:
+				continue
+			case msgOverrun:
+				return nil, fmt.Errorf("netlink receive buffer overrun")
+			case msgDone:
+				return replies, nil
+			case msgError:
+				if err := checkError(msg); err != nil {
+					return nil, err
+				}
+				return replies, nil
+			}
+
+			replies = append(replies, msg)
+			if msg.Header.Flags&FlagMulti == 0 {
+				return replies, nil
+			}
+		}
+	}
+}
+
+// receive reads one datagram, sizing the buffer to the pending message so
+// large replies (e.g., links with many VFs) are not truncated.
+func (c *Conn) receive() ([]Message, error) {
+	buf := make([]byte, os.Getpagesize())
+	n, _, err := syscall.Recvfrom(c.fd, buf, syscall.MSG_PEEK|syscall.MSG_TRUNC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive netlink reply: %w", err)
+	}
+	if n > len(buf) {
+		buf = make([]byte, n)
+	}
+
+	n, _, err = syscall.Recvfrom(c.fd, buf, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive netlink reply: %w", err)
+	}
+	return parseMessages(buf[:n])
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package netlink
+
+// Conn is a netlink socket. Netlink is only available on Linux.
+type Conn struct{}
+
+// Dial returns ErrNotSupported on this platform.
+func Dial(family int) (*Conn, error) {
+	return nil, ErrNotSupported
+}
+
+// Close is a no-op on this platform.
+func (c *Conn) Close() error {
+	return nil
+}
+
+// Execute returns ErrNotSupported on this platform.
+func (c *Conn) Execute(m Message) ([]Message, error) {
+	return nil, ErrNotSupported
+}
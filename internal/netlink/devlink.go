@@ -0,0 +1,266 @@
+package netlink
+
+import (
+	"time"
+)
+
+// devlink commands and attributes (linux/devlink.h).
+const (
+	devlinkFamilyName  = "devlink"
+	devlinkGenlVersion = 1
+	devlinkBusPCI      = "pci"
+
+	devlinkCmdPortGet           = 5
+	devlinkCmdEswitchGet        = 29
+	devlinkCmdHealthReporterGet = 52
+
+	devlinkAttrBusName           = 1
+	devlinkAttrDevName           = 2
+	devlinkAttrPortIndex         = 3
+	devlinkAttrPortType          = 4
+	devlinkAttrPortNetdevName    = 7
+	devlinkAttrPortIBDevName     = 8
+	devlinkAttrEswitchMode       = 25
+	devlinkAttrEswitchInlineMode = 26
+	devlinkAttrEswitchEncapMode  = 62
+	devlinkAttrPortFlavour       = 77
+	devlinkAttrPortNumber        = 78
+	devlinkAttrPortPCIPFNumber   = 127
+	devlinkAttrPortPCIVFNumber   = 128
+	devlinkAttrPortExternal      = 149
+	devlinkAttrPortController    = 150
+	devlinkAttrPortPCISFNumber   = 164
+
+	devlinkAttrHealthReporter               = 114
+	devlinkAttrHealthReporterName           = 115
+	devlinkAttrHealthReporterState          = 116
+	devlinkAttrHealthReporterErrCount       = 117
+	devlinkAttrHealthReporterRecoverCount   = 118
+	devlinkAttrHealthReporterGracefulPeriod = 120
+	devlinkAttrHealthReporterAutoRecover    = 121
+	devlinkAttrHealthReporterDumpTSNs       = 137
+	devlinkAttrHealthReporterAutoDump       = 141
+)
+
+// Names used by the devlink tool for enum values.
+var (
+	devlinkEswitchModes = map[uint16]string{0: "legacy", 1: "switchdev"}
+	devlinkInlineModes  = map[uint8]string{0: "none", 1: "link", 2: "network", 3: "transport"}
+	devlinkEncapModes   = map[uint8]string{0: "none", 1: "basic"}
+	devlinkPortTypes    = map[uint16]string{0: "notset", 1: "auto", 2: "eth", 3: "ib"}
+	devlinkPortFlavours = map[uint16]string{
+		0: "physical",
+		1: "cpu",
+		2: "dsa",
+		3: "pcipf",
+		4: "pcivf",
+		5: "virtual",
+		6: "unused",
+		7: "pcisf",
+	}
+	devlinkHealthStates = map[uint8]string{0: "healthy", 1: "error"}
+)
+
+// DevlinkEswitch is the e-switch configuration of a devlink device.
+type DevlinkEswitch struct {
+	// Mode is "legacy" or "switchdev".
+	Mode       string `json:"mode"`
+	InlineMode string `json:"inline_mode,omitempty"`
+	EncapMode  string `json:"encap_mode,omitempty"`
+}
+
+// DevlinkPort is a port of a devlink device: a physical port, or a PF, VF
+// or SF representor in switchdev mode.
+type DevlinkPort struct {
+	Index   uint32 `json:"index"`
+	Type    string `json:"type,omitempty"`
+	Flavour string `json:"flavour,omitempty"`
+	// Netdev is the port's netdev; for PF/VF/SF flavours, the representor.
+	Netdev string `json:"netdev,omitempty"`
+	IBDev  string `json:"ibdev,omitempty"`
+	// Number is the physical port number; the PF, VF and SF numbers
+	// identify the function a representor stands for.
+	Number     *int `json:"number,omitempty"`
+	Controller *int `json:"controller,omitempty"`
+	PFNumber   *int `json:"pf_number,omitempty"`
+	VFNumber   *int `json:"vf_number,omitempty"`
+	SFNumber   *int `json:"sf_number,omitempty"`
+	External   bool `json:"external,omitempty"`
+}
+
+// DevlinkHealthReporter is a devlink health reporter (e.g., fw, fw_fatal,
+// tx, rx) and its error history.
+type DevlinkHealthReporter struct {
+	Name string `json:"name"`
+	// PortIndex is set for reporters that belong to a port.
+	PortIndex    *uint32 `json:"port_index,omitempty"`
+	State        string  `json:"state"`
+	ErrorCount   uint64  `json:"error_count"`
+	RecoverCount uint64  `json:"recover_count"`
+	// GracefulPeriod is the minimum time between recoveries in milliseconds.
+	GracefulPeriod uint64 `json:"graceful_period_ms,omitempty"`
+	AutoRecover    bool   `json:"auto_recover"`
+	AutoDump       bool   `json:"auto_dump"`
+	// LastDump is the time of the last stored dump, if any.
+	LastDump *time.Time `json:"last_dump,omitempty"`
+}
+
+// devlinkHandle encodes the bus/device attributes identifying a device.
+func devlinkHandle(attrs *AttributeEncoder, device string) {
+	attrs.String(devlinkAttrBusName, devlinkBusPCI)
+	attrs.String(devlinkAttrDevName, device)
+}
+
+// matchesDevlinkHandle reports whether a message belongs to a device.
+func matchesDevlinkHandle(attrs []Attribute, device string) bool {
+	var bus, dev string
+	for _, attr := range attrs {
+		switch attr.Type {
+		case devlinkAttrBusName:
+			bus = attr.String()
+		case devlinkAttrDevName:
+			dev = attr.String()
+		}
+	}
+	return bus == devlinkBusPCI && dev == device
+}
+
+// parseDevlinkEswitch decodes an eswitch get reply.
+func parseDevlinkEswitch(attrs []Attribute) *DevlinkEswitch {
+	eswitch := &DevlinkEswitch{}
+	for _, attr := range attrs {
+		switch attr.Type {
+		case devlinkAttrEswitchMode:
+			eswitch.Mode = devlinkEswitchModes[attr.Uint16()]
+		case devlinkAttrEswitchInlineMode:
+			eswitch.InlineMode = devlinkInlineModes[attr.Uint8()]
+		case devlinkAttrEswitchEncapMode:
+			eswitch.EncapMode = devlinkEncapModes[attr.Uint8()]
+		}
+	}
+	return eswitch
+}
+
+// parseDevlinkPort decodes a port get reply.
+func parseDevlinkPort(attrs []Attribute) DevlinkPort {
+	var port DevlinkPort
+	number := func(attr Attribute, size int) *int {
+		var v int
+		if size == 2 {
+			v = int(attr.Uint16())
+		} else {
+			v = int(attr.Uint32())
+		}
+		return &v
+	}
+
+	for _, attr := range attrs {
+		switch attr.Type {
+		case devlinkAttrPortIndex:
+			port.Index = attr.Uint32()
+		case devlinkAttrPortType:
+			port.Type = devlinkPortTypes[attr.Uint16()]
+		case devlinkAttrPortFlavour:
+			port.Flavour = devlinkPortFlavours[attr.Uint16()]
+		case devlinkAttrPortNetdevName:
+			port.Netdev = attr.String()
+		case devlinkAttrPortIBDevName:
+			port.IBDev = attr.String()
+		case devlinkAttrPortNumber:
+			port.Number = number(attr, 4)
+		case devlinkAttrPortController:
+			port.Controller = number(attr, 4)
+		case devlinkAttrPortPCIPFNumber:
+			port.PFNumber = number(attr, 2)
+		case devlinkAttrPortPCIVFNumber:
+			port.VFNumber = number(attr, 2)
+		case devlinkAttrPortPCISFNumber:
+			port.SFNumber = number(attr, 4)
+		case devlinkAttrPortExternal:
+			port.External = attr.Uint8() != 0
+		}
+	}
+	return port
+}
+
+// parseDevlinkHealthReporter decodes a health reporter get reply. It
+// returns false if the message carries no reporter.
+func parseDevlinkHealthReporter(attrs []Attribute) (DevlinkHealthReporter, bool) {
+	var reporter DevlinkHealthReporter
+	var nested []Attribute
+
+	for _, attr := range attrs {
+		switch attr.Type {
+		case devlinkAttrPortIndex:
+			index := attr.Uint32()
+			reporter.PortIndex = &index
+		case devlinkAttrHealthReporter:
+			var err error
+			if nested, err = ParseAttributes(attr.Data); err != nil {
+				return reporter, false
+			}
+		}
+	}
+	if nested == nil {
+		return reporter, false
+	}
+
+	for _, attr := range nested {
+		switch attr.Type {
+		case devlinkAttrHealthReporterName:
+			reporter.Name = attr.String()
+		case devlinkAttrHealthReporterState:
+			reporter.State = devlinkHealthStates[attr.Uint8()]
+		case devlinkAttrHealthReporterErrCount:
+			reporter.ErrorCount = attr.Uint64()
+		case devlinkAttrHealthReporterRecoverCount:
+			reporter.RecoverCount = attr.Uint64()
+		case devlinkAttrHealthReporterGracefulPeriod:
+			reporter.GracefulPeriod = attr.Uint64()
+		case devlinkAttrHealthReporterAutoRecover:
+			reporter.AutoRecover = attr.Uint8() != 0
+		case devlinkAttrHealthReporterAutoDump:
+			reporter.AutoDump = attr.Uint8() != 0
+		case devlinkAttrHealthReporterDumpTSNs:
+			// Wall-clock time of the last dump; zero when there is none
+			if ns := attr.Uint64(); ns != 0 {
+				ts := time.Unix(0, int64(ns)).UTC()
+				reporter.LastDump = &ts
+			}
+		}
+	}
+	return reporter, true
+}
+
+// devlinkQuery sends a devlink request and returns the attributes of the
+// replies that belong to device.
+func devlinkQuery(device string, cmd uint8, flags uint16) ([][]Attribute, error) {
+	conn, family, err := dialGeneric(devlinkFamilyName)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Dumps are filtered here; older kernels ignore a handle on dumps
+	var attrs AttributeEncoder
+	if flags&FlagDump == 0 {
+		devlinkHandle(&attrs, device)
+	}
+
+	msgs, err := conn.Execute(genlRequest(family, cmd, devlinkGenlVersion, flags, attrs.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	var replies [][]Attribute
+	for _, msg := range msgs {
+		parsed, err := genlAttributes(msg)
+		if err != nil {
+			return nil, err
+		}
+		if matchesDevlinkHandle(parsed, device) {
+			replies = append(replies, parsed)
+		}
+	}
+	return replies, nil
+}
@@ -0,0 +1,165 @@
+package netlink
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func u64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.NativeEndian.PutUint64(b, v)
+	return b
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// parseEncoded parses attributes built by fn.
+func parseEncoded(t *testing.T, fn func(*AttributeEncoder)) []Attribute {
+	t.Helper()
+	var e AttributeEncoder
+	fn(&e)
+	attrs, err := ParseAttributes(e.Encode())
+	if err != nil {
+		t.Fatalf("ParseAttributes() error = %v", err)
+	}
+	return attrs
+}
+
+func TestParseDevlinkEswitch(t *testing.T) {
+	attrs := parseEncoded(t, func(e *AttributeEncoder) {
+		devlinkHandle(e, "0000:3b:00.0")
+		e.Uint16(devlinkAttrEswitchMode, 1)
+		e.Uint8(devlinkAttrEswitchInlineMode, 2)
+		e.Uint8(devlinkAttrEswitchEncapMode, 1)
+	})
+
+	want := &DevlinkEswitch{Mode: "switchdev", InlineMode: "network", EncapMode: "basic"}
+	if got := parseDevlinkEswitch(attrs); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDevlinkEswitch() = %+v, want %+v", got, want)
+	}
+	if !matchesDevlinkHandle(attrs, "0000:3b:00.0") || matchesDevlinkHandle(attrs, "0000:3b:00.1") {
+		t.Error("matchesDevlinkHandle() did not match the device handle")
+	}
+}
+
+func TestParseDevlinkPort(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(*AttributeEncoder)
+		want  DevlinkPort
+	}{
+		{
+			name: "physical",
+			build: func(e *AttributeEncoder) {
+				e.Uint32(devlinkAttrPortIndex, 65535)
+				e.Uint16(devlinkAttrPortType, 2)
+				e.String(devlinkAttrPortNetdevName, "ens1f0np0")
+				e.Uint16(devlinkAttrPortFlavour, 0)
+				e.Uint32(devlinkAttrPortNumber, 0)
+			},
+			want: DevlinkPort{Index: 65535, Type: "eth", Flavour: "physical", Netdev: "ens1f0np0", Number: intPtr(0)},
+		},
+		{
+			name: "VF representor",
+			build: func(e *AttributeEncoder) {
+				e.Uint32(devlinkAttrPortIndex, 65537)
+				e.Uint16(devlinkAttrPortType, 2)
+				e.String(devlinkAttrPortNetdevName, "pf0vf0")
+				e.Uint16(devlinkAttrPortFlavour, 4)
+				e.Uint32(devlinkAttrPortController, 1)
+				e.Uint16(devlinkAttrPortPCIPFNumber, 0)
+				e.Uint16(devlinkAttrPortPCIVFNumber, 3)
+				e.Uint8(devlinkAttrPortExternal, 1)
+			},
+			want: DevlinkPort{
+				Index: 65537, Type: "eth", Flavour: "pcivf", Netdev: "pf0vf0",
+				Controller: intPtr(1), PFNumber: intPtr(0), VFNumber: intPtr(3), External: true,
+			},
+		},
+		{
+			name: "SF representor",
+			build: func(e *AttributeEncoder) {
+				e.Uint32(devlinkAttrPortIndex, 98304)
+				e.Uint16(devlinkAttrPortFlavour, 7)
+				e.Uint16(devlinkAttrPortPCIPFNumber, 0)
+				e.Uint32(devlinkAttrPortPCISFNumber, 88)
+			},
+			want: DevlinkPort{Index: 98304, Flavour: "pcisf", PFNumber: intPtr(0), SFNumber: intPtr(88)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseDevlinkPort(parseEncoded(t, tt.build)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDevlinkPort() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDevlinkHealthReporter(t *testing.T) {
+	dumpTime := time.Date(2026, 9, 30, 14, 2, 11, 0, time.UTC)
+
+	attrs := parseEncoded(t, func(e *AttributeEncoder) {
+		devlinkHandle(e, "0000:3b:00.0")
+		e.Uint32(devlinkAttrPortIndex, 65535)
+		e.Nested(devlinkAttrHealthReporter, func(r *AttributeEncoder) {
+			r.String(devlinkAttrHealthReporterName, "tx")
+			r.Uint8(devlinkAttrHealthReporterState, 1)
+			r.Bytes(devlinkAttrHealthReporterErrCount, u64(3))
+			r.Bytes(devlinkAttrHealthReporterRecoverCount, u64(2))
+			r.Bytes(devlinkAttrHealthReporterGracefulPeriod, u64(500))
+			r.Uint8(devlinkAttrHealthReporterAutoRecover, 1)
+			r.Uint8(devlinkAttrHealthReporterAutoDump, 0)
+			r.Bytes(devlinkAttrHealthReporterDumpTSNs, u64(uint64(dumpTime.UnixNano())))
+		})
+	})
+
+	got, ok := parseDevlinkHealthReporter(attrs)
+	if !ok {
+		t.Fatal("parseDevlinkHealthReporter() found no reporter")
+	}
+	portIndex := uint32(65535)
+	want := DevlinkHealthReporter{
+		Name:           "tx",
+		PortIndex:      &portIndex,
+		State:          "error",
+		ErrorCount:     3,
+		RecoverCount:   2,
+		GracefulPeriod: 500,
+		AutoRecover:    true,
+		LastDump:       &dumpTime,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDevlinkHealthReporter() = %+v, want %+v", got, want)
+	}
+
+	// A message without the nested reporter attribute is skipped
+	if _, ok := parseDevlinkHealthReporter(parseEncoded(t, func(e *AttributeEncoder) {
+		devlinkHandle(e, "0000:3b:00.0")
+	})); ok {
+		t.Error("parseDevlinkHealthReporter() reported a reporter for an empty message")
+	}
+}
+
+func TestGenlRequest(t *testing.T) {
+	var attrs AttributeEncoder
+	attrs.String(ctrlAttrFamilyName, "devlink")
+
+	m := genlRequest(genlIDCtrl, ctrlCmdGetFamily, 1, FlagDump, attrs.Encode())
+	if m.Header.Type != genlIDCtrl || m.Header.Flags != FlagDump {
+		t.Errorf("header = %+v", m.Header)
+	}
+	if m.Data[0] != ctrlCmdGetFamily || m.Data[1] != 1 {
+		t.Errorf("genlmsghdr = %v, want cmd %d version 1", m.Data[:genlHeaderLen], ctrlCmdGetFamily)
+	}
+
+	parsed, err := genlAttributes(m)
+	if err != nil || len(parsed) != 1 || parsed[0].String() != "devlink" {
+		t.Errorf("genlAttributes() = %+v, %v", parsed, err)
+	}
+}
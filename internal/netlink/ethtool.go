@@ -0,0 +1,298 @@
+package netlink
+
+import (
+	"fmt"
+)
+
+// ethtool generic netlink commands and attributes (linux/ethtool_netlink.h).
+const (
+	ethtoolFamilyName  = "ethtool"
+	ethtoolGenlVersion = 1
+
+	ethtoolMsgLinkModesGet    = 4
+	ethtoolMsgChannelsGet     = 17
+	ethtoolMsgFECGet          = 29
+	ethtoolMsgModuleEEPROMGet = 31
+
+	// Every request and reply starts with a header nest (attribute 1)
+	ethtoolAHeader        = 1
+	ethtoolAHeaderDevName = 2
+
+	ethtoolABitsetNoMask = 1
+	ethtoolABitsetBits   = 3
+	ethtoolABitsBit      = 1
+	ethtoolABitIndex     = 1
+	ethtoolABitName      = 2
+	ethtoolABitValue     = 3
+
+	ethtoolALinkModesAutoneg = 2
+	ethtoolALinkModesOurs    = 3
+	ethtoolALinkModesPeer    = 4
+	ethtoolALinkModesSpeed   = 5
+	ethtoolALinkModesDuplex  = 6
+	ethtoolALinkModesLanes   = 9
+
+	ethtoolAChannelsRXMax         = 2
+	ethtoolAChannelsTXMax         = 3
+	ethtoolAChannelsOtherMax      = 4
+	ethtoolAChannelsCombinedMax   = 5
+	ethtoolAChannelsRXCount       = 6
+	ethtoolAChannelsTXCount       = 7
+	ethtoolAChannelsOtherCount    = 8
+	ethtoolAChannelsCombinedCount = 9
+
+	ethtoolAFECModes  = 2
+	ethtoolAFECAuto   = 3
+	ethtoolAFECActive = 4
+
+	ethtoolAModuleEEPROMOffset     = 2
+	ethtoolAModuleEEPROMLength     = 3
+	ethtoolAModuleEEPROMPage       = 4
+	ethtoolAModuleEEPROMBank       = 5
+	ethtoolAModuleEEPROMI2CAddress = 6
+	ethtoolAModuleEEPROMData       = 7
+)
+
+// EEPROMRequest selects a range of a transceiver module EEPROM page.
+// Offsets below 128 address the lower page; a request must not cross the
+// 128-byte boundary.
+type EEPROMRequest struct {
+	I2CAddress uint8
+	Page       uint8
+	Bank       uint8
+	Offset     uint32
+	Length     uint32
+}
+
+// ethtool FEC link mode bits (ETHTOOL_LINK_MODE_FEC_*_BIT), which
+// ETHTOOL_A_FEC_ACTIVE reports, and the names used for them.
+var ethtoolFECModes = map[uint32]string{
+	49: "off",
+	50: "RS",
+	51: "BaseR",
+	74: "LLRS",
+}
+
+// ethtoolFECNames maps the kernel's FEC link mode names to those of
+// ethtoolFECModes.
+var ethtoolFECNames = map[string]string{
+	"None":  "off",
+	"RS":    "RS",
+	"BASER": "BaseR",
+	"LLRS":  "LLRS",
+}
+
+// ethtoolDuplex maps DUPLEX_* values; DUPLEX_UNKNOWN (0xff) is left empty.
+var ethtoolDuplex = map[uint8]string{
+	0: "half",
+	1: "full",
+}
+
+// speedUnknown is SPEED_UNKNOWN, reported while the link is down.
+const speedUnknown = 0xffffffff
+
+// LinkModes holds the link settings of a netdev (ETHTOOL_MSG_LINKMODES_GET).
+// Link modes are named as by the kernel (e.g., "100000baseCR4/Full").
+type LinkModes struct {
+	Autoneg bool `json:"autoneg"`
+	// Speed is in Mb/s; 0 if unknown (e.g., the link is down).
+	Speed      int      `json:"speed,omitempty"`
+	Lanes      int      `json:"lanes,omitempty"`
+	Duplex     string   `json:"duplex,omitempty"`
+	Supported  []string `json:"supported,omitempty"`
+	Advertised []string `json:"advertised,omitempty"`
+	// Peer are the modes advertised by the link partner.
+	Peer []string `json:"peer,omitempty"`
+}
+
+// Channels holds the channel counts of a netdev and their maximums
+// (ETHTOOL_MSG_CHANNELS_GET), as shown by `ethtool -l`.
+type Channels struct {
+	RX          int `json:"rx,omitempty"`
+	TX          int `json:"tx,omitempty"`
+	Other       int `json:"other,omitempty"`
+	Combined    int `json:"combined,omitempty"`
+	MaxRX       int `json:"max_rx,omitempty"`
+	MaxTX       int `json:"max_tx,omitempty"`
+	MaxOther    int `json:"max_other,omitempty"`
+	MaxCombined int `json:"max_combined,omitempty"`
+}
+
+// FECSettings holds the forward error correction settings of a netdev
+// (ETHTOOL_MSG_FEC_GET). Encodings are "off", "RS", "BaseR" or "LLRS".
+type FECSettings struct {
+	// Active is the encoding in use; empty while the link is down.
+	Active string `json:"active,omitempty"`
+	// Configured are the encodings the port may use.
+	Configured []string `json:"configured,omitempty"`
+	Auto       bool     `json:"auto"`
+}
+
+// ethtoolRequest builds an ethtool request for a netdev; build adds the
+// attributes following the header.
+func ethtoolRequest(family uint16, cmd uint8, ifName string, build func(*AttributeEncoder)) Message {
+	var attrs AttributeEncoder
+	attrs.Nested(ethtoolAHeader, func(header *AttributeEncoder) {
+		header.String(ethtoolAHeaderDevName, ifName)
+	})
+	if build != nil {
+		build(&attrs)
+	}
+	return genlRequest(family, cmd, ethtoolGenlVersion, 0, attrs.Encode())
+}
+
+// moduleEEPROMRequest builds an ETHTOOL_MSG_MODULE_EEPROM_GET request.
+func moduleEEPROMRequest(family uint16, ifName string, req EEPROMRequest) Message {
+	return ethtoolRequest(family, ethtoolMsgModuleEEPROMGet, ifName, func(attrs *AttributeEncoder) {
+		attrs.Uint32(ethtoolAModuleEEPROMOffset, req.Offset)
+		attrs.Uint32(ethtoolAModuleEEPROMLength, req.Length)
+		attrs.Uint8(ethtoolAModuleEEPROMPage, req.Page)
+		attrs.Uint8(ethtoolAModuleEEPROMBank, req.Bank)
+		attrs.Uint8(ethtoolAModuleEEPROMI2CAddress, req.I2CAddress)
+	})
+}
+
+// parseLinkModes parses an ETHTOOL_MSG_LINKMODES_GET reply.
+func parseLinkModes(attrs []Attribute) (*LinkModes, error) {
+	modes := &LinkModes{}
+	for _, attr := range attrs {
+		switch attr.Type {
+		case ethtoolALinkModesAutoneg:
+			modes.Autoneg = attr.Uint8() == 1
+		case ethtoolALinkModesSpeed:
+			if speed := attr.Uint32(); speed != speedUnknown {
+				modes.Speed = int(speed)
+			}
+		case ethtoolALinkModesLanes:
+			modes.Lanes = int(attr.Uint32())
+		case ethtoolALinkModesDuplex:
+			modes.Duplex = ethtoolDuplex[attr.Uint8()]
+		case ethtoolALinkModesOurs:
+			advertised, supported, err := parseBitset(attr.Data)
+			if err != nil {
+				return nil, fmt.Errorf("invalid link modes: %w", err)
+			}
+			modes.Supported, modes.Advertised = supported, advertised
+		case ethtoolALinkModesPeer:
+			peer, _, err := parseBitset(attr.Data)
+			if err != nil {
+				return nil, fmt.Errorf("invalid link partner modes: %w", err)
+			}
+			modes.Peer = peer
+		}
+	}
+	return modes, nil
+}
+
+// parseChannels parses an ETHTOOL_MSG_CHANNELS_GET reply.
+func parseChannels(attrs []Attribute) *Channels {
+	channels := &Channels{}
+	fields := map[uint16]*int{
+		ethtoolAChannelsRXMax:         &channels.MaxRX,
+		ethtoolAChannelsTXMax:         &channels.MaxTX,
+		ethtoolAChannelsOtherMax:      &channels.MaxOther,
+		ethtoolAChannelsCombinedMax:   &channels.MaxCombined,
+		ethtoolAChannelsRXCount:       &channels.RX,
+		ethtoolAChannelsTXCount:       &channels.TX,
+		ethtoolAChannelsOtherCount:    &channels.Other,
+		ethtoolAChannelsCombinedCount: &channels.Combined,
+	}
+	for _, attr := range attrs {
+		if field, ok := fields[attr.Type]; ok {
+			*field = int(attr.Uint32())
+		}
+	}
+	return channels
+}
+
+// parseFEC parses an ETHTOOL_MSG_FEC_GET reply.
+func parseFEC(attrs []Attribute) (*FECSettings, error) {
+	fec := &FECSettings{}
+	for _, attr := range attrs {
+		switch attr.Type {
+		case ethtoolAFECModes:
+			configured, _, err := parseBitset(attr.Data)
+			if err != nil {
+				return nil, fmt.Errorf("invalid FEC modes: %w", err)
+			}
+			for _, name := range configured {
+				if mode, ok := ethtoolFECNames[name]; ok {
+					fec.Configured = append(fec.Configured, mode)
+				}
+			}
+		case ethtoolAFECAuto:
+			fec.Auto = attr.Uint8() == 1
+		case ethtoolAFECActive:
+			fec.Active = ethtoolFECModes[attr.Uint32()]
+		}
+	}
+	return fec, nil
+}
+
+// parseBitset parses a verbose ethtool bitset (ETHTOOL_A_BITSET_BITS) and
+// returns the names of the set bits and, unless the bitset has no mask,
+// the names of the bits in the mask.
+func parseBitset(data []byte) (values, mask []string, err error) {
+	attrs, err := ParseAttributes(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	noMask := false
+	var bits []Attribute
+	for _, attr := range attrs {
+		switch attr.Type {
+		case ethtoolABitsetNoMask:
+			noMask = true
+		case ethtoolABitsetBits:
+			if bits, err = ParseAttributes(attr.Data); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	for _, bit := range bits {
+		if bit.Type != ethtoolABitsBit {
+			continue
+		}
+		fields, err := ParseAttributes(bit.Data)
+		if err != nil {
+			return nil, nil, err
+		}
+		var name string
+		set := noMask
+		for _, field := range fields {
+			switch field.Type {
+			case ethtoolABitName:
+				name = field.String()
+			case ethtoolABitIndex:
+				if name == "" {
+					name = fmt.Sprintf("bit%d", field.Uint32())
+				}
+			case ethtoolABitValue:
+				set = true
+			}
+		}
+		if !noMask {
+			mask = append(mask, name)
+		}
+		if set {
+			values = append(values, name)
+		}
+	}
+	return values, mask, nil
+}
+
+// parseModuleEEPROM extracts the data of a module EEPROM reply.
+func parseModuleEEPROM(m Message) ([]byte, error) {
+	attrs, err := genlAttributes(m)
+	if err != nil {
+		return nil, err
+	}
+	for _, attr := range attrs {
+		if attr.Type == ethtoolAModuleEEPROMData {
+			return attr.Data, nil
+		}
+	}
+	return nil, fmt.Errorf("module EEPROM reply has no data")
+}
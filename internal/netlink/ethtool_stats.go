@@ -0,0 +1,263 @@
+package netlink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"syscall"
+)
+
+// ethtool ioctl commands (linux/ethtool.h). Driver statistics and driver
+// information have no netlink equivalent; they are read with the
+// SIOCETHTOOL ioctl. Link settings, FEC, channels and module EEPROM are
+// read with it on kernels without the netlink commands (before 5.6 and 5.13).
+const (
+	siocEthtool          = 0x8946
+	ethtoolGDrvInfo      = 0x03
+	ethtoolGStrings      = 0x1b
+	ethtoolGStats        = 0x1d
+	ethtoolGSSetInfo     = 0x37
+	ethtoolGChannels     = 0x3c
+	ethtoolGModuleInfo   = 0x42
+	ethtoolGModuleEEPROM = 0x43
+	ethtoolGLinkSettings = 0x4c
+	ethtoolGFECParam     = 0x50
+
+	ethSSStats       = 1
+	ethGStringLength = 32
+
+	// drvinfoLen is the size of struct ethtool_drvinfo: the command, five
+	// 32-byte strings, reserved bytes and five counts.
+	drvinfoLen = 196
+
+	// modinfoLen is the size of struct ethtool_modinfo and eepromLen the
+	// size of struct ethtool_eeprom without its data.
+	modinfoLen = 44
+	eepromLen  = 16
+	// ethModuleSFF8472 is the module type of SFP modules, whose flat
+	// EEPROM has the A2h diagnostics after the A0h page.
+	ethModuleSFF8472 = 0x2
+	eepromPageLen    = 128
+
+	// channelsLen is the size of struct ethtool_channels: the command, four
+	// maximums and four counts.
+	channelsLen = 36
+
+	// fecparamLen is the size of struct ethtool_fecparam: the command, the
+	// active and configured FEC masks and a reserved word.
+	fecparamLen = 16
+	// ethtoolFECAuto is ETHTOOL_FEC_AUTO in the configured FEC mask.
+	ethtoolFECAuto = 1 << 1
+
+	// linkSettingsLen is the size of struct ethtool_link_settings without
+	// its link mode masks.
+	linkSettingsLen = 48
+	// maxLinkModeWords bounds the link mode masks the kernel may ask for.
+	maxLinkModeWords = 127
+)
+
+// ethtoolLinkModeNames names the link mode bits (enum
+// ethtool_link_mode_bit_indices) as the kernel does over netlink. Port
+// types, pause and FEC bits are not link modes and are left out.
+var ethtoolLinkModeNames = map[int]string{
+	0: "10baseT/Half", 1: "10baseT/Full", 2: "100baseT/Half", 3: "100baseT/Full",
+	4: "1000baseT/Half", 5: "1000baseT/Full", 12: "10000baseT/Full",
+	15: "2500baseX/Full", 17: "1000baseKX/Full", 18: "10000baseKX4/Full",
+	19: "10000baseKR/Full", 21: "20000baseMLD2/Full", 22: "20000baseKR2/Full",
+	23: "40000baseKR4/Full", 24: "40000baseCR4/Full", 25: "40000baseSR4/Full",
+	26: "40000baseLR4/Full", 27: "56000baseKR4/Full", 28: "56000baseCR4/Full",
+	29: "56000baseSR4/Full", 30: "56000baseLR4/Full", 31: "25000baseCR/Full",
+	32: "25000baseKR/Full", 33: "25000baseSR/Full", 34: "50000baseCR2/Full",
+	35: "50000baseKR2/Full", 36: "100000baseKR4/Full", 37: "100000baseSR4/Full",
+	38: "100000baseCR4/Full", 39: "100000baseLR4_ER4/Full", 40: "50000baseSR2/Full",
+	41: "1000baseX/Full", 42: "10000baseCR/Full", 43: "10000baseSR/Full",
+	44: "10000baseLR/Full", 45: "10000baseLRM/Full", 46: "10000baseER/Full",
+	47: "2500baseT/Full", 48: "5000baseT/Full", 52: "50000baseKR/Full",
+	53: "50000baseSR/Full", 54: "50000baseCR/Full", 55: "50000baseLR_ER_FR/Full",
+	56: "50000baseDR/Full", 57: "100000baseKR2/Full", 58: "100000baseSR2/Full",
+	59: "100000baseCR2/Full", 60: "100000baseLR2_ER2_FR2/Full", 61: "100000baseDR2/Full",
+	62: "200000baseKR4/Full", 63: "200000baseSR4/Full", 64: "200000baseLR4_ER4_FR4/Full",
+	65: "200000baseDR4/Full", 66: "200000baseCR4/Full", 67: "100baseT1/Full",
+	68: "1000baseT1/Full", 69: "400000baseKR8/Full", 70: "400000baseSR8/Full",
+	71: "400000baseLR8_ER8_FR8/Full", 72: "400000baseDR8/Full", 73: "400000baseCR8/Full",
+	75: "100000baseKR/Full", 76: "100000baseSR/Full", 77: "100000baseLR_ER_FR/Full",
+	78: "100000baseCR/Full", 79: "100000baseDR/Full", 80: "200000baseKR2/Full",
+	81: "200000baseSR2/Full", 82: "200000baseLR2_ER2_FR2/Full", 83: "200000baseDR2/Full",
+	84: "200000baseCR2/Full", 85: "400000baseKR4/Full", 86: "400000baseSR4/Full",
+	87: "400000baseLR4_ER4_FR4/Full", 88: "400000baseDR4/Full", 89: "400000baseCR4/Full",
+	90: "100baseFX/Half", 91: "100baseFX/Full", 92: "10baseT1L/Full",
+	93: "800000baseCR8/Full", 94: "800000baseKR8/Full", 95: "800000baseDR8/Full",
+	96: "800000baseDR8_2/Full", 97: "800000baseSR8/Full", 98: "800000baseVR8/Full",
+}
+
+// ethtoolFECBits names the ETHTOOL_FEC_*_BIT bits of struct
+// ethtool_fecparam as ethtoolFECModes does. ETHTOOL_FEC_NONE (FEC not
+// configurable) and ETHTOOL_FEC_AUTO are not encodings.
+var ethtoolFECBits = map[int]string{
+	2: "off",
+	3: "RS",
+	4: "BaseR",
+	5: "LLRS",
+}
+
+// DriverInfo identifies the driver of a netdev, as shown by `ethtool -i`.
+type DriverInfo struct {
+	Driver string `json:"driver"`
+	// Version is the driver version; in-tree drivers report the kernel
+	// release.
+	Version string `json:"version,omitempty"`
+	// FirmwareVersion is the running firmware; mlx5 appends the PSID
+	// (e.g., "16.35.3006 (MT_0000000012)").
+	FirmwareVersion string `json:"firmware_version,omitempty"`
+	BusInfo         string `json:"bus_info,omitempty"`
+	EROMVersion     string `json:"erom_version,omitempty"`
+}
+
+// parseDriverInfo parses a struct ethtool_drvinfo filled by ETHTOOL_GDRVINFO.
+func parseDriverInfo(data []byte) (*DriverInfo, error) {
+	if len(data) < drvinfoLen {
+		return nil, fmt.Errorf("ethtool driver info truncated: %d bytes", len(data))
+	}
+	str := func(offset, length int) string {
+		b := data[offset : offset+length]
+		if end := bytes.IndexByte(b, 0); end >= 0 {
+			b = b[:end]
+		}
+		return string(b)
+	}
+	return &DriverInfo{
+		Driver:          str(4, 32),
+		Version:         str(36, 32),
+		FirmwareVersion: str(68, 32),
+		BusInfo:         str(100, 32),
+		EROMVersion:     str(132, 32),
+	}, nil
+}
+
+// parseLinkSettings parses a struct ethtool_link_settings filled by
+// ETHTOOL_GLINKSETTINGS: the settings followed by the supported,
+// advertised and link partner masks of nwords 32-bit words each. The
+// ioctl does not report lanes.
+func parseLinkSettings(data []byte, nwords int) (*LinkModes, error) {
+	if len(data) < linkSettingsLen+3*nwords*4 {
+		return nil, fmt.Errorf("ethtool link settings truncated: %d bytes for %d mask words", len(data), nwords)
+	}
+	modes := &LinkModes{
+		Autoneg: data[11] == 1,
+		Duplex:  ethtoolDuplex[data[8]],
+	}
+	if speed := binary.NativeEndian.Uint32(data[4:]); speed != speedUnknown {
+		modes.Speed = int(speed)
+	}
+	mask := func(i int) []string {
+		words := data[linkSettingsLen+i*nwords*4:]
+		var names []string
+		for bit := 0; bit < nwords*32; bit++ {
+			if binary.NativeEndian.Uint32(words[bit/32*4:])&(1<<(bit%32)) == 0 {
+				continue
+			}
+			if name, ok := ethtoolLinkModeNames[bit]; ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	modes.Supported, modes.Advertised, modes.Peer = mask(0), mask(1), mask(2)
+	return modes, nil
+}
+
+// parseEthtoolChannels parses a struct ethtool_channels filled by
+// ETHTOOL_GCHANNELS.
+func parseEthtoolChannels(data []byte) (*Channels, error) {
+	if len(data) < channelsLen {
+		return nil, fmt.Errorf("ethtool channels truncated: %d bytes", len(data))
+	}
+	field := func(i int) int {
+		return int(binary.NativeEndian.Uint32(data[4+i*4:]))
+	}
+	return &Channels{
+		MaxRX:       field(0),
+		MaxTX:       field(1),
+		MaxOther:    field(2),
+		MaxCombined: field(3),
+		RX:          field(4),
+		TX:          field(5),
+		Other:       field(6),
+		Combined:    field(7),
+	}, nil
+}
+
+// parseFECParam parses a struct ethtool_fecparam filled by
+// ETHTOOL_GFECPARAM.
+func parseFECParam(data []byte) (*FECSettings, error) {
+	if len(data) < fecparamLen {
+		return nil, fmt.Errorf("ethtool FEC parameters truncated: %d bytes", len(data))
+	}
+	active := binary.NativeEndian.Uint32(data[4:])
+	configured := binary.NativeEndian.Uint32(data[8:])
+
+	fec := &FECSettings{Auto: configured&ethtoolFECAuto != 0}
+	for bit := 0; bit < 32; bit++ {
+		name, ok := ethtoolFECBits[bit]
+		if !ok {
+			continue
+		}
+		if configured&(1<<bit) != 0 {
+			fec.Configured = append(fec.Configured, name)
+		}
+		if active&(1<<bit) != 0 && fec.Active == "" {
+			fec.Active = name
+		}
+	}
+	return fec, nil
+}
+
+// legacyEEPROMRange maps a paged module EEPROM read to the flat EEPROM of
+// ETHTOOL_GMODULEEEPROM, as the kernel does for drivers without paged
+// access: upper pages follow each other after the lower page, and the SFP
+// A2h diagnostics follow the A0h page. Reads past the end are truncated.
+func legacyEEPROMRange(moduleType, length uint32, req EEPROMRequest) (offset, n uint32, err error) {
+	if req.Bank != 0 {
+		return 0, 0, fmt.Errorf("module EEPROM bank %d: %w", req.Bank, syscall.EOPNOTSUPP)
+	}
+	offset = req.Offset
+	if req.Page != 0 {
+		offset += uint32(req.Page) * eepromPageLen
+	}
+	if moduleType == ethModuleSFF8472 && req.I2CAddress == 0x51 {
+		offset += 2 * eepromPageLen
+	}
+	if offset >= length {
+		return 0, 0, fmt.Errorf("page 0x%02x at offset %d is beyond the %d-byte module EEPROM", req.Page, req.Offset, length)
+	}
+	return offset, min(req.Length, length-offset), nil
+}
+
+// parseStringSet splits an ETHTOOL_GSTRINGS buffer into its n names.
+func parseStringSet(data []byte, n int) ([]string, error) {
+	if len(data) < n*ethGStringLength {
+		return nil, fmt.Errorf("ethtool string set truncated: %d bytes for %d strings", len(data), n)
+	}
+	names := make([]string, n)
+	for i := range names {
+		name := data[i*ethGStringLength : (i+1)*ethGStringLength]
+		if end := bytes.IndexByte(name, 0); end >= 0 {
+			name = name[:end]
+		}
+		names[i] = string(name)
+	}
+	return names, nil
+}
+
+// parseStats pairs ETHTOOL_GSTATS values with their names. Drivers may
+// repeat a name; the last value wins.
+func parseStats(names []string, data []byte) (map[string]uint64, error) {
+	if len(data) < len(names)*8 {
+		return nil, fmt.Errorf("ethtool statistics truncated: %d bytes for %d counters", len(data), len(names))
+	}
+	stats := make(map[string]uint64, len(names))
+	for i, name := range names {
+		stats[name] = binary.NativeEndian.Uint64(data[i*8:])
+	}
+	return stats, nil
+}
@@ -0,0 +1,210 @@
+//go:build linux
+
+package netlink
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// ifreq is struct ifreq with ifr_data.
+type ifreq struct {
+	name [syscall.IFNAMSIZ]byte
+	data unsafe.Pointer
+	_    [16]byte
+}
+
+// ethtoolStats reads the driver statistics of a netdev (ethtool -S): the
+// ETH_SS_STATS string set and the matching ETHTOOL_GSTATS values.
+func ethtoolStats(ifName string) (map[string]uint64, error) {
+	if len(ifName) >= syscall.IFNAMSIZ {
+		return nil, fmt.Errorf("invalid interface name %q", ifName)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ethtool socket: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	// struct ethtool_sset_info with one data word
+	ssetInfo := make([]byte, 20)
+	binary.NativeEndian.PutUint32(ssetInfo[0:], ethtoolGSSetInfo)
+	binary.NativeEndian.PutUint64(ssetInfo[8:], 1<<ethSSStats)
+	if err := ethtoolIoctl(fd, ifName, ssetInfo); err != nil {
+		return nil, fmt.Errorf("ETHTOOL_GSSET_INFO failed for %s: %w", ifName, err)
+	}
+	if binary.NativeEndian.Uint64(ssetInfo[8:])&(1<<ethSSStats) == 0 {
+		return nil, fmt.Errorf("%s has no statistics: %w", ifName, syscall.EOPNOTSUPP)
+	}
+	n := int(binary.NativeEndian.Uint32(ssetInfo[16:]))
+
+	// struct ethtool_gstrings
+	gstrings := make([]byte, 12+n*ethGStringLength)
+	binary.NativeEndian.PutUint32(gstrings[0:], ethtoolGStrings)
+	binary.NativeEndian.PutUint32(gstrings[4:], ethSSStats)
+	binary.NativeEndian.PutUint32(gstrings[8:], uint32(n))
+	if err := ethtoolIoctl(fd, ifName, gstrings); err != nil {
+		return nil, fmt.Errorf("ETHTOOL_GSTRINGS failed for %s: %w", ifName, err)
+	}
+	names, err := parseStringSet(gstrings[12:], int(binary.NativeEndian.Uint32(gstrings[8:])))
+	if err != nil {
+		return nil, err
+	}
+
+	// struct ethtool_stats
+	stats := make([]byte, 8+len(names)*8)
+	binary.NativeEndian.PutUint32(stats[0:], ethtoolGStats)
+	binary.NativeEndian.PutUint32(stats[4:], uint32(len(names)))
+	if err := ethtoolIoctl(fd, ifName, stats); err != nil {
+		return nil, fmt.Errorf("ETHTOOL_GSTATS failed for %s: %w", ifName, err)
+	}
+	return parseStats(names, stats[8:])
+}
+
+// ethtoolDriverInfo reads the driver name, version and firmware version of
+// a netdev (ethtool -i) with ETHTOOL_GDRVINFO.
+func ethtoolDriverInfo(ifName string) (*DriverInfo, error) {
+	if len(ifName) >= syscall.IFNAMSIZ {
+		return nil, fmt.Errorf("invalid interface name %q", ifName)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ethtool socket: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	drvinfo := make([]byte, drvinfoLen)
+	binary.NativeEndian.PutUint32(drvinfo[0:], ethtoolGDrvInfo)
+	if err := ethtoolIoctl(fd, ifName, drvinfo); err != nil {
+		return nil, fmt.Errorf("ETHTOOL_GDRVINFO failed for %s: %w", ifName, err)
+	}
+	return parseDriverInfo(drvinfo)
+}
+
+// ethtoolLinkSettings reads the link settings of a netdev with
+// ETHTOOL_GLINKSETTINGS. The first request, with no link mode masks, returns
+// the number of mask words the kernel uses as a negative count.
+func ethtoolLinkSettings(ifName string) (*LinkModes, error) {
+	if len(ifName) >= syscall.IFNAMSIZ {
+		return nil, fmt.Errorf("invalid interface name %q", ifName)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ethtool socket: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	settings := make([]byte, linkSettingsLen)
+	binary.NativeEndian.PutUint32(settings[0:], ethtoolGLinkSettings)
+	if err := ethtoolIoctl(fd, ifName, settings); err != nil {
+		return nil, fmt.Errorf("ETHTOOL_GLINKSETTINGS failed for %s: %w", ifName, err)
+	}
+	nwords := -int(int8(settings[15]))
+	if nwords <= 0 || nwords > maxLinkModeWords {
+		return nil, fmt.Errorf("ETHTOOL_GLINKSETTINGS returned %d mask words for %s", nwords, ifName)
+	}
+
+	settings = make([]byte, linkSettingsLen+3*nwords*4)
+	binary.NativeEndian.PutUint32(settings[0:], ethtoolGLinkSettings)
+	settings[15] = byte(nwords)
+	if err := ethtoolIoctl(fd, ifName, settings); err != nil {
+		return nil, fmt.Errorf("ETHTOOL_GLINKSETTINGS failed for %s: %w", ifName, err)
+	}
+	return parseLinkSettings(settings, nwords)
+}
+
+// ethtoolFECParam reads the FEC settings of a netdev (ethtool
+// --show-fec) with ETHTOOL_GFECPARAM.
+func ethtoolFECParam(ifName string) (*FECSettings, error) {
+	if len(ifName) >= syscall.IFNAMSIZ {
+		return nil, fmt.Errorf("invalid interface name %q", ifName)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ethtool socket: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	fecparam := make([]byte, fecparamLen)
+	binary.NativeEndian.PutUint32(fecparam[0:], ethtoolGFECParam)
+	if err := ethtoolIoctl(fd, ifName, fecparam); err != nil {
+		return nil, fmt.Errorf("ETHTOOL_GFECPARAM failed for %s: %w", ifName, err)
+	}
+	return parseFECParam(fecparam)
+}
+
+// ethtoolChannels reads the channel counts of a netdev (ethtool -l) with
+// ETHTOOL_GCHANNELS.
+func ethtoolChannels(ifName string) (*Channels, error) {
+	if len(ifName) >= syscall.IFNAMSIZ {
+		return nil, fmt.Errorf("invalid interface name %q", ifName)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ethtool socket: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	channels := make([]byte, channelsLen)
+	binary.NativeEndian.PutUint32(channels[0:], ethtoolGChannels)
+	if err := ethtoolIoctl(fd, ifName, channels); err != nil {
+		return nil, fmt.Errorf("ETHTOOL_GCHANNELS failed for %s: %w", ifName, err)
+	}
+	return parseEthtoolChannels(channels)
+}
+
+// ethtoolModuleEEPROM reads a page range of the module EEPROM of a netdev
+// with ETHTOOL_GMODULEINFO and ETHTOOL_GMODULEEEPROM.
+func ethtoolModuleEEPROM(ifName string, req EEPROMRequest) ([]byte, error) {
+	if len(ifName) >= syscall.IFNAMSIZ {
+		return nil, fmt.Errorf("invalid interface name %q", ifName)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ethtool socket: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	modinfo := make([]byte, modinfoLen)
+	binary.NativeEndian.PutUint32(modinfo[0:], ethtoolGModuleInfo)
+	if err := ethtoolIoctl(fd, ifName, modinfo); err != nil {
+		return nil, fmt.Errorf("ETHTOOL_GMODULEINFO failed for %s: %w", ifName, err)
+	}
+	offset, n, err := legacyEEPROMRange(binary.NativeEndian.Uint32(modinfo[4:]), binary.NativeEndian.Uint32(modinfo[8:]), req)
+	if err != nil || n == 0 {
+		return nil, err
+	}
+
+	eeprom := make([]byte, eepromLen+n)
+	binary.NativeEndian.PutUint32(eeprom[0:], ethtoolGModuleEEPROM)
+	binary.NativeEndian.PutUint32(eeprom[8:], offset)
+	binary.NativeEndian.PutUint32(eeprom[12:], n)
+	if err := ethtoolIoctl(fd, ifName, eeprom); err != nil {
+		return nil, fmt.Errorf("ETHTOOL_GMODULEEEPROM failed for %s: %w", ifName, err)
+	}
+	return eeprom[eepromLen:], nil
+}
+
+// ethtoolIoctl issues SIOCETHTOOL for a netdev with data as the command
+// buffer, which the kernel fills in.
+func ethtoolIoctl(fd int, ifName string, data []byte) error {
+	var ifr ifreq
+	copy(ifr.name[:], ifName)
+	ifr.data = unsafe.Pointer(&data[0])
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), siocEthtool, uintptr(unsafe.Pointer(&ifr)))
+	runtime.KeepAlive(data)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+
+package netlink
+
+// ethtoolStats returns ErrNotSupported on this platform.
+func ethtoolStats(ifName string) (map[string]uint64, error) {
+	return nil, ErrNotSupported
+}
+
+// ethtoolDriverInfo returns ErrNotSupported on this platform.
+func ethtoolDriverInfo(ifName string) (*DriverInfo, error) {
+	return nil, ErrNotSupported
+}
+
+// ethtoolLinkSettings returns ErrNotSupported on this platform.
+func ethtoolLinkSettings(ifName string) (*LinkModes, error) {
+	return nil, ErrNotSupported
+}
+
+// ethtoolFECParam returns ErrNotSupported on this platform.
+func ethtoolFECParam(ifName string) (*FECSettings, error) {
+	return nil, ErrNotSupported
+}
+
+// ethtoolChannels returns ErrNotSupported on this platform.
+func ethtoolChannels(ifName string) (*Channels, error) {
+	return nil, ErrNotSupported
+}
+
+// ethtoolModuleEEPROM returns ErrNotSupported on this platform.
+func ethtoolModuleEEPROM(ifName string, req EEPROMRequest) ([]byte, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,330 @@
+package netlink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestModuleEEPROMRequest(t *testing.T) {
+	m := moduleEEPROMRequest(21, "ens1f0np0", EEPROMRequest{I2CAddress: 0x50, Page: 3, Offset: 128, Length: 128})
+	if m.Header.Type != 21 || m.Data[0] != ethtoolMsgModuleEEPROMGet {
+		t.Errorf("header = %+v, cmd = %d", m.Header, m.Data[0])
+	}
+
+	attrs, err := genlAttributes(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[uint16]Attribute)
+	for _, attr := range attrs {
+		got[attr.Type] = attr
+	}
+
+	header, err := ParseAttributes(got[ethtoolAHeader].Data)
+	if err != nil || len(header) != 1 || header[0].Type != ethtoolAHeaderDevName || header[0].String() != "ens1f0np0" {
+		t.Errorf("header attributes = %+v, %v", header, err)
+	}
+	if got[ethtoolAModuleEEPROMOffset].Uint32() != 128 || got[ethtoolAModuleEEPROMLength].Uint32() != 128 {
+		t.Errorf("offset/length = %d/%d, want 128/128", got[ethtoolAModuleEEPROMOffset].Uint32(), got[ethtoolAModuleEEPROMLength].Uint32())
+	}
+	if got[ethtoolAModuleEEPROMPage].Uint8() != 3 || got[ethtoolAModuleEEPROMBank].Uint8() != 0 || got[ethtoolAModuleEEPROMI2CAddress].Uint8() != 0x50 {
+		t.Errorf("page/bank/address = %d/%d/%#x", got[ethtoolAModuleEEPROMPage].Uint8(), got[ethtoolAModuleEEPROMBank].Uint8(), got[ethtoolAModuleEEPROMI2CAddress].Uint8())
+	}
+}
+
+func TestParseModuleEEPROM(t *testing.T) {
+	var attrs AttributeEncoder
+	attrs.Nested(ethtoolAHeader, func(header *AttributeEncoder) {
+		header.String(ethtoolAHeaderDevName, "ens1f0np0")
+	})
+	attrs.Bytes(ethtoolAModuleEEPROMData, []byte{0x11, 0x07, 0x00})
+
+	data, err := parseModuleEEPROM(genlRequest(21, ethtoolMsgModuleEEPROMGet, ethtoolGenlVersion, 0, attrs.Encode()))
+	if err != nil || !bytes.Equal(data, []byte{0x11, 0x07, 0x00}) {
+		t.Errorf("parseModuleEEPROM() = %x, %v", data, err)
+	}
+
+	if _, err := parseModuleEEPROM(genlRequest(21, ethtoolMsgModuleEEPROMGet, ethtoolGenlVersion, 0, nil)); err == nil {
+		t.Error("expected error for reply without data")
+	}
+}
+
+func TestLegacyEEPROMRange(t *testing.T) {
+	const sff8636 = 0x3
+	tests := []struct {
+		name       string
+		moduleType uint32
+		length     uint32
+		req        EEPROMRequest
+		offset, n  uint32
+		wantErr    bool
+	}{
+		{name: "SFP A0h", moduleType: ethModuleSFF8472, length: 512, req: EEPROMRequest{I2CAddress: 0x50, Length: 128}, offset: 0, n: 128},
+		{name: "SFP A2h", moduleType: ethModuleSFF8472, length: 512, req: EEPROMRequest{I2CAddress: 0x51, Offset: 128, Length: 128}, offset: 384, n: 128},
+		{name: "QSFP upper page 0", moduleType: sff8636, length: 640, req: EEPROMRequest{I2CAddress: 0x50, Offset: 128, Length: 128}, offset: 128, n: 128},
+		{name: "QSFP page 3", moduleType: sff8636, length: 640, req: EEPROMRequest{I2CAddress: 0x50, Page: 3, Offset: 128, Length: 128}, offset: 512, n: 128},
+		{name: "truncated", moduleType: sff8636, length: 256, req: EEPROMRequest{I2CAddress: 0x50, Offset: 128, Length: 256}, offset: 128, n: 128},
+		{name: "beyond the EEPROM", moduleType: sff8636, length: 256, req: EEPROMRequest{I2CAddress: 0x50, Page: 3, Offset: 128, Length: 128}, wantErr: true},
+		{name: "bank", moduleType: sff8636, length: 640, req: EEPROMRequest{I2CAddress: 0x50, Bank: 1, Offset: 128, Length: 128}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, n, err := legacyEEPROMRange(tt.moduleType, tt.length, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("legacyEEPROMRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if offset != tt.offset || n != tt.n {
+				t.Errorf("legacyEEPROMRange() = %d, %d, want %d, %d", offset, n, tt.offset, tt.n)
+			}
+		})
+	}
+
+	// Modules with banks cannot be read without paged access
+	if _, _, err := legacyEEPROMRange(sff8636, 640, EEPROMRequest{Bank: 1}); !IsNotSupported(err) {
+		t.Errorf("legacyEEPROMRange(bank 1) error = %v, want not supported", err)
+	}
+}
+
+func TestParseStats(t *testing.T) {
+	gstrings := make([]byte, 3*ethGStringLength)
+	copy(gstrings[0:], "rx_crc_errors_phy")
+	copy(gstrings[ethGStringLength:], "rx_prio3_pause")
+	copy(gstrings[2*ethGStringLength:], "rx_out_of_buffer")
+
+	names, err := parseStringSet(gstrings, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names[0] != "rx_crc_errors_phy" || names[2] != "rx_out_of_buffer" {
+		t.Errorf("parseStringSet() = %q", names)
+	}
+
+	values := append(append(u64(17), u64(1842)...), u64(2048)...)
+	stats, err := parseStats(names, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 3 || stats["rx_crc_errors_phy"] != 17 || stats["rx_prio3_pause"] != 1842 || stats["rx_out_of_buffer"] != 2048 {
+		t.Errorf("parseStats() = %v", stats)
+	}
+
+	if _, err := parseStringSet(gstrings, 4); err == nil {
+		t.Error("expected error for truncated string set")
+	}
+	if _, err := parseStats(names, values[:16]); err == nil {
+		t.Error("expected error for truncated statistics")
+	}
+}
+
+func TestParseDriverInfo(t *testing.T) {
+	data := make([]byte, drvinfoLen)
+	copy(data[4:], "mlx5_core")
+	copy(data[36:], "24.04-0.6.6")
+	copy(data[68:], "16.35.3006 (MT_0000000012)")
+	copy(data[100:], "0000:3b:00.0")
+
+	got, err := parseDriverInfo(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DriverInfo{Driver: "mlx5_core", Version: "24.04-0.6.6", FirmwareVersion: "16.35.3006 (MT_0000000012)", BusInfo: "0000:3b:00.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDriverInfo() = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseDriverInfo(data[:100]); err == nil {
+		t.Error("expected error for truncated driver info")
+	}
+}
+
+// encodeBitset builds a verbose bitset listing bits by name; set bits carry
+// the value flag unless the bitset has no mask.
+func encodeBitset(e *AttributeEncoder, typ uint16, noMask bool, bits map[uint32]string, set ...uint32) {
+	isSet := make(map[uint32]bool)
+	for _, index := range set {
+		isSet[index] = true
+	}
+	e.Nested(typ, func(bitset *AttributeEncoder) {
+		if noMask {
+			bitset.Bytes(ethtoolABitsetNoMask, nil)
+		}
+		bitset.Nested(ethtoolABitsetBits, func(list *AttributeEncoder) {
+			for index := uint32(0); index < 128; index++ {
+				name, ok := bits[index]
+				if !ok || (noMask && !isSet[index]) {
+					continue
+				}
+				list.Nested(ethtoolABitsBit, func(bit *AttributeEncoder) {
+					bit.Uint32(ethtoolABitIndex, index)
+					bit.String(ethtoolABitName, name)
+					if !noMask && isSet[index] {
+						bit.Bytes(ethtoolABitValue, nil)
+					}
+				})
+			}
+		})
+	})
+}
+
+func TestParseLinkModes(t *testing.T) {
+	modes := map[uint32]string{
+		31: "25000baseCR/Full",
+		38: "100000baseCR4/Full",
+		60: "100000baseCR2/Full",
+	}
+	attrs := parseEncoded(t, func(e *AttributeEncoder) {
+		e.Uint8(ethtoolALinkModesAutoneg, 1)
+		encodeBitset(e, ethtoolALinkModesOurs, false, modes, 31, 60)
+		encodeBitset(e, ethtoolALinkModesPeer, true, modes, 31)
+		e.Uint32(ethtoolALinkModesSpeed, 25000)
+		e.Uint8(ethtoolALinkModesDuplex, 1)
+		e.Uint32(ethtoolALinkModesLanes, 1)
+	})
+
+	got, err := parseLinkModes(attrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &LinkModes{
+		Autoneg:    true,
+		Speed:      25000,
+		Lanes:      1,
+		Duplex:     "full",
+		Supported:  []string{"25000baseCR/Full", "100000baseCR4/Full", "100000baseCR2/Full"},
+		Advertised: []string{"25000baseCR/Full", "100000baseCR2/Full"},
+		Peer:       []string{"25000baseCR/Full"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLinkModes() = %+v, want %+v", got, want)
+	}
+
+	// A down link reports SPEED_UNKNOWN and DUPLEX_UNKNOWN
+	down, err := parseLinkModes(parseEncoded(t, func(e *AttributeEncoder) {
+		e.Uint32(ethtoolALinkModesSpeed, speedUnknown)
+		e.Uint8(ethtoolALinkModesDuplex, 0xff)
+	}))
+	if err != nil || down.Speed != 0 || down.Duplex != "" {
+		t.Errorf("parseLinkModes(down) = %+v, %v", down, err)
+	}
+}
+
+func TestParseLinkSettings(t *testing.T) {
+	const nwords = 4
+	data := make([]byte, linkSettingsLen+3*nwords*4)
+	binary.NativeEndian.PutUint32(data[0:], ethtoolGLinkSettings)
+	binary.NativeEndian.PutUint32(data[4:], 800000)
+	data[8] = 1  // DUPLEX_FULL
+	data[11] = 1 // AUTONEG_ENABLE
+	data[15] = nwords
+	setBit := func(mask, bit int) {
+		offset := linkSettingsLen + mask*nwords*4 + bit/32*4
+		binary.NativeEndian.PutUint32(data[offset:], binary.NativeEndian.Uint32(data[offset:])|1<<(bit%32))
+	}
+	for _, bit := range []int{6, 10, 47, 50, 93, 97} { // Autoneg, FIBRE and FEC_RS are skipped
+		setBit(0, bit)
+	}
+	setBit(1, 97)
+	setBit(2, 97)
+
+	got, err := parseLinkSettings(data, nwords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &LinkModes{
+		Autoneg:    true,
+		Speed:      800000,
+		Duplex:     "full",
+		Supported:  []string{"2500baseT/Full", "800000baseCR8/Full", "800000baseSR8/Full"},
+		Advertised: []string{"800000baseSR8/Full"},
+		Peer:       []string{"800000baseSR8/Full"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLinkSettings() = %+v, want %+v", got, want)
+	}
+
+	// A down link reports SPEED_UNKNOWN and DUPLEX_UNKNOWN
+	binary.NativeEndian.PutUint32(data[4:], speedUnknown)
+	data[8] = 0xff
+	if down, err := parseLinkSettings(data, nwords); err != nil || down.Speed != 0 || down.Duplex != "" {
+		t.Errorf("parseLinkSettings(down) = %+v, %v", down, err)
+	}
+
+	if _, err := parseLinkSettings(data[:linkSettingsLen], nwords); err == nil {
+		t.Error("expected error for truncated link settings")
+	}
+}
+
+func TestParseChannels(t *testing.T) {
+	got := parseChannels(parseEncoded(t, func(e *AttributeEncoder) {
+		e.Nested(ethtoolAHeader, func(header *AttributeEncoder) {
+			header.String(ethtoolAHeaderDevName, "ens1f0np0")
+		})
+		e.Uint32(ethtoolAChannelsCombinedMax, 63)
+		e.Uint32(ethtoolAChannelsCombinedCount, 3)
+		e.Uint32(ethtoolAChannelsOtherMax, 0)
+	}))
+	want := &Channels{Combined: 3, MaxCombined: 63}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseChannels() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseFECParam(t *testing.T) {
+	data := make([]byte, fecparamLen)
+	binary.NativeEndian.PutUint32(data[0:], ethtoolGFECParam)
+	binary.NativeEndian.PutUint32(data[4:], 1<<3)                // active: RS
+	binary.NativeEndian.PutUint32(data[8:], 1<<1|1<<2|1<<3|1<<4) // AUTO, OFF, RS, BASER
+
+	got, err := parseFECParam(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &FECSettings{Active: "RS", Configured: []string{"off", "RS", "BaseR"}, Auto: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseFECParam() = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseFECParam(data[:8]); err == nil {
+		t.Error("expected error for truncated FEC parameters")
+	}
+}
+
+func TestParseEthtoolChannels(t *testing.T) {
+	data := make([]byte, channelsLen)
+	for i, v := range []uint32{ethtoolGChannels, 0, 0, 8, 63, 0, 0, 0, 3} {
+		binary.NativeEndian.PutUint32(data[i*4:], v)
+	}
+
+	got, err := parseEthtoolChannels(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Channels{Combined: 3, MaxOther: 8, MaxCombined: 63}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseEthtoolChannels() = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseEthtoolChannels(data[:20]); err == nil {
+		t.Error("expected error for truncated channels")
+	}
+}
+
+func TestParseFEC(t *testing.T) {
+	attrs := parseEncoded(t, func(e *AttributeEncoder) {
+		encodeBitset(e, ethtoolAFECModes, false, map[uint32]string{49: "None", 50: "RS", 51: "BASER"}, 49, 50, 51)
+		e.Uint8(ethtoolAFECAuto, 1)
+		e.Uint32(ethtoolAFECActive, 50)
+	})
+
+	got, err := parseFEC(attrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &FECSettings{Active: "RS", Configured: []string{"off", "RS", "BaseR"}, Auto: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseFEC() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,99 @@
+package netlink
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// Generic netlink controller (linux/genetlink.h).
+const (
+	genlIDCtrl         = 0x10
+	ctrlCmdGetFamily   = 3
+	ctrlAttrFamilyID   = 1
+	ctrlAttrFamilyName = 2
+
+	genlHeaderLen = 4
+)
+
+// ErrFamilyNotFound is returned when the kernel does not provide a generic
+// netlink family (e.g., devlink on kernels built without it).
+var ErrFamilyNotFound = errors.New("generic netlink family not found")
+
+// IsNotSupported reports whether err means a query is not available on this
+// host or device: no netlink, a missing generic netlink family, or a driver
+// that does not implement the operation (EOPNOTSUPP).
+func IsNotSupported(err error) bool {
+	var nlErr *Error
+	if errors.As(err, &nlErr) {
+		return nlErr.Errno == int(syscall.EOPNOTSUPP)
+	}
+	return errors.Is(err, ErrNotSupported) || errors.Is(err, ErrFamilyNotFound) ||
+		errors.Is(err, syscall.EOPNOTSUPP)
+}
+
+// genlRequest builds a generic netlink request (struct genlmsghdr followed
+// by attributes) for a family.
+func genlRequest(family uint16, cmd, version uint8, flags uint16, attrs []byte) Message {
+	data := make([]byte, genlHeaderLen, genlHeaderLen+len(attrs))
+	data[0] = cmd
+	data[1] = version
+	data = append(data, attrs...)
+
+	return Message{
+		Header: Header{Type: family, Flags: flags},
+		Data:   data,
+	}
+}
+
+// genlAttributes returns the attributes of a generic netlink message.
+func genlAttributes(m Message) ([]Attribute, error) {
+	if len(m.Data) < genlHeaderLen {
+		return nil, fmt.Errorf("truncated generic netlink header")
+	}
+	return ParseAttributes(m.Data[genlHeaderLen:])
+}
+
+// familyID resolves a generic netlink family name (e.g., "devlink") to its
+// message type.
+func (c *Conn) familyID(name string) (uint16, error) {
+	var attrs AttributeEncoder
+	attrs.String(ctrlAttrFamilyName, name)
+
+	msgs, err := c.Execute(genlRequest(genlIDCtrl, ctrlCmdGetFamily, 1, 0, attrs.Encode()))
+	var nlErr *Error
+	if errors.As(err, &nlErr) && nlErr.Errno == int(syscall.ENOENT) {
+		return 0, fmt.Errorf("%w: %s", ErrFamilyNotFound, name)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve generic netlink family %s: %w", name, err)
+	}
+
+	for _, msg := range msgs {
+		parsed, err := genlAttributes(msg)
+		if err != nil {
+			return 0, err
+		}
+		for _, attr := range parsed {
+			if attr.Type == ctrlAttrFamilyID && len(attr.Data) >= 2 {
+				return binary.NativeEndian.Uint16(attr.Data), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("generic netlink family %s has no ID", name)
+}
+
+// dialGeneric opens a generic netlink socket and resolves a family.
+func dialGeneric(name string) (*Conn, uint16, error) {
+	conn, err := Dial(FamilyGeneric)
+	if err != nil {
+		return nil, 0, err
+	}
+	family, err := conn.familyID(name)
+	if err != nil {
+		conn.Close()
+		return nil, 0, err
+	}
+	return conn, family, nil
+}
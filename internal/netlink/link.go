@@ -0,0 +1,289 @@
+package netlink
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// Netlink protocol families.
+const (
+	FamilyRoute   = 0
+	FamilyGeneric = 16
+)
+
+// rtnetlink message types and link attributes (linux/rtnetlink.h,
+// linux/if_link.h).
+const (
+	rtmNewLink = 16
+	rtmGetLink = 18
+
+	iflaIfname     = 3
+	iflaLinkInfo   = 18
+	iflaVFInfoList = 22
+	iflaExtMask    = 29
+
+	rtextFilterVF = 1
+
+	iflaVFInfo      = 1
+	iflaVFMAC       = 1
+	iflaVFVLAN      = 2
+	iflaVFSpoofChk  = 4
+	iflaVFLinkState = 5
+	iflaVFRate      = 6
+	iflaVFTrust     = 9
+
+	iflaInfoKind      = 1
+	iflaInfoData      = 2
+	iflaInfoSlaveKind = 4
+
+	iflaVLANID       = 1
+	iflaVLANProtocol = 5
+
+	iflaMacvlanMode = 1
+
+	iflaIPoIBPKey = 1
+	iflaIPoIBMode = 2
+
+	ifInfoMsgLen = 16
+
+	// vfSettingUnsupported is reported for spoof-check and trust when the
+	// driver does not implement the setting.
+	vfSettingUnsupported = 0xffffffff
+)
+
+// VF link states (IFLA_VF_LINK_STATE_*).
+var vfLinkStates = map[uint32]string{
+	0: "auto",
+	1: "enable",
+	2: "disable",
+}
+
+// VLAN protocols (IFLA_VLAN_PROTOCOL, an ethertype).
+var vlanProtocols = map[uint16]string{
+	0x8100: "802.1Q",
+	0x88a8: "802.1ad",
+}
+
+// macvlan modes (MACVLAN_MODE_*).
+var macvlanModes = map[uint32]string{
+	1:  "private",
+	2:  "vepa",
+	4:  "bridge",
+	8:  "passthru",
+	16: "source",
+}
+
+// IPoIB modes (IPOIB_MODE_*).
+var ipoibModes = map[uint16]string{
+	0: "datagram",
+	1: "connected",
+}
+
+// LinkInfo is the link type of a netdev and its type-specific settings
+// from IFLA_LINKINFO. Only the fields of the netdev's kind are set.
+type LinkInfo struct {
+	// Kind is the link type (e.g., "bond", "bridge", "vlan", "macvlan",
+	// "ipoib", "openvswitch"); it is empty for physical netdevs.
+	Kind string `json:"kind,omitempty"`
+	// SlaveKind is the kind of the master the netdev is enslaved to.
+	SlaveKind    string `json:"slave_kind,omitempty"`
+	VLANID       int    `json:"vlan_id,omitempty"`
+	VLANProtocol string `json:"vlan_protocol,omitempty"`
+	MacvlanMode  string `json:"macvlan_mode,omitempty"`
+	PKey         int    `json:"pkey,omitempty"`
+	IPoIBMode    string `json:"ipoib_mode,omitempty"`
+}
+
+// VFInfo is the configuration of an SR-IOV virtual function as reported by
+// its physical function's netdev in IFLA_VFINFO_LIST.
+type VFInfo struct {
+	Index int    `json:"index"`
+	MAC   string `json:"mac,omitempty"`
+	VLAN  int    `json:"vlan,omitempty"`
+	QoS   int    `json:"qos,omitempty"`
+	// SpoofCheck and Trust are nil when the driver does not support them.
+	SpoofCheck *bool  `json:"spoof_check,omitempty"`
+	Trust      *bool  `json:"trust,omitempty"`
+	LinkState  string `json:"link_state,omitempty"`
+	// MinTxRate and MaxTxRate are in Mbps; 0 means unlimited.
+	MinTxRate int `json:"min_tx_rate,omitempty"`
+	MaxTxRate int `json:"max_tx_rate,omitempty"`
+}
+
+// getLinkRequest builds an RTM_GETLINK request for ifName. extMask asks
+// the kernel for optional information (e.g., rtextFilterVF for VFs).
+func getLinkRequest(ifName string, extMask uint32) Message {
+	var attrs AttributeEncoder
+	attrs.String(iflaIfname, ifName)
+	if extMask != 0 {
+		attrs.Uint32(iflaExtMask, extMask)
+	}
+
+	// struct ifinfomsg is all zeroes: any family, look up by name
+	data := make([]byte, ifInfoMsgLen)
+	data = append(data, attrs.Encode()...)
+
+	return Message{
+		Header: Header{Type: rtmGetLink},
+		Data:   data,
+	}
+}
+
+// linkAttributes returns the attributes of an RTM_NEWLINK message.
+func linkAttributes(m Message) ([]Attribute, error) {
+	if m.Header.Type != rtmNewLink {
+		return nil, fmt.Errorf("unexpected rtnetlink message type %d", m.Header.Type)
+	}
+	if len(m.Data) < ifInfoMsgLen {
+		return nil, fmt.Errorf("truncated ifinfomsg")
+	}
+	return ParseAttributes(m.Data[ifInfoMsgLen:])
+}
+
+// parseLinkVFs extracts the VF list from an RTM_NEWLINK message.
+func parseLinkVFs(m Message) ([]VFInfo, error) {
+	attrs, err := linkAttributes(m)
+	if err != nil {
+		return nil, err
+	}
+	for _, attr := range attrs {
+		if attr.Type == iflaVFInfoList {
+			return ParseVFInfoList(attr.Data)
+		}
+	}
+	return nil, nil
+}
+
+// ParseVFInfoList parses the payload of an IFLA_VFINFO_LIST attribute.
+func ParseVFInfoList(data []byte) ([]VFInfo, error) {
+	list, err := ParseAttributes(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VF info list: %w", err)
+	}
+
+	var vfs []VFInfo
+	for _, entry := range list {
+		if entry.Type != iflaVFInfo {
+			continue
+		}
+		attrs, err := ParseAttributes(entry.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid VF info: %w", err)
+		}
+
+		var vf VFInfo
+		for _, attr := range attrs {
+			// Every VF attribute starts with the u32 VF index
+			if len(attr.Data) < 4 {
+				continue
+			}
+			vf.Index = int(attr.Uint32())
+			fields := attr.Data[4:]
+
+			switch attr.Type {
+			case iflaVFMAC:
+				if len(fields) >= 6 {
+					vf.MAC = net.HardwareAddr(fields[:6]).String()
+				}
+			case iflaVFVLAN:
+				if len(fields) >= 8 {
+					vf.VLAN = int(binary.NativeEndian.Uint32(fields[0:4]))
+					vf.QoS = int(binary.NativeEndian.Uint32(fields[4:8]))
+				}
+			case iflaVFSpoofChk:
+				vf.SpoofCheck = vfSetting(fields)
+			case iflaVFTrust:
+				vf.Trust = vfSetting(fields)
+			case iflaVFLinkState:
+				if len(fields) >= 4 {
+					vf.LinkState = vfLinkStates[binary.NativeEndian.Uint32(fields)]
+				}
+			case iflaVFRate:
+				if len(fields) >= 8 {
+					vf.MinTxRate = int(binary.NativeEndian.Uint32(fields[0:4]))
+					vf.MaxTxRate = int(binary.NativeEndian.Uint32(fields[4:8]))
+				}
+			}
+		}
+		vfs = append(vfs, vf)
+	}
+
+	return vfs, nil
+}
+
+// vfSetting decodes a u32 on/off VF setting, returning nil if unsupported.
+func vfSetting(fields []byte) *bool {
+	if len(fields) < 4 {
+		return nil
+	}
+	value := binary.NativeEndian.Uint32(fields)
+	if value == vfSettingUnsupported {
+		return nil
+	}
+	enabled := value != 0
+	return &enabled
+}
+
+// parseLinkInfo extracts the link type from an RTM_NEWLINK message.
+func parseLinkInfo(m Message) (*LinkInfo, error) {
+	attrs, err := linkAttributes(m)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &LinkInfo{}
+	for _, attr := range attrs {
+		if attr.Type != iflaLinkInfo {
+			continue
+		}
+		nested, err := ParseAttributes(attr.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link info: %w", err)
+		}
+		// IFLA_INFO_KIND precedes IFLA_INFO_DATA, which depends on it
+		var data []byte
+		for _, a := range nested {
+			switch a.Type {
+			case iflaInfoKind:
+				info.Kind = a.String()
+			case iflaInfoSlaveKind:
+				info.SlaveKind = a.String()
+			case iflaInfoData:
+				data = a.Data
+			}
+		}
+		if data != nil {
+			if err := parseLinkInfoData(info, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return info, nil
+}
+
+// parseLinkInfoData parses the IFLA_INFO_DATA of the kinds the agent
+// reports.
+func parseLinkInfoData(info *LinkInfo, data []byte) error {
+	attrs, err := ParseAttributes(data)
+	if err != nil {
+		return fmt.Errorf("invalid %s link data: %w", info.Kind, err)
+	}
+
+	for _, attr := range attrs {
+		switch {
+		case info.Kind == "vlan" && attr.Type == iflaVLANID:
+			info.VLANID = int(attr.Uint16())
+		case info.Kind == "vlan" && attr.Type == iflaVLANProtocol && len(attr.Data) >= 2:
+			// The protocol is an ethertype in network byte order
+			info.VLANProtocol = vlanProtocols[binary.BigEndian.Uint16(attr.Data)]
+		case (info.Kind == "macvlan" || info.Kind == "macvtap") && attr.Type == iflaMacvlanMode:
+			info.MacvlanMode = macvlanModes[attr.Uint32()]
+		case info.Kind == "ipoib" && attr.Type == iflaIPoIBPKey:
+			info.PKey = int(attr.Uint16())
+		case info.Kind == "ipoib" && attr.Type == iflaIPoIBMode:
+			info.IPoIBMode = ipoibModes[attr.Uint16()]
+		}
+	}
+	return nil
+}
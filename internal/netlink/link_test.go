@@ -0,0 +1,235 @@
+package netlink
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func u32s(values ...uint32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.NativeEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+func vfMAC(vf uint32, mac []byte) []byte {
+	b := u32s(vf)
+	padded := make([]byte, 32)
+	copy(padded, mac)
+	return append(b, padded...)
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func TestParseVFInfoList(t *testing.T) {
+	var list AttributeEncoder
+	list.Nested(iflaVFInfo, func(e *AttributeEncoder) {
+		e.Bytes(iflaVFMAC, vfMAC(0, []byte{0x02, 0x11, 0x22, 0x33, 0x44, 0x55}))
+		e.Bytes(iflaVFVLAN, u32s(0, 100, 3))
+		e.Bytes(iflaVFRate, u32s(0, 0, 10000))
+		e.Bytes(iflaVFSpoofChk, u32s(0, 1))
+		e.Bytes(iflaVFLinkState, u32s(0, 0))
+		e.Bytes(iflaVFTrust, u32s(0, 0))
+	})
+	list.Nested(iflaVFInfo, func(e *AttributeEncoder) {
+		e.Bytes(iflaVFMAC, vfMAC(1, nil))
+		e.Bytes(iflaVFVLAN, u32s(1, 0, 0))
+		e.Bytes(iflaVFSpoofChk, u32s(1, vfSettingUnsupported))
+		e.Bytes(iflaVFLinkState, u32s(1, 2))
+		e.Bytes(iflaVFTrust, u32s(1, 1))
+	})
+
+	vfs, err := ParseVFInfoList(list.Encode())
+	if err != nil {
+		t.Fatalf("ParseVFInfoList() error = %v", err)
+	}
+
+	want := []VFInfo{
+		{
+			Index:      0,
+			MAC:        "02:11:22:33:44:55",
+			VLAN:       100,
+			QoS:        3,
+			SpoofCheck: boolPtr(true),
+			Trust:      boolPtr(false),
+			LinkState:  "auto",
+			MaxTxRate:  10000,
+		},
+		{
+			Index:     1,
+			MAC:       "00:00:00:00:00:00",
+			Trust:     boolPtr(true),
+			LinkState: "disable",
+		},
+	}
+	if !reflect.DeepEqual(vfs, want) {
+		t.Errorf("ParseVFInfoList() = %+v, want %+v", vfs, want)
+	}
+}
+
+func TestParseVFInfoList_Invalid(t *testing.T) {
+	// Attribute length larger than the buffer
+	data := []byte{0xff, 0x00, 0x01, 0x00}
+	if _, err := ParseVFInfoList(data); err == nil {
+		t.Error("ParseVFInfoList() expected error for truncated attribute")
+	}
+}
+
+func TestGetLinkRequest(t *testing.T) {
+	m := getLinkRequest("ens1f0np0", rtextFilterVF)
+	if m.Header.Type != rtmGetLink {
+		t.Errorf("Type = %d, want %d", m.Header.Type, rtmGetLink)
+	}
+
+	attrs, err := ParseAttributes(m.Data[ifInfoMsgLen:])
+	if err != nil {
+		t.Fatalf("ParseAttributes() error = %v", err)
+	}
+	if len(attrs) != 2 {
+		t.Fatalf("got %d attributes, want 2", len(attrs))
+	}
+	if attrs[0].Type != iflaIfname || attrs[0].String() != "ens1f0np0" {
+		t.Errorf("attrs[0] = %d %q, want IFLA_IFNAME ens1f0np0", attrs[0].Type, attrs[0].String())
+	}
+	if attrs[1].Type != iflaExtMask || attrs[1].Uint32() != rtextFilterVF {
+		t.Errorf("attrs[1] = %d %d, want IFLA_EXT_MASK RTEXT_FILTER_VF", attrs[1].Type, attrs[1].Uint32())
+	}
+}
+
+func TestParseLinkVFs(t *testing.T) {
+	var list AttributeEncoder
+	list.Nested(iflaVFInfo, func(e *AttributeEncoder) {
+		e.Bytes(iflaVFVLAN, u32s(3, 42, 0))
+	})
+	var attrs AttributeEncoder
+	attrs.String(iflaIfname, "ens1f0np0")
+	attrs.Nested(iflaVFInfoList, func(e *AttributeEncoder) {
+		e.buf = append(e.buf, list.Encode()...)
+	})
+
+	m := Message{
+		Header: Header{Type: rtmNewLink},
+		Data:   append(make([]byte, ifInfoMsgLen), attrs.Encode()...),
+	}
+
+	vfs, err := parseLinkVFs(m)
+	if err != nil {
+		t.Fatalf("parseLinkVFs() error = %v", err)
+	}
+	if len(vfs) != 1 || vfs[0].Index != 3 || vfs[0].VLAN != 42 {
+		t.Errorf("parseLinkVFs() = %+v, want VF 3 with VLAN 42", vfs)
+	}
+
+	m.Header.Type = rtmGetLink
+	if _, err := parseLinkVFs(m); err == nil {
+		t.Error("parseLinkVFs() expected error for non-RTM_NEWLINK message")
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	in := Message{
+		Header: Header{Type: rtmGetLink, Flags: FlagRequest | FlagAck, Sequence: 7, PID: 0},
+		Data:   []byte{1, 2, 3, 4, 5},
+	}
+
+	msgs, err := parseMessages(append(in.marshal(), in.marshal()...))
+	if err != nil {
+		t.Fatalf("parseMessages() error = %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages, want 2", len(msgs))
+	}
+	if msgs[0].Header.Length != 21 || msgs[0].Header.Sequence != 7 || !reflect.DeepEqual(msgs[0].Data, in.Data) {
+		t.Errorf("parseMessages()[0] = %+v", msgs[0])
+	}
+}
+
+func TestCheckError(t *testing.T) {
+	ack := Message{Header: Header{Type: msgError}, Data: u32s(0)}
+	if err := checkError(ack); err != nil {
+		t.Errorf("checkError(ack) = %v, want nil", err)
+	}
+
+	// -ENODEV
+	nack := Message{Header: Header{Type: msgError}, Data: u32s(^uint32(19 - 1))}
+	err := checkError(nack)
+	var nlErr *Error
+	if !errors.As(err, &nlErr) || nlErr.Errno != 19 {
+		t.Errorf("checkError(nack) = %v, want errno 19", err)
+	}
+}
+
+func TestParseLinkInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		kind string
+		data func(e *AttributeEncoder)
+		want LinkInfo
+	}{
+		{
+			name: "vlan",
+			kind: "vlan",
+			data: func(e *AttributeEncoder) {
+				e.Uint16(iflaVLANID, 100)
+				e.Bytes(iflaVLANProtocol, []byte{0x88, 0xa8})
+			},
+			want: LinkInfo{Kind: "vlan", VLANID: 100, VLANProtocol: "802.1ad"},
+		},
+		{
+			name: "macvlan",
+			kind: "macvlan",
+			data: func(e *AttributeEncoder) {
+				e.Uint32(iflaMacvlanMode, 4)
+			},
+			want: LinkInfo{Kind: "macvlan", MacvlanMode: "bridge"},
+		},
+		{
+			name: "ipoib",
+			kind: "ipoib",
+			data: func(e *AttributeEncoder) {
+				e.Uint16(iflaIPoIBPKey, 0x8001)
+				e.Uint16(iflaIPoIBMode, 1)
+			},
+			want: LinkInfo{Kind: "ipoib", PKey: 0x8001, IPoIBMode: "connected"},
+		},
+		{
+			name: "bond slave",
+			kind: "",
+			want: LinkInfo{SlaveKind: "bond"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attrs AttributeEncoder
+			attrs.String(iflaIfname, "test0")
+			attrs.Nested(iflaLinkInfo, func(e *AttributeEncoder) {
+				if tt.kind != "" {
+					e.String(iflaInfoKind, tt.kind)
+				}
+				if tt.want.SlaveKind != "" {
+					e.String(iflaInfoSlaveKind, tt.want.SlaveKind)
+				}
+				if tt.data != nil {
+					e.Nested(iflaInfoData, tt.data)
+				}
+			})
+
+			info, err := parseLinkInfo(Message{
+				Header: Header{Type: rtmNewLink},
+				Data:   append(make([]byte, ifInfoMsgLen), attrs.Encode()...),
+			})
+			if err != nil {
+				t.Fatalf("parseLinkInfo() error = %v", err)
+			}
+			if *info != tt.want {
+				t.Errorf("parseLinkInfo() = %+v, want %+v", *info, tt.want)
+			}
+		})
+	}
+}
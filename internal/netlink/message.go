@@ -0,0 +1,113 @@
+// Package netlink is a minimal netlink client for the kernel interfaces the
+// agent queries: rtnetlink links (link type and SR-IOV VF configuration) and
+// generic netlink families such as devlink and ethtool. Driver statistics
+// and driver information, which ethtool only exposes through its ioctl, are
+// read with SIOCETHTOOL.
+package netlink
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// Netlink message types and flags (linux/netlink.h).
+const (
+	msgNoop    = 0x1
+	msgError   = 0x2
+	msgDone    = 0x3
+	msgOverrun = 0x4
+
+	FlagRequest = 0x1
+	FlagMulti   = 0x2
+	FlagAck     = 0x4
+	FlagDump    = 0x300
+
+	headerLen = 16
+)
+
+// ErrNotSupported is returned on platforms without netlink.
+var ErrNotSupported = errors.New("netlink is not supported on this platform")
+
+// Header is a netlink message header (struct nlmsghdr).
+type Header struct {
+	Length   uint32
+	Type     uint16
+	Flags    uint16
+	Sequence uint32
+	PID      uint32
+}
+
+// Message is a netlink message.
+type Message struct {
+	Header Header
+	Data   []byte
+}
+
+// marshal encodes the message, filling in its length.
+func (m Message) marshal() []byte {
+	length := headerLen + len(m.Data)
+	b := make([]byte, align(length))
+	binary.NativeEndian.PutUint32(b[0:4], uint32(length))
+	binary.NativeEndian.PutUint16(b[4:6], m.Header.Type)
+	binary.NativeEndian.PutUint16(b[6:8], m.Header.Flags)
+	binary.NativeEndian.PutUint32(b[8:12], m.Header.Sequence)
+	binary.NativeEndian.PutUint32(b[12:16], m.Header.PID)
+	copy(b[headerLen:], m.Data)
+	return b
+}
+
+// parseMessages decodes the messages in a netlink datagram.
+func parseMessages(b []byte) ([]Message, error) {
+	var msgs []Message
+	for len(b) >= headerLen {
+		h := Header{
+			Length:   binary.NativeEndian.Uint32(b[0:4]),
+			Type:     binary.NativeEndian.Uint16(b[4:6]),
+			Flags:    binary.NativeEndian.Uint16(b[6:8]),
+			Sequence: binary.NativeEndian.Uint32(b[8:12]),
+			PID:      binary.NativeEndian.Uint32(b[12:16]),
+		}
+		if int(h.Length) < headerLen || int(h.Length) > len(b) {
+			return nil, fmt.Errorf("invalid netlink message length %d (%d bytes left)", h.Length, len(b))
+		}
+
+		msgs = append(msgs, Message{Header: h, Data: b[headerLen:h.Length]})
+
+		next := align(int(h.Length))
+		if next > len(b) {
+			break
+		}
+		b = b[next:]
+	}
+	return msgs, nil
+}
+
+// Error is an error returned by the kernel in an NLMSG_ERROR message.
+type Error struct {
+	// Errno is the positive errno value.
+	Errno int
+	// Message is the extended ACK message, if the kernel provided one.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("netlink: %v: %s", syscall.Errno(e.Errno), e.Message)
+	}
+	return fmt.Sprintf("netlink: %v", syscall.Errno(e.Errno))
+}
+
+// checkError decodes an NLMSG_ERROR payload. It returns nil for an ACK.
+func checkError(m Message) error {
+	if len(m.Data) < 4 {
+		return fmt.Errorf("truncated netlink error message")
+	}
+	errno := int32(binary.NativeEndian.Uint32(m.Data[0:4]))
+	if errno == 0 {
+		return nil
+	}
+	return &Error{Errno: int(-errno)}
+}
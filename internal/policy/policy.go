@@ -0,0 +1,431 @@
+package policy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/filanov/netctrl-agent/internal/instruction"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+const (
+	// ActionAllow permits an instruction.
+	ActionAllow = "allow"
+	// ActionDeny rejects an instruction with a POLICY_DENIED result.
+	ActionDeny = "deny"
+)
+
+// Policy is a local execution policy evaluated before instructions are dispatched.
+// Rules are evaluated in order and the first matching rule decides; if no rule
+// matches, DefaultAction applies.
+type Policy struct {
+	Name            string `json:"name"`
+	DefaultAction   string `json:"default_action"`
+	MaintenanceMode bool   `json:"maintenance_mode"`
+	// MaintenanceFile enables maintenance mode while the file exists,
+	// so host owners can toggle it without editing the policy.
+	MaintenanceFile string `json:"maintenance_file,omitempty"`
+	Rules           []Rule `json:"rules"`
+
+	digest string
+	// now is the clock used by Authorize; nil means time.Now.
+	now func() time.Time
+}
+
+// Rule allows or denies instructions that match all of its conditions.
+// Empty conditions match everything.
+type Rule struct {
+	Name             string       `json:"name"`
+	Action           string       `json:"action"`
+	InstructionTypes []string     `json:"instruction_types,omitempty"`
+	TimeWindows      []TimeWindow `json:"time_windows,omitempty"`
+	Devices          *DeviceMatch `json:"devices,omitempty"`
+	// MaintenanceMode restricts the rule to when maintenance mode is on (true) or off (false).
+	MaintenanceMode *bool `json:"maintenance_mode,omitempty"`
+
+	types map[v1.InstructionType]bool
+}
+
+// TimeWindow is a daily time range, optionally limited to weekdays.
+// A window whose end is before its start spans midnight; start and end
+// must differ.
+type TimeWindow struct {
+	Days     []string `json:"days,omitempty"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Timezone string   `json:"timezone,omitempty"`
+
+	start    int
+	end      int
+	days     map[time.Weekday]bool
+	location *time.Location
+}
+
+// DeviceMatch matches devices referenced by an instruction payload.
+// Patterns use shell glob syntax (e.g., "0000:03:00.*", "ens1f*").
+type DeviceMatch struct {
+	PCIAddresses []string `json:"pci_addresses,omitempty"`
+	Interfaces   []string `json:"interfaces,omitempty"`
+}
+
+// Decision is the outcome of evaluating an instruction against the policy.
+type Decision struct {
+	Allowed bool
+	Rule    string
+	Reason  string
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Load reads and validates a policy file.
+func Load(filename string) (*Policy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse parses and validates a JSON policy document.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+
+	if p.DefaultAction == "" {
+		p.DefaultAction = ActionAllow
+	}
+	if err := validateAction(p.DefaultAction); err != nil {
+		return nil, fmt.Errorf("default_action: %w", err)
+	}
+
+	for i := range p.Rules {
+		if err := p.Rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i, p.Rules[i].Name, err)
+		}
+	}
+
+	hash := sha256.Sum256(data)
+	p.digest = fmt.Sprintf("%x", hash[:8])
+	p.now = time.Now
+
+	return &p, nil
+}
+
+// Digest returns a short hash of the policy document.
+func (p *Policy) Digest() string {
+	return p.digest
+}
+
+// Summary returns a one-line description of the active policy suitable for
+// reporting to the server.
+func (p *Policy) Summary() string {
+	name := p.Name
+	if name == "" {
+		name = "unnamed"
+	}
+	return fmt.Sprintf("name=%s digest=%s default=%s rules=%d maintenance=%t",
+		name, p.digest, p.DefaultAction, len(p.Rules), p.InMaintenance())
+}
+
+// InMaintenance reports whether maintenance mode is currently active.
+func (p *Policy) InMaintenance() bool {
+	if p.MaintenanceMode {
+		return true
+	}
+	if p.MaintenanceFile != "" {
+		if _, err := os.Stat(p.MaintenanceFile); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate decides whether the instruction is allowed at the given time.
+func (p *Policy) Evaluate(inst *v1.Instruction, now time.Time) Decision {
+	maintenance := p.InMaintenance()
+	devices := extractDevices(inst.Payload)
+
+	for _, rule := range p.Rules {
+		if !rule.matches(inst.Type, now, maintenance, devices) {
+			continue
+		}
+		return Decision{
+			Allowed: rule.Action == ActionAllow,
+			Rule:    rule.Name,
+			Reason:  fmt.Sprintf("matched rule %q", rule.Name),
+		}
+	}
+
+	return Decision{
+		Allowed: p.DefaultAction == ActionAllow,
+		Reason:  "default action",
+	}
+}
+
+// Authorize implements instruction.Authorizer.
+func (p *Policy) Authorize(ctx context.Context, inst *v1.Instruction) error {
+	now := time.Now
+	if p.now != nil {
+		now = p.now
+	}
+	decision := p.Evaluate(inst, now())
+	if decision.Allowed {
+		return nil
+	}
+	return instruction.NewStatusError(instruction.StatusPolicyDenied,
+		"instruction %s (%s) denied by local policy: %s",
+		inst.Id, instruction.TypeName(inst.Type), decision.Reason)
+}
+
+// compile validates the rule and prepares it for evaluation.
+func (r *Rule) compile() error {
+	if err := validateAction(r.Action); err != nil {
+		return err
+	}
+
+	r.types = make(map[v1.InstructionType]bool)
+	for _, name := range r.InstructionTypes {
+		if name == "*" {
+			r.types = nil
+			break
+		}
+		t, err := instruction.ParseType(name)
+		if err != nil {
+			return err
+		}
+		r.types[t] = true
+	}
+
+	for i := range r.TimeWindows {
+		if err := r.TimeWindows[i].compile(); err != nil {
+			return fmt.Errorf("time window %d: %w", i, err)
+		}
+	}
+
+	if r.Devices != nil {
+		for _, pattern := range append(append([]string{}, r.Devices.PCIAddresses...), r.Devices.Interfaces...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid device pattern %q: %w", pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// matches reports whether all rule conditions hold.
+func (r *Rule) matches(t v1.InstructionType, now time.Time, maintenance bool, devices deviceRefs) bool {
+	if len(r.types) > 0 && !r.types[t] {
+		return false
+	}
+
+	if r.MaintenanceMode != nil && *r.MaintenanceMode != maintenance {
+		return false
+	}
+
+	if len(r.TimeWindows) > 0 {
+		inWindow := false
+		for _, w := range r.TimeWindows {
+			if w.contains(now) {
+				inWindow = true
+				break
+			}
+		}
+		if !inWindow {
+			return false
+		}
+	}
+
+	if r.Devices != nil && !r.Devices.matches(devices) {
+		return false
+	}
+
+	return true
+}
+
+// compile parses the window's clock times, weekdays and timezone.
+func (w *TimeWindow) compile() error {
+	var err error
+	if w.start, err = parseClock(w.Start); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	if w.end, err = parseClock(w.End); err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+	if w.start == w.end {
+		return fmt.Errorf("start and end are both %s; the window would be empty", w.Start)
+	}
+
+	w.location = time.Local
+	if w.Timezone != "" {
+		if w.location, err = time.LoadLocation(w.Timezone); err != nil {
+			return fmt.Errorf("timezone: %w", err)
+		}
+	}
+
+	if len(w.Days) > 0 {
+		w.days = make(map[time.Weekday]bool)
+		for _, day := range w.Days {
+			key := strings.ToLower(day)
+			if len(key) > 3 {
+				key = key[:3]
+			}
+			weekday, ok := weekdays[key]
+			if !ok {
+				return fmt.Errorf("unknown day: %s", day)
+			}
+			w.days[weekday] = true
+		}
+	}
+
+	return nil
+}
+
+// contains reports whether t falls inside the window.
+func (w *TimeWindow) contains(t time.Time) bool {
+	local := t.In(w.location)
+	minute := local.Hour()*60 + local.Minute()
+	day := local.Weekday()
+
+	if w.start <= w.end {
+		return w.onDay(day) && minute >= w.start && minute < w.end
+	}
+
+	// Window spans midnight: the part after midnight belongs to the previous day.
+	if minute >= w.start {
+		return w.onDay(day)
+	}
+	if minute < w.end {
+		return w.onDay((day + 6) % 7)
+	}
+	return false
+}
+
+func (w *TimeWindow) onDay(day time.Weekday) bool {
+	return w.days == nil || w.days[day]
+}
+
+// matches reports whether any referenced device matches the patterns.
+// Instructions that reference no devices never match a device condition.
+func (d *DeviceMatch) matches(devices deviceRefs) bool {
+	for _, addr := range devices.pciAddresses {
+		if matchAny(d.PCIAddresses, addr) {
+			return true
+		}
+	}
+	for _, name := range devices.interfaces {
+		if matchAny(d.Interfaces, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// deviceRefs holds the devices an instruction payload refers to.
+type deviceRefs struct {
+	pciAddresses []string
+	interfaces   []string
+}
+
+// extractDevices collects PCI addresses and interface names from well-known
+// keys anywhere in a JSON payload.
+func extractDevices(payload string) deviceRefs {
+	var refs deviceRefs
+	if payload == "" {
+		return refs
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal([]byte(payload), &doc); err != nil {
+		return refs
+	}
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for key, child := range val {
+				switch key {
+				case "pci_address", "pci_addresses":
+					refs.pciAddresses = append(refs.pciAddresses, stringValues(child)...)
+				case "interface", "interface_name", "interfaces":
+					refs.interfaces = append(refs.interfaces, stringValues(child)...)
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range val {
+				walk(child)
+			}
+		case string:
+			// Nested instructions (e.g., batch steps) may carry their payload as a JSON string.
+			if strings.HasPrefix(strings.TrimSpace(val), "{") {
+				nested := extractDevices(val)
+				refs.pciAddresses = append(refs.pciAddresses, nested.pciAddresses...)
+				refs.interfaces = append(refs.interfaces, nested.interfaces...)
+			}
+		}
+	}
+	walk(doc)
+
+	return refs
+}
+
+func stringValues(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return []string{val}
+	case []interface{}:
+		var out []string
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func validateAction(action string) error {
+	switch action {
+	case ActionAllow, ActionDeny:
+		return nil
+	default:
+		return fmt.Errorf("invalid action %q (expected %q or %q)", action, ActionAllow, ActionDeny)
+	}
+}
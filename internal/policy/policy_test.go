@@ -0,0 +1,230 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/filanov/netctrl-agent/internal/instruction"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+const storagePolicy = `{
+  "name": "storage-nodes",
+  "default_action": "allow",
+  "rules": [
+    {
+      "name": "maintenance-allows-everything",
+      "action": "allow",
+      "maintenance_mode": true
+    },
+    {
+      "name": "no-link-changes-business-hours",
+      "action": "deny",
+      "instruction_types": ["POLL_INTERVAL"],
+      "time_windows": [{"days": ["mon", "tue", "wed", "thu", "fri"], "start": "09:00", "end": "17:00", "timezone": "UTC"}],
+      "devices": {"pci_addresses": ["0000:03:00.*"], "interfaces": ["ens1f*"]}
+    },
+    {
+      "name": "no-hardware-collection-at-night",
+      "action": "deny",
+      "instruction_types": ["INSTRUCTION_TYPE_COLLECT_HARDWARE"],
+      "time_windows": [{"start": "22:00", "end": "06:00", "timezone": "UTC"}]
+    }
+  ]
+}`
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+	}{
+		{name: "malformed json", policy: `{`},
+		{name: "bad default action", policy: `{"default_action": "maybe"}`},
+		{name: "bad rule action", policy: `{"rules": [{"action": "block"}]}`},
+		{name: "unknown instruction type", policy: `{"rules": [{"action": "deny", "instruction_types": ["REBOOT"]}]}`},
+		{name: "bad time", policy: `{"rules": [{"action": "deny", "time_windows": [{"start": "9am", "end": "17:00"}]}]}`},
+		{name: "bad day", policy: `{"rules": [{"action": "deny", "time_windows": [{"days": ["someday"], "start": "09:00", "end": "17:00"}]}]}`},
+		{name: "empty window", policy: `{"rules": [{"action": "deny", "time_windows": [{"start": "09:00", "end": "09:00"}]}]}`},
+		{name: "bad timezone", policy: `{"rules": [{"action": "deny", "time_windows": [{"start": "09:00", "end": "17:00", "timezone": "Nowhere/City"}]}]}`},
+		{name: "bad pattern", policy: `{"rules": [{"action": "deny", "devices": {"interfaces": ["ens["]}}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.policy)); err == nil {
+				t.Error("Parse() expected error")
+			}
+		})
+	}
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	p, err := Parse([]byte(storagePolicy))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	// 2026-10-14 is a Wednesday.
+	businessHours := time.Date(2026, 10, 14, 10, 30, 0, 0, time.UTC)
+	evening := time.Date(2026, 10, 14, 19, 0, 0, 0, time.UTC)
+	saturday := time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC)
+	afterMidnight := time.Date(2026, 10, 15, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		instruction *v1.Instruction
+		now         time.Time
+		allowed     bool
+		rule        string
+	}{
+		{
+			name:        "device in window is denied",
+			instruction: &v1.Instruction{Type: v1.InstructionType_INSTRUCTION_TYPE_POLL_INTERVAL, Payload: `{"pci_address": "0000:03:00.1"}`},
+			now:         businessHours,
+			allowed:     false,
+			rule:        "no-link-changes-business-hours",
+		},
+		{
+			name:        "interface list in window is denied",
+			instruction: &v1.Instruction{Type: v1.InstructionType_INSTRUCTION_TYPE_POLL_INTERVAL, Payload: `{"interfaces": ["eth0", "ens1f1"]}`},
+			now:         businessHours,
+			allowed:     false,
+			rule:        "no-link-changes-business-hours",
+		},
+		{
+			name:        "other device is allowed",
+			instruction: &v1.Instruction{Type: v1.InstructionType_INSTRUCTION_TYPE_POLL_INTERVAL, Payload: `{"pci_address": "0000:81:00.0"}`},
+			now:         businessHours,
+			allowed:     true,
+		},
+		{
+			name:        "no device reference is allowed",
+			instruction: &v1.Instruction{Type: v1.InstructionType_INSTRUCTION_TYPE_POLL_INTERVAL, Payload: `{"interval_seconds": 30}`},
+			now:         businessHours,
+			allowed:     true,
+		},
+		{
+			name:        "outside window is allowed",
+			instruction: &v1.Instruction{Type: v1.InstructionType_INSTRUCTION_TYPE_POLL_INTERVAL, Payload: `{"pci_address": "0000:03:00.1"}`},
+			now:         evening,
+			allowed:     true,
+		},
+		{
+			name:        "weekend is allowed",
+			instruction: &v1.Instruction{Type: v1.InstructionType_INSTRUCTION_TYPE_POLL_INTERVAL, Payload: `{"pci_address": "0000:03:00.1"}`},
+			now:         saturday,
+			allowed:     true,
+		},
+		{
+			name:        "window spanning midnight",
+			instruction: &v1.Instruction{Type: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE},
+			now:         afterMidnight,
+			allowed:     false,
+			rule:        "no-hardware-collection-at-night",
+		},
+		{
+			name:        "other instruction type is allowed",
+			instruction: &v1.Instruction{Type: v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK},
+			now:         afterMidnight,
+			allowed:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := p.Evaluate(tt.instruction, tt.now)
+			if decision.Allowed != tt.allowed {
+				t.Errorf("Evaluate() allowed = %v, want %v (%s)", decision.Allowed, tt.allowed, decision.Reason)
+			}
+			if decision.Rule != tt.rule {
+				t.Errorf("Evaluate() rule = %q, want %q", decision.Rule, tt.rule)
+			}
+		})
+	}
+}
+
+func TestPolicy_MaintenanceFile(t *testing.T) {
+	dir := t.TempDir()
+	flagFile := filepath.Join(dir, "maintenance")
+
+	p, err := Parse([]byte(`{
+		"default_action": "deny",
+		"maintenance_file": "` + flagFile + `",
+		"rules": [{"name": "maintenance", "action": "allow", "maintenance_mode": true}]
+	}`))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	inst := &v1.Instruction{Type: v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE}
+
+	if p.Evaluate(inst, time.Now()).Allowed {
+		t.Error("expected instruction to be denied outside maintenance")
+	}
+
+	if err := os.WriteFile(flagFile, nil, 0o644); err != nil {
+		t.Fatalf("failed to create maintenance file: %v", err)
+	}
+
+	if !p.InMaintenance() {
+		t.Error("InMaintenance() = false with maintenance file present")
+	}
+	if !p.Evaluate(inst, time.Now()).Allowed {
+		t.Error("expected instruction to be allowed in maintenance")
+	}
+}
+
+func TestPolicy_Authorize(t *testing.T) {
+	p, err := Parse([]byte(`{"default_action": "deny"}`))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	err = p.Authorize(context.Background(), &v1.Instruction{
+		Id:   "test-1",
+		Type: v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK,
+	})
+	if err == nil {
+		t.Fatal("Authorize() expected error")
+	}
+	if status := instruction.StatusOf(err); status != instruction.StatusPolicyDenied {
+		t.Errorf("StatusOf() = %s, want %s", status, instruction.StatusPolicyDenied)
+	}
+}
+
+func TestPolicy_Authorize_Literal(t *testing.T) {
+	// A policy not built by Parse uses the real clock
+	p := &Policy{DefaultAction: ActionAllow}
+	if err := p.Authorize(context.Background(), &v1.Instruction{
+		Id:   "test-1",
+		Type: v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK,
+	}); err != nil {
+		t.Errorf("Authorize() returned error: %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(filename, []byte(storagePolicy), 0o644); err != nil {
+		t.Fatalf("failed to write policy: %v", err)
+	}
+
+	p, err := Load(filename)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if p.Digest() == "" {
+		t.Error("Digest() is empty")
+	}
+	if p.Name != "storage-nodes" {
+		t.Errorf("Name = %s, want storage-nodes", p.Name)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Load() expected error for missing file")
+	}
+}
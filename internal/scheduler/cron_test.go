@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParseCron(expr); err == nil {
+				t.Errorf("ParseCron(%q) expected error", expr)
+			}
+		})
+	}
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	// 2026-10-14 is a Wednesday.
+	base := time.Date(2026, 10, 14, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "every minute",
+			expr: "* * * * *",
+			from: base,
+			want: base.Add(time.Minute),
+		},
+		{
+			name: "hourly",
+			expr: "0 * * * *",
+			from: base,
+			want: time.Date(2026, 10, 14, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "every 15 minutes",
+			expr: "*/15 * * * *",
+			from: base.Add(10 * time.Second),
+			want: time.Date(2026, 10, 14, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			name: "daily at 02:00",
+			expr: "0 2 * * *",
+			from: base,
+			want: time.Date(2026, 10, 15, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "weekdays at 09:30",
+			expr: "30 9 * * 1-5",
+			from: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), // Friday
+			want: time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC), // Monday
+		},
+		{
+			name: "sunday as 7",
+			expr: "0 0 * * 7",
+			from: base,
+			want: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "first of month or monday",
+			expr: "0 0 1 * 1",
+			from: base,
+			want: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// A day field starting with "*" is unrestricted, so both day
+			// fields must match rather than either
+			name: "odd days that are mondays",
+			expr: "0 0 */2 * 1",
+			from: base,
+			want: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "yearly across year boundary",
+			expr: "0 0 1 1 *",
+			from: base,
+			want: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "never matches",
+			expr: "0 0 30 2 *",
+			from: base,
+			want: time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) failed: %v", tt.expr, err)
+			}
+			if got := cron.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
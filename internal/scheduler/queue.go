@@ -0,0 +1,237 @@
+package scheduler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+// MaxQueuedResults and MaxQueuedBytes bound the result queue; the oldest
+// results are dropped when the server is unreachable for a long time.
+const (
+	MaxQueuedResults = 1000
+	MaxQueuedBytes   = 64 << 20
+)
+
+// QueuedResult is the outcome of a locally scheduled execution awaiting
+// submission to the server.
+type QueuedResult struct {
+	InstructionID string             `json:"instruction_id"`
+	Type          v1.InstructionType `json:"type"`
+	Result        string             `json:"result,omitempty"`
+	Error         string             `json:"error,omitempty"`
+	CompletedAt   time.Time          `json:"completed_at"`
+}
+
+// ResultQueue is a persistent FIFO of results waiting to be submitted. It
+// is stored as JSON Lines: a push appends one line, and the file is only
+// rewritten when results are drained or the queue exceeds its bounds.
+type ResultQueue struct {
+	// drainMu serializes drains so that a result is not submitted twice.
+	drainMu sync.Mutex
+
+	mu      sync.Mutex
+	path    string
+	results []QueuedResult
+	// sizes are the encoded sizes of results, including the newline.
+	sizes []int
+	bytes int
+	// head is the number of results ever removed from the front, so that
+	// a drain can tell which of the results it submitted are still queued.
+	head int
+}
+
+// NewResultQueue creates a queue persisted at path, loading any results
+// left over from a previous run. A line cut short by a crash while it was
+// appended is skipped.
+func NewResultQueue(path string) (*ResultQueue, error) {
+	q := &ResultQueue{path: path}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load result queue: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var result QueuedResult
+			if jsonErr := json.Unmarshal(line, &result); jsonErr == nil {
+				q.add(result, len(line))
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load result queue: %w", err)
+		}
+	}
+	return q, nil
+}
+
+// Push appends a result to the queue.
+func (q *ResultQueue) Push(result QueuedResult) error {
+	line, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.add(result, len(line))
+	if q.trim() {
+		return q.rewrite()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(q.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(q.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Drain calls submit for each queued result in order and removes the
+// results that were submitted successfully. It stops at the first failure
+// so ordering is preserved for the next attempt. The queue is not locked
+// while submit runs, so results can be pushed concurrently.
+func (q *ResultQueue) Drain(submit func(result QueuedResult) error) error {
+	q.drainMu.Lock()
+	defer q.drainMu.Unlock()
+
+	q.mu.Lock()
+	pending := append([]QueuedResult(nil), q.results...)
+	start := q.head
+	q.mu.Unlock()
+
+	submitted := 0
+	var submitErr error
+	for _, result := range pending {
+		if submitErr = submit(result); submitErr != nil {
+			break
+		}
+		submitted++
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Results trimmed by a concurrent push are already gone
+	n := start + submitted - q.head
+	if n <= 0 {
+		return submitErr
+	}
+
+	q.drop(n)
+	if err := q.rewrite(); err != nil {
+		return err
+	}
+	return submitErr
+}
+
+// Len returns the number of queued results.
+func (q *ResultQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.results)
+}
+
+// add appends a result of the given encoded size.
+func (q *ResultQueue) add(result QueuedResult, size int) {
+	q.results = append(q.results, result)
+	q.sizes = append(q.sizes, size)
+	q.bytes += size
+}
+
+// drop removes the n oldest results.
+func (q *ResultQueue) drop(n int) {
+	for _, size := range q.sizes[:n] {
+		q.bytes -= size
+	}
+	q.results = q.results[n:]
+	q.sizes = q.sizes[n:]
+	q.head += n
+}
+
+// trim drops the oldest results beyond MaxQueuedResults and MaxQueuedBytes,
+// always keeping the newest result. It reports whether any were dropped.
+func (q *ResultQueue) trim() bool {
+	n, size := 0, q.bytes
+	for len(q.results)-n > 1 && (len(q.results)-n > MaxQueuedResults || size > MaxQueuedBytes) {
+		size -= q.sizes[n]
+		n++
+	}
+	if n == 0 {
+		return false
+	}
+	q.drop(n)
+	return true
+}
+
+// rewrite atomically replaces the queue file with the queued results.
+func (q *ResultQueue) rewrite() error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, result := range q.results {
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	}
+	return writeFile(q.path, buf.Bytes())
+}
+
+// readJSONFile decodes a JSON file into v. A missing file is not an error.
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeJSONFile atomically replaces path with the JSON encoding of v.
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// writeFile atomically replaces path with data.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
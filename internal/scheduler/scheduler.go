@@ -0,0 +1,349 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filanov/netctrl-agent/internal/instruction"
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+// maxIdle is the longest the scheduler sleeps between checks, so entries
+// added while it is idle are picked up promptly.
+const maxIdle = 30 * time.Second
+
+// Executor executes instructions; *instruction.Registry satisfies it.
+type Executor interface {
+	Execute(ctx context.Context, instruction *v1.Instruction) (string, error)
+}
+
+// Recurrence describes how often an entry repeats. Exactly one of
+// IntervalSeconds and Cron may be set.
+type Recurrence struct {
+	IntervalSeconds int    `json:"interval_seconds,omitempty"`
+	Cron            string `json:"cron,omitempty"`
+}
+
+// Entry is a locally scheduled instruction.
+type Entry struct {
+	ID          string             `json:"id"`
+	Type        v1.InstructionType `json:"type"`
+	Payload     string             `json:"payload,omitempty"`
+	NotBefore   time.Time          `json:"not_before,omitempty"`
+	ExpiresAt   time.Time          `json:"expires_at,omitempty"`
+	Recurrence  *Recurrence        `json:"recurrence,omitempty"`
+	NextRun     time.Time          `json:"next_run"`
+	LastRun     time.Time          `json:"last_run,omitempty"`
+	Runs        int                `json:"runs"`
+	ScheduledAt time.Time          `json:"scheduled_at"`
+}
+
+// Validate checks the entry's recurrence and time bounds.
+func (e *Entry) Validate() error {
+	if e.ID == "" {
+		return fmt.Errorf("schedule id is required")
+	}
+	if !e.ExpiresAt.IsZero() && !e.NotBefore.IsZero() && !e.ExpiresAt.After(e.NotBefore) {
+		return fmt.Errorf("expires_at must be after not_before")
+	}
+	if e.Recurrence != nil {
+		if e.Recurrence.IntervalSeconds != 0 && e.Recurrence.Cron != "" {
+			return fmt.Errorf("recurrence must set either interval_seconds or cron, not both")
+		}
+		if e.Recurrence.IntervalSeconds < 0 {
+			return fmt.Errorf("recurrence interval_seconds must be positive")
+		}
+		if e.Recurrence.IntervalSeconds == 0 && e.Recurrence.Cron == "" {
+			return fmt.Errorf("recurrence must set interval_seconds or cron")
+		}
+		if e.Recurrence.Cron != "" {
+			if _, err := ParseCron(e.Recurrence.Cron); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// nextAfter returns the next run time after t, or the zero time if the
+// entry does not recur.
+func (e *Entry) nextAfter(t time.Time) time.Time {
+	if e.Recurrence == nil {
+		return time.Time{}
+	}
+	if e.Recurrence.IntervalSeconds > 0 {
+		return t.Add(time.Duration(e.Recurrence.IntervalSeconds) * time.Second)
+	}
+	cron, err := ParseCron(e.Recurrence.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return cron.Next(t)
+}
+
+// Scheduler executes scheduled and recurring instructions locally through
+// an Executor and queues their results for submission. Entries are persisted
+// so schedules survive agent restarts and server outages.
+type Scheduler struct {
+	mu       sync.Mutex
+	path     string
+	entries  map[string]*Entry
+	executor Executor
+	results  *ResultQueue
+	rejected *ResultQueue
+	wake     chan struct{}
+	now      func() time.Time
+}
+
+// New creates a scheduler that persists its entries in stateDir.
+func New(stateDir string, executor Executor) (*Scheduler, error) {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	results, err := NewResultQueue(filepath.Join(stateDir, "results.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	rejected, err := NewResultQueue(filepath.Join(stateDir, "rejected.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Scheduler{
+		path:     filepath.Join(stateDir, "schedule.json"),
+		entries:  make(map[string]*Entry),
+		executor: executor,
+		results:  results,
+		rejected: rejected,
+		wake:     make(chan struct{}, 1),
+		now:      time.Now,
+	}
+
+	var entries []*Entry
+	if err := readJSONFile(s.path, &entries); err != nil {
+		return nil, fmt.Errorf("failed to load schedule: %w", err)
+	}
+	for _, entry := range entries {
+		s.entries[entry.ID] = entry
+	}
+
+	return s, nil
+}
+
+// Results returns the queue of results awaiting submission.
+func (s *Scheduler) Results() *ResultQueue {
+	return s.results
+}
+
+// Rejected returns the queue of results the server refused to accept. They
+// are kept for inspection and never resubmitted.
+func (s *Scheduler) Rejected() *ResultQueue {
+	return s.rejected
+}
+
+// Add adds or replaces a scheduled entry.
+func (s *Scheduler) Add(entry Entry) (*Entry, error) {
+	if err := entry.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	entry.ScheduledAt = now
+	entry.Runs = 0
+	entry.LastRun = time.Time{}
+	entry.NextRun = now
+	if entry.NotBefore.After(now) {
+		entry.NextRun = entry.NotBefore
+	}
+	if !entry.ExpiresAt.IsZero() && !entry.ExpiresAt.After(entry.NextRun) {
+		return nil, fmt.Errorf("schedule %s expires before its first run", entry.ID)
+	}
+
+	s.mu.Lock()
+	s.entries[entry.ID] = &entry
+	err := s.saveLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify()
+	added := entry
+	return &added, nil
+}
+
+// Remove deletes a scheduled entry. It returns false if no entry has that ID.
+func (s *Scheduler) Remove(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[id]; !ok {
+		return false, nil
+	}
+	delete(s.entries, id)
+	return true, s.saveLocked()
+}
+
+// Entries returns a snapshot of the scheduled entries ordered by next run.
+func (s *Scheduler) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].NextRun.Before(entries[j].NextRun)
+	})
+	return entries
+}
+
+// Run executes due entries until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		s.RunDue(ctx)
+
+		timer := time.NewTimer(s.untilNext())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// RunDue executes all entries whose next run time has passed. Expired
+// entries are removed without running. Missed runs of recurring entries are
+// not replayed; the next run is computed from the current time.
+func (s *Scheduler) RunDue(ctx context.Context) {
+	now := s.now()
+
+	s.mu.Lock()
+	var due []Entry
+	for id, entry := range s.entries {
+		if !entry.ExpiresAt.IsZero() && !now.Before(entry.ExpiresAt) {
+			log.Printf("Scheduled instruction %s expired, removing", id)
+			delete(s.entries, id)
+			continue
+		}
+		if !entry.NextRun.After(now) {
+			due = append(due, *entry)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextRun.Before(due[j].NextRun)
+	})
+
+	for _, entry := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		s.runEntry(ctx, entry, now)
+	}
+
+	s.mu.Lock()
+	if err := s.saveLocked(); err != nil {
+		log.Printf("Failed to persist schedule: %v", err)
+	}
+	s.mu.Unlock()
+}
+
+// runEntry executes one due entry, queues its result and reschedules it.
+func (s *Scheduler) runEntry(ctx context.Context, entry Entry, now time.Time) {
+	inst := &v1.Instruction{
+		Id:      fmt.Sprintf("%s@%s", entry.ID, now.UTC().Format(time.RFC3339)),
+		Type:    entry.Type,
+		Payload: entry.Payload,
+	}
+
+	log.Printf("Running scheduled instruction: id=%s, type=%s", inst.Id, instruction.TypeName(inst.Type))
+	result, err := s.executor.Execute(ctx, inst)
+
+	queued := QueuedResult{
+		InstructionID: inst.Id,
+		Type:          inst.Type,
+		Result:        result,
+		CompletedAt:   s.now(),
+	}
+	if err != nil {
+		log.Printf("Scheduled instruction %s failed: %v", inst.Id, err)
+		queued.Result = ""
+		queued.Error = err.Error()
+	}
+	if pushErr := s.results.Push(queued); pushErr != nil {
+		log.Printf("Failed to queue result for scheduled instruction %s: %v", inst.Id, pushErr)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.entries[entry.ID]
+	if !ok || !current.ScheduledAt.Equal(entry.ScheduledAt) {
+		// Removed or replaced while running
+		return
+	}
+
+	current.Runs++
+	current.LastRun = now
+	next := current.nextAfter(now)
+	if next.IsZero() || (!current.ExpiresAt.IsZero() && !next.Before(current.ExpiresAt)) {
+		delete(s.entries, entry.ID)
+		return
+	}
+	current.NextRun = next
+}
+
+// untilNext returns how long to sleep until the next entry is due.
+func (s *Scheduler) untilNext() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := maxIdle
+	now := s.now()
+	for _, entry := range s.entries {
+		if d := entry.NextRun.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// notify wakes the Run loop after the schedule changed.
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// saveLocked persists the entries. The caller must hold s.mu.
+func (s *Scheduler) saveLocked() error {
+	entries := make([]*Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+
+	if err := writeJSONFile(s.path, entries); err != nil {
+		return fmt.Errorf("failed to persist schedule: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/filanov/netctrl-server/pkg/api/v1"
+)
+
+// fakeExecutor records executed instruction IDs.
+type fakeExecutor struct {
+	executed []string
+	err      error
+}
+
+func (f *fakeExecutor) Execute(ctx context.Context, instruction *v1.Instruction) (string, error) {
+	f.executed = append(f.executed, instruction.Id)
+	if f.err != nil {
+		return "", f.err
+	}
+	return `{"status":"ok"}`, nil
+}
+
+func newTestScheduler(t *testing.T, dir string, executor Executor, now *time.Time) *Scheduler {
+	t.Helper()
+	s, err := New(dir, executor)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestScheduler_OneShotNotBefore(t *testing.T) {
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	executor := &fakeExecutor{}
+	s := newTestScheduler(t, t.TempDir(), executor, &now)
+
+	_, err := s.Add(Entry{
+		ID:        "inventory",
+		Type:      v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE,
+		NotBefore: now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+
+	s.RunDue(context.Background())
+	if len(executor.executed) != 0 {
+		t.Fatalf("executed before not_before: %v", executor.executed)
+	}
+
+	now = now.Add(time.Hour)
+	s.RunDue(context.Background())
+	if len(executor.executed) != 1 {
+		t.Fatalf("executed %d times, want 1", len(executor.executed))
+	}
+
+	if len(s.Entries()) != 0 {
+		t.Error("one-shot entry not removed after run")
+	}
+	if s.Results().Len() != 1 {
+		t.Errorf("queued results = %d, want 1", s.Results().Len())
+	}
+}
+
+func TestScheduler_RecurringUntilExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	executor := &fakeExecutor{}
+	s := newTestScheduler(t, t.TempDir(), executor, &now)
+
+	_, err := s.Add(Entry{
+		ID:         "counters",
+		Type:       v1.InstructionType_INSTRUCTION_TYPE_HEALTH_CHECK,
+		ExpiresAt:  now.Add(150 * time.Minute),
+		Recurrence: &Recurrence{IntervalSeconds: 3600},
+	})
+	if err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		s.RunDue(context.Background())
+		now = now.Add(time.Hour)
+	}
+
+	// Runs at 10:00, 11:00 and 12:00; 13:00 is past expiry
+	if len(executor.executed) != 3 {
+		t.Errorf("executed %d times, want 3: %v", len(executor.executed), executor.executed)
+	}
+	if len(s.Entries()) != 0 {
+		t.Error("expired entry not removed")
+	}
+}
+
+func TestScheduler_Persistence(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	executor := &fakeExecutor{err: errors.New("collection failed")}
+
+	s := newTestScheduler(t, dir, executor, &now)
+	if _, err := s.Add(Entry{
+		ID:         "daily",
+		Type:       v1.InstructionType_INSTRUCTION_TYPE_COLLECT_HARDWARE,
+		Recurrence: &Recurrence{Cron: "0 2 * * *"},
+	}); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	s.RunDue(context.Background())
+
+	// Reload from disk
+	reloaded := newTestScheduler(t, dir, executor, &now)
+	entries := reloaded.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("reloaded %d entries, want 1", len(entries))
+	}
+	if want := time.Date(2026, 10, 15, 2, 0, 0, 0, time.UTC); !entries[0].NextRun.Equal(want) {
+		t.Errorf("NextRun = %v, want %v", entries[0].NextRun, want)
+	}
+	if entries[0].Runs != 1 {
+		t.Errorf("Runs = %d, want 1", entries[0].Runs)
+	}
+
+	var drained []QueuedResult
+	if err := reloaded.Results().Drain(func(r QueuedResult) error {
+		drained = append(drained, r)
+		return nil
+	}); err != nil {
+		t.Fatalf("Drain() failed: %v", err)
+	}
+	if len(drained) != 1 || drained[0].Error == "" {
+		t.Errorf("drained = %+v, want one failed result", drained)
+	}
+	if reloaded.Results().Len() != 0 {
+		t.Error("results remain after drain")
+	}
+}
+
+func TestScheduler_AddInvalid(t *testing.T) {
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+	s := newTestScheduler(t, t.TempDir(), &fakeExecutor{}, &now)
+
+	tests := []struct {
+		name  string
+		entry Entry
+	}{
+		{name: "missing id", entry: Entry{}},
+		{name: "both recurrences", entry: Entry{ID: "x", Recurrence: &Recurrence{IntervalSeconds: 60, Cron: "* * * * *"}}},
+		{name: "empty recurrence", entry: Entry{ID: "x", Recurrence: &Recurrence{}}},
+		{name: "bad cron", entry: Entry{ID: "x", Recurrence: &Recurrence{Cron: "every hour"}}},
+		{name: "already expired", entry: Entry{ID: "x", ExpiresAt: now.Add(-time.Minute)}},
+		{name: "expires before not_before", entry: Entry{ID: "x", NotBefore: now.Add(time.Hour), ExpiresAt: now.Add(time.Minute)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Add(tt.entry); err == nil {
+				t.Error("Add() expected error")
+			}
+		})
+	}
+}
+
+func TestResultQueue_DrainStopsOnFailure(t *testing.T) {
+	q, err := NewResultQueue(t.TempDir() + "/results.jsonl")
+	if err != nil {
+		t.Fatalf("NewResultQueue() failed: %v", err)
+	}
+
+	for _, id := range []string{"a", "b", "c"} {
+		if err := q.Push(QueuedResult{InstructionID: id}); err != nil {
+			t.Fatalf("Push() failed: %v", err)
+		}
+	}
+
+	err = q.Drain(func(r QueuedResult) error {
+		if r.InstructionID == "b" {
+			return errors.New("server unavailable")
+		}
+		return nil
+	})
+	if err == nil {
+		t.Error("Drain() expected error")
+	}
+	if q.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", q.Len())
+	}
+}
+
+func TestResultQueue_PushDuringDrain(t *testing.T) {
+	path := t.TempDir() + "/results.jsonl"
+	q, err := NewResultQueue(path)
+	if err != nil {
+		t.Fatalf("NewResultQueue() failed: %v", err)
+	}
+
+	for _, id := range []string{"a", "b"} {
+		if err := q.Push(QueuedResult{InstructionID: id}); err != nil {
+			t.Fatalf("Push() failed: %v", err)
+		}
+	}
+
+	var submitted []string
+	err = q.Drain(func(r QueuedResult) error {
+		submitted = append(submitted, r.InstructionID)
+		if r.InstructionID == "a" {
+			// Would deadlock if the queue were locked during submission
+			if err := q.Push(QueuedResult{InstructionID: "c"}); err != nil {
+				t.Fatalf("Push() during Drain() failed: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Drain() failed: %v", err)
+	}
+	if len(submitted) != 2 {
+		t.Errorf("submitted %v, want [a b]", submitted)
+	}
+
+	reloaded, err := NewResultQueue(path)
+	if err != nil {
+		t.Fatalf("NewResultQueue() reload failed: %v", err)
+	}
+	for _, queue := range []*ResultQueue{q, reloaded} {
+		var remaining []string
+		queue.Drain(func(r QueuedResult) error {
+			remaining = append(remaining, r.InstructionID)
+			return nil
+		})
+		if len(remaining) != 1 || remaining[0] != "c" {
+			t.Errorf("remaining = %v, want [c]", remaining)
+		}
+	}
+}
+
+func TestResultQueue_Persistence(t *testing.T) {
+	path := t.TempDir() + "/results.jsonl"
+	q, err := NewResultQueue(path)
+	if err != nil {
+		t.Fatalf("NewResultQueue() failed: %v", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := q.Push(QueuedResult{InstructionID: id, Result: `{"status":"ok"}`}); err != nil {
+			t.Fatalf("Push() failed: %v", err)
+		}
+	}
+
+	// Simulate a crash while appending a third result
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"instruction_id":"c","res`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	q, err = NewResultQueue(path)
+	if err != nil {
+		t.Fatalf("NewResultQueue() reload failed: %v", err)
+	}
+	var ids []string
+	if err := q.Drain(func(r QueuedResult) error {
+		ids = append(ids, r.InstructionID)
+		return nil
+	}); err != nil {
+		t.Fatalf("Drain() failed: %v", err)
+	}
+	if strings.Join(ids, ",") != "a,b" {
+		t.Errorf("drained %v, want [a b]", ids)
+	}
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Errorf("queue file after drain = %q, want empty", data)
+	}
+}
+
+func TestResultQueue_ByteLimit(t *testing.T) {
+	q, err := NewResultQueue(t.TempDir() + "/results.jsonl")
+	if err != nil {
+		t.Fatalf("NewResultQueue() failed: %v", err)
+	}
+
+	large := strings.Repeat("x", MaxQueuedBytes/3)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		if err := q.Push(QueuedResult{InstructionID: id, Result: large}); err != nil {
+			t.Fatalf("Push() failed: %v", err)
+		}
+	}
+
+	// Only two results fit in the byte limit
+	var ids []string
+	_ = q.Drain(func(r QueuedResult) error {
+		ids = append(ids, r.InstructionID)
+		return errors.New("stop")
+	})
+	if q.Len() != 2 || strings.Join(ids, ",") != "c" {
+		t.Errorf("Len() = %d, first queued %v, want 2 starting at c", q.Len(), ids)
+	}
+}
@@ -0,0 +1,134 @@
+package transceiver
+
+// CMIS (QSFP-DD, OSFP, QSFP with CMIS) fields. The lower page holds the
+// module monitors, page 00h the serial ID, page 01h the supported monitors,
+// page 02h the thresholds and banked page 11h the lane monitors.
+const (
+	cmisFlatMemByte      = 2
+	cmisFlatMem          = 0x80
+	cmisTemp             = 14
+	cmisVcc              = 16
+	cmisAppMediaLanes    = 88
+	cmisMaxLanes         = 8
+	cmisVendorName       = 129
+	cmisVendorOUI        = 145
+	cmisVendorPN         = 148
+	cmisVendorRev        = 164
+	cmisVendorSN         = 166
+	cmisDateCode         = 182
+	cmisCableLength      = 202
+	cmisConnector        = 203
+	cmisMediaTech        = 212
+	cmisDiagSupport      = 159
+	cmisDiagTXBias       = 0x01
+	cmisDiagTXPower      = 0x02
+	cmisDiagRXPower      = 0x04
+	cmisBiasScaling      = 160
+	cmisBiasScalingMask  = 0x18
+	cmisThresholdTemp    = 128
+	cmisThresholdVcc     = 136
+	cmisThresholdTXPower = 176
+	cmisThresholdBias    = 184
+	cmisThresholdRXPower = 192
+	cmisLaneTXPower      = 154
+	cmisLaneTXBias       = 170
+	cmisLaneRXPower      = 186
+)
+
+// cmisLengthMultipliers are the multipliers of the cable assembly length
+// base value (byte 202 bits 7-6).
+var cmisLengthMultipliers = [4]float64{0.1, 1, 10, 100}
+
+// decodeCMIS decodes a CMIS module.
+func decodeCMIS(read PageReader, lower []byte) (*Module, error) {
+	page0, err := readPage(read, lower, AddressA0, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Module{
+		Identifier: identifiers[lower[0]],
+		Spec:       SpecCMIS,
+		VendorName: asciiField(page0, cmisVendorName, 16),
+		VendorOUI:  ouiField(page0, cmisVendorOUI),
+		VendorPN:   asciiField(page0, cmisVendorPN, 16),
+		VendorRev:  asciiField(page0, cmisVendorRev, 2),
+		VendorSN:   asciiField(page0, cmisVendorSN, 16),
+		DateCode:   dateCodeField(page0, cmisDateCode),
+		Connector:  connectors[page0[cmisConnector]],
+		CableType:  cableType(page0[cmisMediaTech], page0[cmisConnector]),
+	}
+	if length := page0[cmisCableLength]; length != 0 {
+		m.CableLength = float64(length&0x3f) * cmisLengthMultipliers[length>>6]
+	}
+	if m.CableType == CablePassiveCopper {
+		return m, nil
+	}
+
+	diag := &Diagnostics{
+		Temperature: value(temperature(lower, cmisTemp)),
+		Voltage:     value(voltage(lower, cmisVcc)),
+	}
+	m.Diagnostics = diag
+
+	// Flat-memory modules only have the lower page and page 00h
+	if lower[cmisFlatMemByte]&cmisFlatMem != 0 {
+		return m, nil
+	}
+
+	page1, err := readPage(read, lower, AddressA0, 1, 0)
+	if err != nil {
+		return m, nil
+	}
+	support := page1[cmisDiagSupport]
+	multiplier := 1 << ((page1[cmisBiasScaling] & cmisBiasScalingMask) >> 3)
+	scaledBias := func(data []byte, offset int) float64 {
+		return bias(data, offset, multiplier)
+	}
+
+	if page2, err := readPage(read, lower, AddressA0, 2, 0); err == nil {
+		diag.Thresholds = &DiagnosticThresholds{
+			Temperature: thresholds(page2, cmisThresholdTemp, temperature),
+			Voltage:     thresholds(page2, cmisThresholdVcc, voltage),
+		}
+		if support&cmisDiagTXBias != 0 {
+			diag.Thresholds.TXBias = thresholds(page2, cmisThresholdBias, scaledBias)
+		}
+		if support&cmisDiagTXPower != 0 {
+			diag.Thresholds.TXPower = thresholds(page2, cmisThresholdTXPower, power)
+		}
+		if support&cmisDiagRXPower != 0 {
+			diag.Thresholds.RXPower = thresholds(page2, cmisThresholdRXPower, power)
+		}
+	}
+
+	page11, err := readPage(read, lower, AddressA0, 0x11, 0)
+	if err != nil {
+		return m, nil
+	}
+	for lane := 0; lane < cmisLanes(lower); lane++ {
+		ld := LaneDiagnostics{Lane: lane + 1}
+		if support&cmisDiagTXBias != 0 {
+			ld.TXBias = value(scaledBias(page11, cmisLaneTXBias+2*lane))
+		}
+		if support&cmisDiagTXPower != 0 {
+			ld.TXPower = value(power(page11, cmisLaneTXPower+2*lane))
+		}
+		if support&cmisDiagRXPower != 0 {
+			ld.RXPower = value(power(page11, cmisLaneRXPower+2*lane))
+		}
+		diag.Lanes = append(diag.Lanes, ld)
+	}
+
+	return m, nil
+}
+
+// cmisLanes returns the media lane count of the module's first application
+// (e.g., 4 for 400GBASE-DR4), or the maximum if it is not advertised.
+func cmisLanes(lower []byte) int {
+	lanes := int(lower[cmisAppMediaLanes] & 0x0f)
+	if lanes == 0 || lanes > cmisMaxLanes {
+		return cmisMaxLanes
+	}
+	return lanes
+}
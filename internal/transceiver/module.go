@@ -0,0 +1,219 @@
+// Package transceiver decodes the EEPROM of pluggable transceiver modules
+// and cables: SFP (SFF-8472), QSFP+/QSFP28 (SFF-8636) and CMIS modules such
+// as QSFP-DD and OSFP. It reports module identity, cable type and length,
+// and digital diagnostics (DOM) with their alarm and warning thresholds.
+package transceiver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// I2C addresses of the module EEPROM. SFF-8472 modules expose their
+// diagnostics at a second address.
+const (
+	AddressA0 = 0x50
+	AddressA2 = 0x51
+
+	// pageSize is the size of a lower or upper EEPROM page.
+	pageSize = 128
+)
+
+// Cable types.
+const (
+	CableOptical       = "optical"
+	CableActiveOptical = "active_optical"
+	CablePassiveCopper = "passive_copper"
+	CableActiveCopper  = "active_copper"
+)
+
+// Module specifications.
+const (
+	SpecSFF8472 = "SFF-8472"
+	SpecSFF8636 = "SFF-8636"
+	SpecCMIS    = "CMIS"
+)
+
+// PageReader reads length bytes at offset (0-255) of an EEPROM page. Offsets
+// below 128 address the lower page, which is the same for every page;
+// reads must not cross the 128-byte boundary.
+type PageReader func(i2cAddr, page, bank uint8, offset, length int) ([]byte, error)
+
+// Module is a decoded transceiver module or cable.
+type Module struct {
+	Identifier string `json:"identifier"`
+	Spec       string `json:"spec"`
+	VendorName string `json:"vendor_name,omitempty"`
+	VendorOUI  string `json:"vendor_oui,omitempty"`
+	VendorPN   string `json:"vendor_pn,omitempty"`
+	VendorRev  string `json:"vendor_rev,omitempty"`
+	VendorSN   string `json:"vendor_sn,omitempty"`
+	DateCode   string `json:"date_code,omitempty"`
+	Connector  string `json:"connector,omitempty"`
+	CableType  string `json:"cable_type,omitempty"`
+	// CableLength is the length of a cable assembly (DAC/AOC) in meters.
+	CableLength float64      `json:"cable_length_m,omitempty"`
+	Diagnostics *Diagnostics `json:"diagnostics,omitempty"`
+}
+
+// Diagnostics are the digital diagnostic monitoring (DOM) readings of a
+// module. Readings the module does not implement are nil.
+type Diagnostics struct {
+	Temperature *float64              `json:"temperature_c,omitempty"`
+	Voltage     *float64              `json:"voltage_v,omitempty"`
+	Lanes       []LaneDiagnostics     `json:"lanes,omitempty"`
+	Thresholds  *DiagnosticThresholds `json:"thresholds,omitempty"`
+}
+
+// LaneDiagnostics are the per-lane laser readings.
+type LaneDiagnostics struct {
+	Lane    int      `json:"lane"`
+	TXBias  *float64 `json:"tx_bias_ma,omitempty"`
+	TXPower *float64 `json:"tx_power_mw,omitempty"`
+	RXPower *float64 `json:"rx_power_mw,omitempty"`
+}
+
+// DiagnosticThresholds are the alarm and warning thresholds of the
+// diagnostics, in the same units as the readings.
+type DiagnosticThresholds struct {
+	Temperature *Thresholds `json:"temperature_c,omitempty"`
+	Voltage     *Thresholds `json:"voltage_v,omitempty"`
+	TXBias      *Thresholds `json:"tx_bias_ma,omitempty"`
+	TXPower     *Thresholds `json:"tx_power_mw,omitempty"`
+	RXPower     *Thresholds `json:"rx_power_mw,omitempty"`
+}
+
+// Thresholds are the alarm and warning limits of one reading.
+type Thresholds struct {
+	HighAlarm   float64 `json:"high_alarm"`
+	HighWarning float64 `json:"high_warning"`
+	LowWarning  float64 `json:"low_warning"`
+	LowAlarm    float64 `json:"low_alarm"`
+}
+
+// identifiers maps SFF-8024 identifier codes to module types.
+var identifiers = map[byte]string{
+	0x03: "SFP/SFP+/SFP28",
+	0x0c: "QSFP",
+	0x0d: "QSFP+",
+	0x11: "QSFP28",
+	0x18: "QSFP-DD",
+	0x19: "OSFP",
+	0x1e: "QSFP+ (CMIS)",
+}
+
+// connectors maps SFF-8024 connector codes to names.
+var connectors = map[byte]string{
+	0x01: "SC",
+	0x07: "LC",
+	0x0b: "Optical pigtail",
+	0x0c: "MPO 1x12",
+	0x0d: "MPO 2x16",
+	0x21: "Copper pigtail",
+	0x22: "RJ45",
+	0x23: "No separable connector",
+	0x24: "MXC 2x16",
+	0x25: "CS optical connector",
+	0x26: "SN optical connector",
+	0x27: "MPO 2x12",
+	0x28: "MPO 1x16",
+}
+
+// Decode reads and decodes the EEPROM of a module.
+func Decode(read PageReader) (*Module, error) {
+	lower, err := read(AddressA0, 0, 0, 0, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module EEPROM: %w", err)
+	}
+	if len(lower) < pageSize {
+		return nil, fmt.Errorf("short module EEPROM read: %d bytes", len(lower))
+	}
+
+	switch id := lower[0]; id {
+	case 0x03:
+		return decodeSFF8472(read, lower)
+	case 0x0c, 0x0d, 0x11:
+		return decodeSFF8636(read, lower)
+	case 0x18, 0x19, 0x1e:
+		return decodeCMIS(read, lower)
+	default:
+		return nil, fmt.Errorf("unsupported module identifier 0x%02x", id)
+	}
+}
+
+// readPage returns a 256-byte page: the lower page followed by the given
+// upper page, so spec offsets index it directly.
+func readPage(read PageReader, lower []byte, i2cAddr, page, bank uint8) ([]byte, error) {
+	upper, err := read(i2cAddr, page, bank, pageSize, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(upper) < pageSize {
+		return nil, fmt.Errorf("short read of page 0x%02x: %d bytes", page, len(upper))
+	}
+	data := make([]byte, 0, 2*pageSize)
+	data = append(data, lower[:pageSize]...)
+	return append(data, upper[:pageSize]...), nil
+}
+
+// asciiField returns a space-padded ASCII field.
+func asciiField(data []byte, offset, length int) string {
+	return strings.TrimSpace(strings.TrimRight(string(data[offset:offset+length]), "\x00"))
+}
+
+// ouiField formats a 3-byte vendor OUI.
+func ouiField(data []byte, offset int) string {
+	oui := data[offset : offset+3]
+	if oui[0] == 0 && oui[1] == 0 && oui[2] == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%02x:%02x:%02x", oui[0], oui[1], oui[2])
+}
+
+// dateCodeField formats a YYMMDD date code as YYYY-MM-DD.
+func dateCodeField(data []byte, offset int) string {
+	code := asciiField(data, offset, 6)
+	if len(code) != 6 || strings.Trim(code, "0123456789") != "" {
+		return code
+	}
+	return fmt.Sprintf("20%s-%s-%s", code[0:2], code[2:4], code[4:6])
+}
+
+// Units of the diagnostic fields (SFF-8472 section 9, shared by SFF-8636 and CMIS).
+
+// temperature decodes a signed 1/256 °C value.
+func temperature(data []byte, offset int) float64 {
+	return float64(int16(binary.BigEndian.Uint16(data[offset:]))) / 256
+}
+
+// voltage decodes a 100 µV value in volts.
+func voltage(data []byte, offset int) float64 {
+	return float64(binary.BigEndian.Uint16(data[offset:])) / 10000
+}
+
+// bias decodes a 2 µA value in mA, scaled by a module-specific multiplier.
+func bias(data []byte, offset, multiplier int) float64 {
+	return float64(int(binary.BigEndian.Uint16(data[offset:]))*multiplier) / 500
+}
+
+// power decodes a 0.1 µW value in mW.
+func power(data []byte, offset int) float64 {
+	return float64(binary.BigEndian.Uint16(data[offset:])) / 10000
+}
+
+// thresholds decodes four consecutive 2-byte limits in the order high
+// alarm, low alarm, high warning, low warning.
+func thresholds(data []byte, offset int, decode func([]byte, int) float64) *Thresholds {
+	return &Thresholds{
+		HighAlarm:   decode(data, offset),
+		LowAlarm:    decode(data, offset+2),
+		HighWarning: decode(data, offset+4),
+		LowWarning:  decode(data, offset+6),
+	}
+}
+
+// value returns a pointer to v.
+func value(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,189 @@
+package transceiver
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// loadDump reads an EEPROM dump and returns a PageReader over it. A dump is
+// a sequence of "-- <i2c address> <page> <bank> --" sections of hex bytes;
+// page 0 sections hold the lower and upper page (256 bytes), others only
+// the upper page (128 bytes).
+func loadDump(t *testing.T, path string) PageReader {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages := make(map[string][]byte)
+	var key string
+	var body strings.Builder
+	flush := func() {
+		if key == "" {
+			return
+		}
+		b, err := hex.DecodeString(strings.Join(strings.Fields(body.String()), ""))
+		if err != nil {
+			t.Fatalf("%s: section %q: %v", path, key, err)
+		}
+		pages[key] = b
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "-- ") && strings.HasSuffix(line, " --") {
+			flush()
+			key, body = strings.TrimSpace(line[3:len(line)-3]), strings.Builder{}
+			continue
+		}
+		if key != "" {
+			body.WriteString(line)
+		}
+	}
+	flush()
+
+	return func(i2cAddr, page, bank uint8, offset, length int) ([]byte, error) {
+		if offset < pageSize {
+			page, bank = 0, 0
+		}
+		data, ok := pages[fmt.Sprintf("0x%02x %d %d", i2cAddr, page, bank)]
+		if !ok {
+			return nil, errors.New("page not present")
+		}
+		if len(data) == pageSize {
+			offset -= pageSize
+		}
+		return data[offset : offset+length], nil
+	}
+}
+
+func TestDecode_Golden(t *testing.T) {
+	dumps, err := filepath.Glob("testdata/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dumps) == 0 {
+		t.Fatal("no EEPROM dumps in testdata")
+	}
+
+	for _, dump := range dumps {
+		name := strings.TrimSuffix(filepath.Base(dump), ".txt")
+		t.Run(name, func(t *testing.T) {
+			module, err := Decode(loadDump(t, dump))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+
+			got, err := json.MarshalIndent(module, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("decoded module does not match %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestDecode_Units(t *testing.T) {
+	tests := []struct {
+		dump  string
+		check func(t *testing.T, m *Module)
+	}{
+		{
+			dump: "sff8472_sfp28_sr.txt",
+			check: func(t *testing.T, m *Module) {
+				d := m.Diagnostics
+				if *d.Temperature != 35.5 || *d.Voltage != 3.2931 {
+					t.Errorf("temperature/voltage = %v/%v, want 35.5/3.2931", *d.Temperature, *d.Voltage)
+				}
+				if *d.Lanes[0].TXBias != 6.842 || *d.Lanes[0].RXPower != 0.4467 {
+					t.Errorf("lane 1 = %+v", d.Lanes[0])
+				}
+				if d.Thresholds.Temperature.LowAlarm != -5 {
+					t.Errorf("temperature low alarm = %v, want -5", d.Thresholds.Temperature.LowAlarm)
+				}
+			},
+		},
+		{
+			dump: "sff8636_qsfp28_dac.txt",
+			check: func(t *testing.T, m *Module) {
+				if m.CableType != CablePassiveCopper || m.CableLength != 3 || m.Diagnostics != nil {
+					t.Errorf("module = %+v, want 3 m passive copper without diagnostics", m)
+				}
+			},
+		},
+		{
+			dump: "cmis_qsfpdd_dr4.txt",
+			check: func(t *testing.T, m *Module) {
+				d := m.Diagnostics
+				if len(d.Lanes) != 4 {
+					t.Fatalf("got %d lanes, want 4 (400GBASE-DR4)", len(d.Lanes))
+				}
+				// Bias is stored in units of 4 µA (multiplier x2)
+				if *d.Lanes[1].TXBias != 7.4 || d.Thresholds.TXBias.HighAlarm != 15 {
+					t.Errorf("lane 2 bias = %v, high alarm = %v, want 7.4/15", *d.Lanes[1].TXBias, d.Thresholds.TXBias.HighAlarm)
+				}
+			},
+		},
+		{
+			dump: "cmis_osfp_dac.txt",
+			check: func(t *testing.T, m *Module) {
+				if m.CableLength != 1.5 || m.CableType != CablePassiveCopper {
+					t.Errorf("cable = %v m %s, want 1.5 m passive copper", m.CableLength, m.CableType)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dump, func(t *testing.T) {
+			module, err := Decode(loadDump(t, filepath.Join("testdata", tt.dump)))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			tt.check(t, module)
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	failing := func(i2cAddr, page, bank uint8, offset, length int) ([]byte, error) {
+		return nil, errors.New("netlink: input/output error")
+	}
+	if _, err := Decode(failing); err == nil {
+		t.Error("Decode() expected error when the EEPROM cannot be read")
+	}
+
+	unknown := func(i2cAddr, page, bank uint8, offset, length int) ([]byte, error) {
+		data := make([]byte, length)
+		data[0] = 0x7f
+		return data, nil
+	}
+	if _, err := Decode(unknown); err == nil || !strings.Contains(err.Error(), "0x7f") {
+		t.Errorf("Decode() error = %v, want unsupported identifier", err)
+	}
+}
@@ -0,0 +1,93 @@
+package transceiver
+
+// SFF-8472 (SFP) fields. A0h holds the serial ID, A2h the diagnostics.
+const (
+	sff8472Connector      = 2
+	sff8472CableTech      = 8
+	sff8472LengthCopper   = 18
+	sff8472VendorName     = 20
+	sff8472VendorOUI      = 37
+	sff8472VendorPN       = 40
+	sff8472VendorRev      = 56
+	sff8472VendorSN       = 68
+	sff8472DateCode       = 84
+	sff8472DiagType       = 92
+	sff8472PassiveCable   = 0x04
+	sff8472ActiveCable    = 0x08
+	sff8472DiagImplem     = 0x40
+	sff8472DiagExternal   = 0x10
+	sff8472ThresholdTemp  = 0
+	sff8472ThresholdVcc   = 8
+	sff8472ThresholdBias  = 16
+	sff8472ThresholdTXPwr = 24
+	sff8472ThresholdRXPwr = 32
+	sff8472Temp           = 96
+	sff8472Vcc            = 98
+	sff8472Bias           = 100
+	sff8472TXPower        = 102
+	sff8472RXPower        = 104
+)
+
+// decodeSFF8472 decodes an SFP module. Only the lower 128 bytes of A0h and
+// A2h are needed.
+func decodeSFF8472(read PageReader, a0 []byte) (*Module, error) {
+	m := &Module{
+		Identifier: identifiers[a0[0]],
+		Spec:       SpecSFF8472,
+		VendorName: asciiField(a0, sff8472VendorName, 16),
+		VendorOUI:  ouiField(a0, sff8472VendorOUI),
+		VendorPN:   asciiField(a0, sff8472VendorPN, 16),
+		VendorRev:  asciiField(a0, sff8472VendorRev, 4),
+		VendorSN:   asciiField(a0, sff8472VendorSN, 16),
+		DateCode:   dateCodeField(a0, sff8472DateCode),
+		Connector:  connectors[a0[sff8472Connector]],
+		CableType:  CableOptical,
+	}
+
+	switch {
+	case a0[sff8472CableTech]&sff8472PassiveCable != 0:
+		m.CableType = CablePassiveCopper
+	case a0[sff8472CableTech]&sff8472ActiveCable != 0:
+		m.CableType = CableActiveCopper
+	}
+	if m.CableType != CableOptical {
+		m.CableLength = float64(a0[sff8472LengthCopper])
+	}
+
+	// Externally calibrated modules need calibration constants applied;
+	// only internally calibrated readings are reported
+	diagType := a0[sff8472DiagType]
+	if diagType&sff8472DiagImplem == 0 || diagType&sff8472DiagExternal != 0 {
+		return m, nil
+	}
+
+	a2, err := read(AddressA2, 0, 0, 0, pageSize)
+	if err != nil || len(a2) < pageSize {
+		return m, nil
+	}
+
+	m.Diagnostics = &Diagnostics{
+		Temperature: value(temperature(a2, sff8472Temp)),
+		Voltage:     value(voltage(a2, sff8472Vcc)),
+		Lanes: []LaneDiagnostics{{
+			Lane:    1,
+			TXBias:  value(bias(a2, sff8472Bias, 1)),
+			TXPower: value(power(a2, sff8472TXPower)),
+			RXPower: value(power(a2, sff8472RXPower)),
+		}},
+		Thresholds: &DiagnosticThresholds{
+			Temperature: thresholds(a2, sff8472ThresholdTemp, temperature),
+			Voltage:     thresholds(a2, sff8472ThresholdVcc, voltage),
+			TXBias:      thresholds(a2, sff8472ThresholdBias, unscaledBias),
+			TXPower:     thresholds(a2, sff8472ThresholdTXPwr, power),
+			RXPower:     thresholds(a2, sff8472ThresholdRXPwr, power),
+		},
+	}
+
+	return m, nil
+}
+
+// unscaledBias decodes a bias value without a multiplier.
+func unscaledBias(data []byte, offset int) float64 {
+	return bias(data, offset, 1)
+}
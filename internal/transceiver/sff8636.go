@@ -0,0 +1,117 @@
+package transceiver
+
+// SFF-8636 (QSFP+/QSFP28) fields. The lower page holds the monitors,
+// upper page 00h the serial ID and upper page 03h the thresholds.
+const (
+	sff8636Status        = 2
+	sff8636FlatMem       = 0x04
+	sff8636Temp          = 22
+	sff8636Vcc           = 26
+	sff8636RXPower       = 34
+	sff8636TXBias        = 42
+	sff8636TXPower       = 50
+	sff8636Lanes         = 4
+	sff8636Connector     = 130
+	sff8636LengthCable   = 146
+	sff8636DeviceTech    = 147
+	sff8636VendorName    = 148
+	sff8636VendorOUI     = 165
+	sff8636VendorPN      = 168
+	sff8636VendorRev     = 184
+	sff8636VendorSN      = 196
+	sff8636DateCode      = 212
+	sff8636DiagType      = 220
+	sff8636DiagTemp      = 0x20
+	sff8636DiagVcc       = 0x10
+	sff8636DiagTXPower   = 0x04
+	sff8636ThresholdTemp = 128
+	sff8636ThresholdVcc  = 144
+	sff8636ThresholdRX   = 176
+	sff8636ThresholdBias = 184
+	sff8636ThresholdTX   = 192
+)
+
+// decodeSFF8636 decodes a QSFP/QSFP+/QSFP28 module.
+func decodeSFF8636(read PageReader, lower []byte) (*Module, error) {
+	page0, err := readPage(read, lower, AddressA0, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Module{
+		Identifier: identifiers[lower[0]],
+		Spec:       SpecSFF8636,
+		VendorName: asciiField(page0, sff8636VendorName, 16),
+		VendorOUI:  ouiField(page0, sff8636VendorOUI),
+		VendorPN:   asciiField(page0, sff8636VendorPN, 16),
+		VendorRev:  asciiField(page0, sff8636VendorRev, 2),
+		VendorSN:   asciiField(page0, sff8636VendorSN, 16),
+		DateCode:   dateCodeField(page0, sff8636DateCode),
+		Connector:  connectors[page0[sff8636Connector]],
+		CableType:  cableType(page0[sff8636DeviceTech]>>4, page0[sff8636Connector]),
+	}
+	if m.CableType != CableOptical {
+		m.CableLength = float64(page0[sff8636LengthCable])
+	}
+	if m.CableType == CablePassiveCopper {
+		return m, nil
+	}
+
+	diagType := page0[sff8636DiagType]
+	diag := &Diagnostics{}
+	if diagType&sff8636DiagTemp != 0 {
+		diag.Temperature = value(temperature(lower, sff8636Temp))
+	}
+	if diagType&sff8636DiagVcc != 0 {
+		diag.Voltage = value(voltage(lower, sff8636Vcc))
+	}
+	for lane := 0; lane < sff8636Lanes; lane++ {
+		ld := LaneDiagnostics{
+			Lane:    lane + 1,
+			TXBias:  value(bias(lower, sff8636TXBias+2*lane, 1)),
+			RXPower: value(power(lower, sff8636RXPower+2*lane)),
+		}
+		if diagType&sff8636DiagTXPower != 0 {
+			ld.TXPower = value(power(lower, sff8636TXPower+2*lane))
+		}
+		diag.Lanes = append(diag.Lanes, ld)
+	}
+	m.Diagnostics = diag
+
+	// Flat-memory modules have no page 03h
+	if lower[sff8636Status]&sff8636FlatMem != 0 {
+		return m, nil
+	}
+	page3, err := readPage(read, lower, AddressA0, 3, 0)
+	if err != nil {
+		return m, nil
+	}
+	diag.Thresholds = &DiagnosticThresholds{
+		Temperature: thresholds(page3, sff8636ThresholdTemp, temperature),
+		Voltage:     thresholds(page3, sff8636ThresholdVcc, voltage),
+		TXBias:      thresholds(page3, sff8636ThresholdBias, unscaledBias),
+		RXPower:     thresholds(page3, sff8636ThresholdRX, power),
+	}
+	if diagType&sff8636DiagTXPower != 0 {
+		diag.Thresholds.TXPower = thresholds(page3, sff8636ThresholdTX, power)
+	}
+
+	return m, nil
+}
+
+// cableType classifies a module by its transmitter technology (SFF-8636
+// byte 147 bits 7-4, CMIS media interface technology), which uses codes
+// 0xa-0xf for copper, and its connector.
+func cableType(tech, connector byte) string {
+	switch {
+	case tech == 0x0a || tech == 0x0b:
+		return CablePassiveCopper
+	case tech >= 0x0c && tech <= 0x0f:
+		return CableActiveCopper
+	case connector == 0x23:
+		// Optical transmitter without a separable connector
+		return CableActiveOptical
+	default:
+		return CableOptical
+	}
+}